@@ -62,7 +62,7 @@ type RTBSource struct {
 	MinBid billing.Money // Minimal bid value
 	MaxBid billing.Money // Maximal bid value
 
-	// TODO
+	// Budget limits (0 – unlimit), enforced by the adsource/budget tracker
 	Budget      billing.Money // Budget for this source
 	DailyBudget billing.Money // Daily budget for this source
 
@@ -0,0 +1,61 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package accessors
+
+import (
+	"context"
+	"time"
+
+	"github.com/geniusrabbit/adcorelib/adtype"
+)
+
+// ErrSourceBudgetExhausted skip reason of the source without budget
+var ErrSourceBudgetExhausted = adtype.ErrResponseSkipped.WithMessage("source budget is exhausted")
+
+type budgetChecker interface {
+	Exhausted(sourceID uint64) bool
+}
+
+// BudgetAccessor decorates the source accessor and skips sources with the exhausted budget
+type BudgetAccessor struct {
+	accessor adtype.SourceAccessor
+	budgets  budgetChecker
+}
+
+// NewBudgetAccessor wraps the source accessor with the budget check (see budget.Tracker)
+func NewBudgetAccessor(accessor adtype.SourceAccessor, budgets budgetChecker) *BudgetAccessor {
+	return &BudgetAccessor{accessor: accessor, budgets: budgets}
+}
+
+// Iterator returns the sources of the wrapped accessor which have budget
+func (a *BudgetAccessor) Iterator(request adtype.BidRequester) adtype.SourceIterator {
+	return func(yield func(float32, adtype.Source) bool) {
+		for priority, src := range a.accessor.Iterator(request) {
+			if src == nil {
+				break
+			}
+			if a.budgets.Exhausted(src.ID()) {
+				sendSourceSkip(request, src, ErrSourceBudgetExhausted)
+				continue
+			}
+			if !yield(priority, src) {
+				return
+			}
+		}
+	}
+}
+
+// SourceByID returns source instance
+func (a *BudgetAccessor) SourceByID(ctx context.Context, id uint64) (adtype.Source, error) {
+	return a.accessor.SourceByID(ctx, id)
+}
+
+// SetTimeout for sourcer
+func (a *BudgetAccessor) SetTimeout(ctx context.Context, timeout time.Duration) {
+	a.accessor.SetTimeout(ctx, timeout)
+}
+
+var _ adtype.SourceAccessor = (*BudgetAccessor)(nil)
@@ -0,0 +1,59 @@
+package accessors
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/geniusrabbit/adcorelib/adquery/bidrequest"
+	"github.com/geniusrabbit/adcorelib/adsource/budget"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
+	"github.com/geniusrabbit/adcorelib/eventtraking/eventstream"
+)
+
+func TestBudgetAccessor(t *testing.T) {
+	var (
+		ctx      = context.Background()
+		stream   = &testStream{}
+		request  = &bidrequest.BidRequest{Ctx: eventstream.WithStream(ctx, stream)}
+		tracker  = budget.NewTracker()
+		accessor = NewBudgetAccessor(&testAccessor{sources: []adtype.Source{
+			&testSource{id: 1}, &testSource{id: 2}, &testSource{id: 3},
+		}}, tracker)
+	)
+	tracker.SetBudget(1, 0, billing.MoneyFloat(10.)) // Daily cap
+	tracker.SetBudget(2, billing.MoneyFloat(5.), 0)  // Total cap
+
+	// Sources with the remaining budget are not skipped
+	for id, amount := range map[uint64]float64{1: 9, 2: 4, 3: 100} {
+		if err := tracker.Book(ctx, id, billing.MoneyFloat(amount)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ids := routedSourceIDs(accessor, request); !slices.Equal(ids, []uint64{1, 2, 3}) {
+		t.Fatalf("sources with budget: %v", ids)
+	}
+	if len(stream.skips) != 0 {
+		t.Fatalf("skip events: %v", stream.skips)
+	}
+
+	// Exhausted daily and total caps skip the sources
+	for _, id := range []uint64{1, 2} {
+		if err := tracker.Book(ctx, id, billing.MoneyFloat(1.)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ids := routedSourceIDs(accessor, request); !slices.Equal(ids, []uint64{3}) {
+		t.Fatalf("sources with budget: %v", ids)
+	}
+	if len(stream.skips) != 2 {
+		t.Fatalf("skip events: %v", stream.skips)
+	}
+	for _, err := range stream.skips {
+		if !errors.Is(err, ErrSourceBudgetExhausted) || !errors.Is(err, adtype.ErrResponseSkipped) {
+			t.Fatalf("skip reason: %v", err)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package accessors
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/geniusrabbit/adcorelib/adquery/bidresponse"
+	"github.com/geniusrabbit/adcorelib/adsource/auctiontrace"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	counter "github.com/geniusrabbit/adcorelib/errorcounter"
+	"github.com/geniusrabbit/adcorelib/eventtraking/eventstream"
+	"github.com/geniusrabbit/adcorelib/openlatency"
+)
+
+// Default parameters of the circuit breaker
+const (
+	defaultBreakerFailureThreshold = 10
+	defaultBreakerSuccessThreshold = 3
+	defaultBreakerCooldown         = 10 * time.Second
+	defaultBreakerProbeInterval    = time.Second
+)
+
+// Skip reasons of the circuit breaker
+var (
+	ErrSourceCircuitOpen     = adtype.ErrResponseSkipped.WithMessage("circuit breaker is open")
+	ErrSourceCircuitHalfOpen = adtype.ErrResponseSkipped.WithMessage("circuit breaker is half-open")
+	ErrSourceErrorRate       = adtype.ErrResponseSkipped.WithMessage("source error rate is too high")
+)
+
+// Circuit breaker states
+const (
+	breakerClosed int32 = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// CircuitBreakerAccessor decorates the source accessor and skips unhealthy sources.
+//
+// Every source has the error counter fed by the errors of responses (including timeouts).
+//   - Closed: the source is skipped randomly by the error rate (see counter.ErrorCounter.Next).
+//   - Open: after N consecutive failures the source is skipped for the cooldown period.
+//   - Half-open: after the cooldown the source receives one probe request per probe interval.
+//     The probe failure opens the circuit again, M successful probes close it.
+//
+// Each skipped source is reported as events.SourceSkip with the reason in the response error.
+type CircuitBreakerAccessor struct {
+	accessor adtype.SourceAccessor
+
+	failureThreshold int32
+	successThreshold int32
+	cooldown         time.Duration
+	probeInterval    time.Duration
+
+	sources sync.Map // map[uint64]*sourceHealth
+}
+
+// CircuitBreakerOption type
+type CircuitBreakerOption func(a *CircuitBreakerAccessor)
+
+// WithBreakerFailureThreshold sets the number of consecutive failures which opens the circuit
+func WithBreakerFailureThreshold(count int) CircuitBreakerOption {
+	return func(a *CircuitBreakerAccessor) {
+		a.failureThreshold = int32(count)
+	}
+}
+
+// WithBreakerSuccessThreshold sets the number of successful probes which closes the circuit
+func WithBreakerSuccessThreshold(count int) CircuitBreakerOption {
+	return func(a *CircuitBreakerAccessor) {
+		a.successThreshold = int32(count)
+	}
+}
+
+// WithBreakerCooldown sets the duration of the open state
+func WithBreakerCooldown(cooldown time.Duration) CircuitBreakerOption {
+	return func(a *CircuitBreakerAccessor) {
+		a.cooldown = cooldown
+	}
+}
+
+// WithBreakerProbeInterval sets the interval between probe requests in the half-open state
+func WithBreakerProbeInterval(interval time.Duration) CircuitBreakerOption {
+	return func(a *CircuitBreakerAccessor) {
+		a.probeInterval = interval
+	}
+}
+
+// NewCircuitBreakerAccessor wraps the source accessor with the circuit breaker
+func NewCircuitBreakerAccessor(accessor adtype.SourceAccessor, opts ...CircuitBreakerOption) *CircuitBreakerAccessor {
+	a := &CircuitBreakerAccessor{
+		accessor:         accessor,
+		failureThreshold: defaultBreakerFailureThreshold,
+		successThreshold: defaultBreakerSuccessThreshold,
+		cooldown:         defaultBreakerCooldown,
+		probeInterval:    defaultBreakerProbeInterval,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	a.failureThreshold = max(a.failureThreshold, 1)
+	a.successThreshold = max(a.successThreshold, 1)
+	return a
+}
+
+// Iterator returns the sources of the wrapped accessor except of unhealthy ones
+func (a *CircuitBreakerAccessor) Iterator(request adtype.BidRequester) adtype.SourceIterator {
+	return func(yield func(float32, adtype.Source) bool) {
+		for priority, src := range a.accessor.Iterator(request) {
+			if src == nil {
+				break
+			}
+			health := a.health(src.ID())
+			probe, reason := health.allow(a, time.Now())
+			if reason != nil {
+				sendSourceSkip(request, src, reason)
+				continue
+			}
+			if !yield(priority, &breakerSource{Source: src, accessor: a, health: health, probe: probe}) {
+				return
+			}
+		}
+	}
+}
+
+// SourceByID returns source instance
+func (a *CircuitBreakerAccessor) SourceByID(ctx context.Context, id uint64) (adtype.Source, error) {
+	return a.accessor.SourceByID(ctx, id)
+}
+
+// SetTimeout for sourcer
+func (a *CircuitBreakerAccessor) SetTimeout(ctx context.Context, timeout time.Duration) {
+	a.accessor.SetTimeout(ctx, timeout)
+}
+
+// IsOpen returns true if the source is not available because of the open circuit
+func (a *CircuitBreakerAccessor) IsOpen(sourceID uint64) bool {
+	if health, ok := a.sources.Load(sourceID); ok {
+		return health.(*sourceHealth).state.Load() != breakerClosed
+	}
+	return false
+}
+
+///////////////////////////////////////////////////////////////////////////////
+/// Internal methods
+///////////////////////////////////////////////////////////////////////////////
+
+func (a *CircuitBreakerAccessor) health(sourceID uint64) *sourceHealth {
+	health, ok := a.sources.Load(sourceID)
+	if !ok {
+		health, _ = a.sources.LoadOrStore(sourceID, &sourceHealth{})
+	}
+	return health.(*sourceHealth)
+}
+
+// sendSourceSkip event with the skip reason as the response error
+func sendSourceSkip(request adtype.BidRequester, src adtype.Source, reason error) {
+	stream, _ := request.Context().Value(eventstream.CtxStreamObject).(eventstream.Stream)
+	if stream != nil {
+		_ = stream.SendSourceSkip(bidresponse.NewEmptyResponse(request, src, reason))
+	}
+	auctiontrace.FromRequest(request).SourceSkipped(src, reason)
+}
+
+type sourceHealth struct {
+	errors    counter.ErrorCounter
+	state     atomic.Int32
+	failures  atomic.Int32 // Consecutive failures
+	successes atomic.Int32 // Successful probes in the half-open state
+	openedAt  atomic.Int64
+	probedAt  atomic.Int64
+}
+
+// allow the request to the source or returns the skip reason.
+// The probe of the half-open circuit is claimed only by the request (see claimProbe),
+// so the sources which are iterated but never requested don't use up the probe.
+func (h *sourceHealth) allow(a *CircuitBreakerAccessor, now time.Time) (probe bool, _ error) {
+	switch h.state.Load() {
+	case breakerOpen:
+		if now.Sub(time.Unix(0, h.openedAt.Load())) < a.cooldown {
+			return false, ErrSourceCircuitOpen
+		}
+		if h.state.CompareAndSwap(breakerOpen, breakerHalfOpen) {
+			h.successes.Store(0)
+			h.probedAt.Store(0)
+		}
+		fallthrough
+	case breakerHalfOpen:
+		if now.Sub(time.Unix(0, h.probedAt.Load())) < a.probeInterval {
+			return false, ErrSourceCircuitHalfOpen
+		}
+		return true, nil
+	default:
+		if !h.errors.Next() {
+			return false, ErrSourceErrorRate
+		}
+	}
+	return false, nil
+}
+
+// claimProbe of the half-open circuit, only one request per probe interval gets it
+func (h *sourceHealth) claimProbe(a *CircuitBreakerAccessor, now time.Time) bool {
+	probedAt := h.probedAt.Load()
+	return now.Sub(time.Unix(0, probedAt)) >= a.probeInterval &&
+		h.probedAt.CompareAndSwap(probedAt, now.UnixNano())
+}
+
+// observe the response error of the source
+func (h *sourceHealth) observe(a *CircuitBreakerAccessor, err error) {
+	if err != nil && (errors.Is(err, adtype.ErrResponseSkipped) || errors.Is(err, adtype.ErrResponseInvalidRequest)) {
+		// The source didn't process the request, so it says nothing about its health
+		return
+	}
+	failure := err != nil && !errors.Is(err, adtype.ErrResponseNoBid) && !errors.Is(err, adtype.ErrResponseEmpty)
+	h.errors.Do(failure)
+
+	if failure {
+		h.successes.Store(0)
+		if h.failures.Add(1) >= a.failureThreshold || h.state.Load() == breakerHalfOpen {
+			h.open()
+		}
+		return
+	}
+
+	h.failures.Store(0)
+	if h.state.Load() == breakerHalfOpen && h.successes.Add(1) >= a.successThreshold {
+		h.state.CompareAndSwap(breakerHalfOpen, breakerClosed)
+	}
+}
+
+func (h *sourceHealth) open() {
+	h.openedAt.Store(time.Now().UnixNano())
+	h.failures.Store(0)
+	h.state.Store(breakerOpen)
+}
+
+// breakerSource observes the responses of the source
+type breakerSource struct {
+	adtype.Source
+	accessor *CircuitBreakerAccessor
+	health   *sourceHealth
+	probe    bool // The request is the probe of the half-open circuit
+}
+
+// Bid request for the source with the response observation
+func (s *breakerSource) Bid(request adtype.BidRequester) adtype.Response {
+	if s.probe && !s.health.claimProbe(s.accessor, time.Now()) {
+		return bidresponse.NewEmptyResponse(request, s.Source, ErrSourceCircuitHalfOpen)
+	}
+	response := s.Source.Bid(request)
+	if response != nil {
+		s.health.observe(s.accessor, response.Error())
+	}
+	return response
+}
+
+// SetTimeout for the source if it supports it
+func (s *breakerSource) SetTimeout(timeout time.Duration) {
+	if setter, _ := s.Source.(adtype.SourceTimeoutSetter); setter != nil {
+		setter.SetTimeout(timeout)
+	}
+}
+
+// Throttle returns true if the source is throttled by the rate limit (see adtype.SourceThrottler)
+func (s *breakerSource) Throttle() bool {
+	throttler, _ := s.Source.(adtype.SourceThrottler)
+	return throttler != nil && throttler.Throttle()
+}
+
+// Metrics of the source if it supports it
+func (s *breakerSource) Metrics() *openlatency.MetricsInfo {
+	if accessor, _ := s.Source.(metricsAccessor); accessor != nil {
+		return accessor.Metrics()
+	}
+	return nil
+}
+
+// Unwrap returns the original source
+func (s *breakerSource) Unwrap() adtype.Source { return s.Source }
+
+type metricsAccessor interface {
+	Metrics() *openlatency.MetricsInfo
+}
+
+var (
+	_ adtype.SourceAccessor      = (*CircuitBreakerAccessor)(nil)
+	_ adtype.SourceTester        = (*breakerSource)(nil)
+	_ adtype.SourceTimeoutSetter = (*breakerSource)(nil)
+	_ adtype.SourceThrottler     = (*breakerSource)(nil)
+)
@@ -0,0 +1,188 @@
+package accessors
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/geniusrabbit/adcorelib/adquery/bidrequest"
+	"github.com/geniusrabbit/adcorelib/adquery/bidresponse"
+	"github.com/geniusrabbit/adcorelib/adsource/ratelimit"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/eventtraking/eventstream"
+	"github.com/geniusrabbit/adcorelib/openlatency"
+)
+
+var errNetwork = errors.New("network error")
+
+type testSource struct {
+	adtype.Source
+	id    uint64
+	err   error
+	calls int
+}
+
+func (s *testSource) ID() uint64       { return s.id }
+func (s *testSource) Protocol() string { return "test" }
+
+func (s *testSource) Bid(request adtype.BidRequester) adtype.Response {
+	s.calls++
+	return bidresponse.NewEmptyResponse(request, s, s.err)
+}
+
+type testAccessor struct {
+	adtype.SourceAccessor
+	sources []adtype.Source
+}
+
+func (a *testAccessor) Iterator(request adtype.BidRequester) adtype.SourceIterator {
+	return func(yield func(float32, adtype.Source) bool) {
+		for _, src := range a.sources {
+			if !yield(1, src) {
+				return
+			}
+		}
+	}
+}
+
+type testStream struct {
+	eventstream.Stream
+	skips []error
+}
+
+func (s *testStream) SendSourceSkip(response adtype.Response) error {
+	s.skips = append(s.skips, response.Error())
+	return nil
+}
+
+func bidAll(accessor adtype.SourceAccessor, request adtype.BidRequester) int {
+	count := 0
+	for _, src := range accessor.Iterator(request) {
+		src.Bid(request)
+		count++
+	}
+	return count
+}
+
+func TestCircuitBreakerAccessor(t *testing.T) {
+	var (
+		stream  = &testStream{}
+		request = &bidrequest.BidRequest{Ctx: eventstream.WithStream(context.Background(), stream)}
+		healthy = &testSource{id: 1, err: adtype.ErrResponseNoBid}
+		broken  = &testSource{id: 2, err: errNetwork}
+		breaker = NewCircuitBreakerAccessor(
+			&testAccessor{sources: []adtype.Source{healthy, broken}},
+			WithBreakerFailureThreshold(3),
+			WithBreakerSuccessThreshold(2),
+			WithBreakerCooldown(20*time.Millisecond),
+			WithBreakerProbeInterval(time.Millisecond),
+		)
+	)
+
+	for range 3 {
+		bidAll(breaker, request)
+	}
+	if !breaker.IsOpen(broken.id) || breaker.IsOpen(healthy.id) {
+		t.Fatalf("expected open circuit of the broken source only")
+	}
+
+	// The broken source is skipped during the cooldown
+	if count := bidAll(breaker, request); count != 1 || broken.calls != 3 {
+		t.Fatalf("requested sources=%d broken calls=%d", count, broken.calls)
+	}
+	if len(stream.skips) != 1 || !errors.Is(stream.skips[0], ErrSourceCircuitOpen) {
+		t.Fatalf("skip events: %v", stream.skips)
+	}
+
+	// The failed probe opens the circuit again
+	time.Sleep(25 * time.Millisecond)
+	bidAll(breaker, request)
+	if broken.calls != 4 || !breaker.IsOpen(broken.id) {
+		t.Fatalf("broken calls=%d after the probe", broken.calls)
+	}
+	if count := bidAll(breaker, request); count != 1 {
+		t.Fatalf("requested sources=%d after the failed probe", count)
+	}
+
+	// Successful probes close the circuit
+	broken.err = nil
+	time.Sleep(25 * time.Millisecond)
+	for range 2 {
+		bidAll(breaker, request)
+		time.Sleep(2 * time.Millisecond)
+	}
+	if breaker.IsOpen(broken.id) {
+		t.Fatal("expected the closed circuit after successful probes")
+	}
+}
+
+func TestCircuitBreakerAccessor_ProbeClaimedByRequest(t *testing.T) {
+	var (
+		request = &bidrequest.BidRequest{Ctx: eventstream.WithStream(context.Background(), &testStream{})}
+		broken  = &testSource{id: 1, err: errNetwork}
+		breaker = NewCircuitBreakerAccessor(
+			&testAccessor{sources: []adtype.Source{broken}},
+			WithBreakerFailureThreshold(1),
+			WithBreakerCooldown(10*time.Millisecond),
+			WithBreakerProbeInterval(time.Hour),
+		)
+	)
+	bidAll(breaker, request)
+	time.Sleep(15 * time.Millisecond)
+
+	// The iterated but not requested source keeps the probe
+	for range 3 {
+		for range breaker.Iterator(request) {
+		}
+	}
+	var probes []adtype.Source
+	for _, src := range breaker.Iterator(request) {
+		probes = append(probes, src)
+	}
+	for _, src := range breaker.Iterator(request) {
+		probes = append(probes, src)
+	}
+	if len(probes) != 2 {
+		t.Fatalf("half-open sources=%d want=2", len(probes))
+	}
+
+	// Only one request of the probe interval reaches the source
+	broken.err = nil
+	if resp := probes[0].Bid(request); resp.Error() != nil {
+		t.Fatalf("probe response: %v", resp.Error())
+	}
+	if resp := probes[1].Bid(request); !errors.Is(resp.Error(), ErrSourceCircuitHalfOpen) {
+		t.Fatalf("second probe response: %v", resp.Error())
+	}
+	if broken.calls != 2 {
+		t.Fatalf("broken calls=%d want=2", broken.calls)
+	}
+}
+
+func TestCircuitBreakerAccessor_RateLimitedSource(t *testing.T) {
+	var (
+		request = &bidrequest.BidRequest{Ctx: eventstream.WithStream(context.Background(), &testStream{})}
+		limited = ratelimit.Wrap(&testSource{id: 1, err: adtype.ErrResponseNoBid}, 1)
+		breaker = NewCircuitBreakerAccessor(&testAccessor{sources: []adtype.Source{limited}})
+	)
+	for _, src := range breaker.Iterator(request) {
+		throttler, ok := src.(adtype.SourceThrottler)
+		if !ok {
+			t.Fatal("the breaker source must forward the throttler of the rate limited source")
+		}
+		if throttler.Throttle() {
+			t.Fatal("the source must not be throttled before the request")
+		}
+		src.Bid(request)
+		if !throttler.Throttle() {
+			t.Fatal("the source must be throttled after the request")
+		}
+		metrics, _ := src.(interface {
+			Metrics() *openlatency.MetricsInfo
+		})
+		if metrics == nil || metrics.Metrics().QPSLimit != 1 || metrics.Metrics().Throttled <= 0 {
+			t.Fatal("the breaker source must forward the metrics of the rate limited source")
+		}
+	}
+}
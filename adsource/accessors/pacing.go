@@ -0,0 +1,76 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package accessors
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/geniusrabbit/adcorelib/adsource/pacing"
+	"github.com/geniusrabbit/adcorelib/adtype"
+)
+
+// ErrSourcePaced skip reason of the source throttled by the budget pacing
+var ErrSourcePaced = adtype.ErrResponseSkipped.WithMessage("source is throttled by the budget pacing")
+
+// ErrPacingBidMultiplierMode is returned for the controller of the bid multiplier mode.
+// The participation is never throttled in this mode, so the accessor would be no-op.
+var ErrPacingBidMultiplierMode = errors.New("[accessors] pacing accessor requires the probability mode of the controller")
+
+type pacingController interface {
+	Allow(key pacing.Key) bool
+}
+
+type pacingModer interface {
+	Mode() pacing.Mode
+}
+
+// PacingAccessor decorates the source accessor and throttles sources by the budget pacing
+type PacingAccessor struct {
+	accessor adtype.SourceAccessor
+	pacing   pacingController
+}
+
+// NewPacingAccessor wraps the source accessor with the pacing check (see pacing.Controller).
+// The controller must be in the pacing.ProbabilityMode, the bid multipliers of the
+// pacing.BidMultiplierMode have to be applied by the bidder itself (see Controller.Decide).
+func NewPacingAccessor(accessor adtype.SourceAccessor, ctrl pacingController) (*PacingAccessor, error) {
+	if moder, _ := ctrl.(pacingModer); moder != nil && moder.Mode() == pacing.BidMultiplierMode {
+		return nil, ErrPacingBidMultiplierMode
+	}
+	return &PacingAccessor{accessor: accessor, pacing: ctrl}, nil
+}
+
+// Iterator returns the sources of the wrapped accessor which are allowed by the pacing
+func (a *PacingAccessor) Iterator(request adtype.BidRequester) adtype.SourceIterator {
+	return func(yield func(float32, adtype.Source) bool) {
+		for priority, src := range a.accessor.Iterator(request) {
+			if src == nil {
+				break
+			}
+			if !a.pacing.Allow(pacing.SourceKey(src.ID())) {
+				sendSourceSkip(request, src, ErrSourcePaced)
+				continue
+			}
+			if !yield(priority, src) {
+				return
+			}
+		}
+	}
+}
+
+// SourceByID returns source instance
+func (a *PacingAccessor) SourceByID(ctx context.Context, id uint64) (adtype.Source, error) {
+	return a.accessor.SourceByID(ctx, id)
+}
+
+// SetTimeout for sourcer
+func (a *PacingAccessor) SetTimeout(ctx context.Context, timeout time.Duration) {
+	a.accessor.SetTimeout(ctx, timeout)
+}
+
+var _ adtype.SourceAccessor = (*PacingAccessor)(nil)
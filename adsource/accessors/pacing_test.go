@@ -0,0 +1,36 @@
+package accessors
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/geniusrabbit/adcorelib/admodels/types"
+	"github.com/geniusrabbit/adcorelib/adsource/pacing"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+func TestPacingAccessor(t *testing.T) {
+	var (
+		sources = &testAccessor{sources: []adtype.Source{&testSource{id: 1}, &testSource{id: 2}}}
+		spend   = pacing.WithSpend(func(pacing.Key) billing.Money { return billing.MoneyInt(100) })
+		config  = pacing.Config{Pacing: types.AdPacingEvenly, DailyBudget: billing.MoneyInt(100)}
+	)
+
+	// The bid multiplier mode never throttles the participation
+	_, err := NewPacingAccessor(sources, pacing.NewController(pacing.WithMode(pacing.BidMultiplierMode), spend))
+	if !errors.Is(err, ErrPacingBidMultiplierMode) {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	ctrl := pacing.NewController(spend)
+	ctrl.SetEntity(pacing.SourceKey(1), config)
+	accessor, err := NewPacingAccessor(sources, ctrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := routedSourceIDs(accessor, newRouterRequest(t, "auction")); !slices.Equal(ids, []uint64{2}) {
+		t.Fatalf("unexpected paced sources %v", ids)
+	}
+}
@@ -0,0 +1,154 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package accessors
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/geniusrabbit/adcorelib/admodels"
+	"github.com/geniusrabbit/adcorelib/adsource/auctiontrace"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/context/ctxlogger"
+)
+
+// Number of the traffic split buckets (0.01% precision)
+const routerSplitBuckets = 10000
+
+// ErrSourceNotRouted is the skip reason of the source excluded by the traffic router
+var ErrSourceNotRouted = adtype.ErrResponseSkipped.WithMessage("source is not routed by the traffic router")
+
+// TrafficRouterAccessor routes the request to the sources selected by the traffic routers.
+//
+// Routers are evaluated in the order of definition against the TargetPointers() of the request.
+// The first router which matches any target and covers the auction by its Percent is selected,
+// the router with zero Percent receives no traffic. The Percent split is deterministic for
+// the auction ID, so the same auction is always routed to the same sources. If no router is
+// selected the default router is used regardless of its Percent, and if there is no default
+// router the request is not restricted by routing.
+//
+// The selected router and the excluded sources are recorded in the auction trace.
+type TrafficRouterAccessor struct {
+	accessor      adtype.SourceAccessor
+	routers       atomic.Pointer[[]*admodels.TrafficRouter]
+	defaultRouter *admodels.TrafficRouter
+}
+
+// TrafficRouterOption type
+type TrafficRouterOption func(a *TrafficRouterAccessor)
+
+// WithDefaultTrafficRouter sets the router used if no one router matches the request
+func WithDefaultTrafficRouter(router *admodels.TrafficRouter) TrafficRouterOption {
+	return func(a *TrafficRouterAccessor) {
+		a.defaultRouter = router
+	}
+}
+
+// NewTrafficRouterAccessor wraps the source accessor with the traffic routers
+func NewTrafficRouterAccessor(accessor adtype.SourceAccessor, routers []*admodels.TrafficRouter, opts ...TrafficRouterOption) *TrafficRouterAccessor {
+	a := &TrafficRouterAccessor{accessor: accessor}
+	for _, opt := range opts {
+		opt(a)
+	}
+	a.SetRouters(routers)
+	return a
+}
+
+// SetRouters replaces the list of traffic routers
+func (a *TrafficRouterAccessor) SetRouters(routers []*admodels.TrafficRouter) {
+	routers = slices.DeleteFunc(slices.Clone(routers), func(r *admodels.TrafficRouter) bool { return r == nil })
+	a.routers.Store(&routers)
+}
+
+// Iterator returns the sources routed by the selected traffic router
+func (a *TrafficRouterAccessor) Iterator(request adtype.BidRequester) adtype.SourceIterator {
+	router := a.Route(request)
+	if request.IsDebug() {
+		fields := []zap.Field{zap.String("auction_id", request.AuctionID())}
+		if router != nil {
+			fields = append(fields,
+				zap.Uint64("router_id", router.ID),
+				zap.Uint64s("sources", router.RTBSourceIDs),
+				zap.Bool("default", router == a.defaultRouter))
+		}
+		ctxlogger.Get(request.Context()).Info("Traffic routing", fields...)
+	}
+	if router == nil {
+		return a.accessor.Iterator(request)
+	}
+	trace := auctiontrace.FromRequest(request)
+	trace.Routed(router.ID, router == a.defaultRouter, router.RTBSourceIDs)
+	return func(yield func(float32, adtype.Source) bool) {
+		for priority, src := range a.accessor.Iterator(request) {
+			if src == nil {
+				break
+			}
+			if !slices.Contains(router.RTBSourceIDs, src.ID()) {
+				trace.SourceSkipped(src, ErrSourceNotRouted)
+				continue
+			}
+			if !yield(priority, src) {
+				return
+			}
+		}
+	}
+}
+
+// Route returns the traffic router selected for the request or nil
+func (a *TrafficRouterAccessor) Route(request adtype.BidRequester) *admodels.TrafficRouter {
+	targets := request.TargetPointers()
+	for _, router := range *a.routers.Load() {
+		if !routerCoversAuction(router, request.AuctionID()) {
+			continue
+		}
+		for _, target := range targets {
+			if router.Test(target) == nil {
+				return router
+			}
+		}
+	}
+	return a.defaultRouter
+}
+
+// SourceByID returns source instance
+func (a *TrafficRouterAccessor) SourceByID(ctx context.Context, id uint64) (adtype.Source, error) {
+	return a.accessor.SourceByID(ctx, id)
+}
+
+// SetTimeout for sourcer
+func (a *TrafficRouterAccessor) SetTimeout(ctx context.Context, timeout time.Duration) {
+	a.accessor.SetTimeout(ctx, timeout)
+}
+
+// routerCoversAuction checks if the auction belongs to the traffic percent of the router.
+// Zero (or negative) percent means no traffic and 100 percent or more means all traffic.
+func routerCoversAuction(router *admodels.TrafficRouter, auctionID string) bool {
+	switch {
+	case router.Percent <= 0:
+		return false
+	case router.Percent >= 100:
+		return true
+	}
+	return float32(splitBucket(router.ID, auctionID)) < router.Percent*routerSplitBuckets/100
+}
+
+// splitBucket returns the stable bucket of the auction for the router
+func splitBucket(routerID uint64, auctionID string) uint64 {
+	var salt [8]byte
+	binary.LittleEndian.PutUint64(salt[:], routerID)
+	hash := fnv.New64a()
+	_, _ = hash.Write(salt[:])
+	_, _ = hash.Write([]byte(auctionID))
+	return hash.Sum64() % routerSplitBuckets
+}
+
+var _ adtype.SourceAccessor = (*TrafficRouterAccessor)(nil)
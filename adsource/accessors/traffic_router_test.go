@@ -0,0 +1,117 @@
+package accessors
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/geniusrabbit/adcorelib/admodels"
+	"github.com/geniusrabbit/adcorelib/admodels/types"
+	"github.com/geniusrabbit/adcorelib/adquery/bidrequest"
+	"github.com/geniusrabbit/adcorelib/adsource/auctiontrace"
+	"github.com/geniusrabbit/adcorelib/adtype"
+)
+
+func newRouterRequest(t *testing.T, auctionID string) *bidrequest.BidRequest {
+	request := &bidrequest.BidRequest{IDVal: auctionID, Imps: []*adtype.Impression{{ID: "1"}}}
+	if err := request.PrepareRequest(0, nil); err != nil {
+		t.Fatal(err)
+	}
+	return request
+}
+
+func routedSourceIDs(accessor adtype.SourceAccessor, request adtype.BidRequester) []uint64 {
+	var ids []uint64
+	for _, src := range accessor.Iterator(request) {
+		ids = append(ids, src.ID())
+	}
+	return ids
+}
+
+func TestTrafficRouterAccessor(t *testing.T) {
+	var (
+		sources = &testAccessor{sources: []adtype.Source{
+			&testSource{id: 1}, &testSource{id: 2}, &testSource{id: 3},
+		}}
+		secureOnly = &admodels.TrafficRouter{ID: 1, RTBSourceIDs: []uint64{1}, Percent: 100,
+			Filter: types.BaseFilter{Secure: types.SecureOnly}}
+		half        = &admodels.TrafficRouter{ID: 2, RTBSourceIDs: []uint64{2}, Percent: 50}
+		defRouter   = &admodels.TrafficRouter{ID: 3, RTBSourceIDs: []uint64{3}}
+		accessor    = NewTrafficRouterAccessor(sources, []*admodels.TrafficRouter{secureOnly, half}, WithDefaultTrafficRouter(defRouter))
+		routedCount = 0
+	)
+
+	for i := range 1000 {
+		request := newRouterRequest(t, fmt.Sprintf("auction-%d", i))
+		ids := routedSourceIDs(accessor, request)
+		if len(ids) != 1 || ids[0] == 1 {
+			t.Fatalf("unexpected routed sources %v", ids)
+		}
+		if ids[0] == 2 {
+			routedCount++
+		}
+		// The split must be stable for the same auction
+		if again := routedSourceIDs(accessor, request); again[0] != ids[0] {
+			t.Fatalf("unstable routing of the auction %s", request.AuctionID())
+		}
+	}
+	if routedCount < 400 || routedCount > 600 {
+		t.Fatalf("routed by 50%% router: %d of 1000", routedCount)
+	}
+
+	// Without the default router all sources are available
+	accessor = NewTrafficRouterAccessor(sources, []*admodels.TrafficRouter{secureOnly})
+	if ids := routedSourceIDs(accessor, newRouterRequest(t, "auction")); len(ids) != 3 {
+		t.Fatalf("unexpected routed sources %v", ids)
+	}
+}
+
+func TestTrafficRouterAccessor_ZeroPercent(t *testing.T) {
+	var (
+		sources = &testAccessor{sources: []adtype.Source{
+			&testSource{id: 1}, &testSource{id: 2},
+		}}
+		disabled  = &admodels.TrafficRouter{ID: 1, RTBSourceIDs: []uint64{1}}
+		defRouter = &admodels.TrafficRouter{ID: 2, RTBSourceIDs: []uint64{2}}
+		accessor  = NewTrafficRouterAccessor(sources, []*admodels.TrafficRouter{disabled}, WithDefaultTrafficRouter(defRouter))
+	)
+	for i := range 100 {
+		request := newRouterRequest(t, fmt.Sprintf("auction-%d", i))
+		if ids := routedSourceIDs(accessor, request); !slices.Equal(ids, []uint64{2}) {
+			t.Fatalf("the router with 0%% percent must not receive traffic: %v", ids)
+		}
+	}
+}
+
+func TestTrafficRouterAccessor_Trace(t *testing.T) {
+	var (
+		sources = &testAccessor{sources: []adtype.Source{
+			&testSource{id: 1}, &testSource{id: 2}, &testSource{id: 3},
+		}}
+		defRouter = &admodels.TrafficRouter{ID: 7, RTBSourceIDs: []uint64{2}}
+		accessor  = NewTrafficRouterAccessor(sources, nil, WithDefaultTrafficRouter(defRouter))
+		request   = newRouterRequest(t, "auction")
+	)
+	request.Debug = true
+	trace := auctiontrace.Start(request)
+
+	if ids := routedSourceIDs(accessor, request); !slices.Equal(ids, []uint64{2}) {
+		t.Fatalf("unexpected routed sources %v", ids)
+	}
+	if trace.Router == nil || trace.Router.ID != 7 || !trace.Router.Default {
+		t.Fatalf("unexpected trace router %+v", trace.Router)
+	}
+	var skipped []uint64
+	for _, src := range trace.Sources {
+		if src.Status == auctiontrace.SourceStatusSkipped && src.Reason == ErrSourceNotRouted.Error() {
+			skipped = append(skipped, src.ID)
+		}
+	}
+	if !slices.Equal(skipped, []uint64{1, 3}) {
+		t.Fatalf("unexpected skipped sources %v", skipped)
+	}
+	if !errors.Is(ErrSourceNotRouted, adtype.ErrResponseSkipped) {
+		t.Fatal("router exclusion must be the skip reason")
+	}
+}
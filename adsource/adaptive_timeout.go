@@ -0,0 +1,162 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package adsource
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/geniusrabbit/adcorelib/adtype"
+)
+
+// Default parameters of the adaptive timeouts
+const (
+	defaultTimeoutPercentile = 0.95
+	defaultTimeoutWindow     = 512
+	defaultTimeoutMinSamples = 100
+	timeoutRecalcEvery       = 16
+)
+
+// AdaptiveTimeoutOption type
+type AdaptiveTimeoutOption func(at *adaptiveTimeout)
+
+// WithTimeoutPercentile sets the latency percentile (0..1) used as the base of the timeout
+func WithTimeoutPercentile(percentile float64) AdaptiveTimeoutOption {
+	return func(at *adaptiveTimeout) {
+		at.percentile = percentile
+	}
+}
+
+// WithTimeoutWindow sets the number of the latest requests used to estimate the percentile
+func WithTimeoutWindow(window int) AdaptiveTimeoutOption {
+	return func(at *adaptiveTimeout) {
+		at.window = window
+	}
+}
+
+// WithTimeoutMinSamples sets the number of requests required before the timeout adaptation
+func WithTimeoutMinSamples(count int) AdaptiveTimeoutOption {
+	return func(at *adaptiveTimeout) {
+		at.minSamples = count
+	}
+}
+
+// adaptiveTimeout computes the timeout of each source from its observed latency
+// as `percentile(latency) + margin`, limited by the request timeout of the wrapper.
+type adaptiveTimeout struct {
+	percentile float64
+	margin     time.Duration
+	window     int
+	minSamples int
+
+	sources sync.Map // map[uint64]*sourceLatency
+}
+
+func newAdaptiveTimeout(margin time.Duration, opts ...AdaptiveTimeoutOption) *adaptiveTimeout {
+	at := &adaptiveTimeout{
+		percentile: defaultTimeoutPercentile,
+		margin:     margin,
+		window:     defaultTimeoutWindow,
+		minSamples: defaultTimeoutMinSamples,
+	}
+	for _, opt := range opts {
+		opt(at)
+	}
+	if at.percentile <= 0 || at.percentile > 1 {
+		at.percentile = defaultTimeoutPercentile
+	}
+	if at.window <= 0 {
+		at.window = defaultTimeoutWindow
+	}
+	at.minSamples = min(max(at.minSamples, 1), at.window)
+	return at
+}
+
+// Timeout of the source or zero if the source has not enough observations
+func (at *adaptiveTimeout) Timeout(sourceID uint64) time.Duration {
+	if lat, ok := at.sources.Load(sourceID); ok {
+		return time.Duration(lat.(*sourceLatency).timeout.Load())
+	}
+	return 0
+}
+
+// Observe the latency of the source request and update the source timeout.
+// The new timeout is pushed to the source if it supports adtype.SourceTimeoutSetter.
+func (at *adaptiveTimeout) Observe(src adtype.Source, latency, maxTimeout time.Duration) {
+	lat, ok := at.sources.Load(src.ID())
+	if !ok {
+		lat, _ = at.sources.LoadOrStore(src.ID(), &sourceLatency{samples: make([]time.Duration, 0, at.window)})
+	}
+	timeout, ok := lat.(*sourceLatency).observe(latency, at)
+	if !ok {
+		return
+	}
+	timeout = min(max(timeout, minimalTimeout), maxTimeout)
+	if prev := time.Duration(lat.(*sourceLatency).timeout.Swap(int64(timeout))); prev != timeout {
+		if setter, _ := src.(adtype.SourceTimeoutSetter); setter != nil {
+			setter.SetTimeout(timeout)
+		}
+	}
+}
+
+// ObserveResponse observes the latency of the response which went over the wire.
+// The skipped responses (e.g. by the own rate limiter of the source) and the responses
+// failed on the request construction are returned instantly, so they are ignored
+// to keep the percentile of the real requests.
+func (at *adaptiveTimeout) ObserveResponse(src adtype.Source, resp adtype.Response, latency, maxTimeout time.Duration) {
+	if !isWireResponse(resp) {
+		return
+	}
+	at.Observe(src, latency, maxTimeout)
+}
+
+// Reset all the computed timeouts
+func (at *adaptiveTimeout) Reset() {
+	at.sources.Clear()
+}
+
+type sourceLatency struct {
+	mx      sync.Mutex
+	samples []time.Duration
+	pos     int
+	count   uint64
+	timeout atomic.Int64
+}
+
+// observe the latency and returns the new timeout if it was recalculated
+func (lat *sourceLatency) observe(latency time.Duration, at *adaptiveTimeout) (time.Duration, bool) {
+	lat.mx.Lock()
+	defer lat.mx.Unlock()
+
+	if len(lat.samples) < at.window {
+		lat.samples = append(lat.samples, latency)
+	} else {
+		lat.samples[lat.pos] = latency
+		lat.pos = (lat.pos + 1) % at.window
+	}
+	lat.count++
+
+	if len(lat.samples) < at.minSamples || lat.count%timeoutRecalcEvery != 0 {
+		return 0, false
+	}
+
+	sorted := slices.Clone(lat.samples)
+	slices.Sort(sorted)
+	idx := min(int(float64(len(sorted))*at.percentile), len(sorted)-1)
+	return sorted[idx] + at.margin, true
+}
+
+// isWireResponse returns true if the response was received from the source by the request
+func isWireResponse(resp adtype.Response) bool {
+	if resp == nil {
+		return false
+	}
+	err := resp.Error()
+	return !errors.Is(err, adtype.ErrResponseSkipped) && !errors.Is(err, adtype.ErrResponseInvalidRequest)
+}
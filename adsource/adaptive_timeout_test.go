@@ -0,0 +1,87 @@
+package adsource
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/geniusrabbit/adcorelib/adtype"
+)
+
+type timeoutSource struct {
+	adtype.Source
+	id      uint64
+	timeout time.Duration
+}
+
+func (s *timeoutSource) ID() uint64                       { return s.id }
+func (s *timeoutSource) SetTimeout(timeout time.Duration) { s.timeout = timeout }
+
+func TestAdaptiveTimeout(t *testing.T) {
+	var (
+		at   = newAdaptiveTimeout(5*time.Millisecond, WithTimeoutWindow(100), WithTimeoutMinSamples(100))
+		fast = &timeoutSource{id: 1}
+		slow = &timeoutSource{id: 2}
+	)
+	for i := range 160 {
+		at.Observe(fast, time.Duration(i%100+1)*time.Millisecond/10, time.Second)
+		at.Observe(slow, time.Duration(i%100+1)*time.Millisecond*10, 300*time.Millisecond)
+	}
+	// p95 of 0.1..10ms latency plus 5ms margin
+	if want := 14600 * time.Microsecond; fast.timeout != want || at.Timeout(fast.id) != want {
+		t.Fatalf("fast source timeout=%s want=%s", fast.timeout, want)
+	}
+	if slow.timeout != 300*time.Millisecond {
+		t.Fatalf("slow source timeout=%s must be capped by the request timeout", slow.timeout)
+	}
+
+	// p95 of 10..1000ms latency plus 5ms margin
+	for i := range 160 {
+		at.Observe(slow, time.Duration(i%100+1)*time.Millisecond*10, 2*time.Second)
+	}
+	if want := 965 * time.Millisecond; slow.timeout != want {
+		t.Fatalf("slow source timeout=%s want=%s", slow.timeout, want)
+	}
+
+	if timeout := at.Timeout(3); timeout != 0 {
+		t.Fatalf("unknown source timeout=%s", timeout)
+	}
+}
+
+func TestMultisourceWrapper_SourceTimeout(t *testing.T) {
+	wrp := &MultisourceWrapper{requestTimeout: 100 * time.Millisecond}
+	if timeout := wrp.SourceTimeout(1); timeout != wrp.requestTimeout {
+		t.Fatalf("timeout=%s without adaptive timeouts", timeout)
+	}
+
+	wrp.adaptiveTimeout = newAdaptiveTimeout(0, WithTimeoutMinSamples(16))
+	src := &timeoutSource{id: 1}
+	for range 16 {
+		wrp.adaptiveTimeout.Observe(src, 20*time.Millisecond, wrp.requestTimeout)
+	}
+	if timeout := wrp.SourceTimeout(1); timeout != 20*time.Millisecond {
+		t.Fatalf("timeout=%s want=20ms", timeout)
+	}
+}
+
+func TestAdaptiveTimeout_SkippedResponses(t *testing.T) {
+	var (
+		at       = newAdaptiveTimeout(5*time.Millisecond, WithTimeoutWindow(100), WithTimeoutMinSamples(32))
+		src      = &timeoutSource{id: 1}
+		ok       = adtype.NewErrorResponse(nil, adtype.ErrResponseNoBid)
+		skipped  = adtype.NewErrorResponse(nil, adtype.ErrResponseSkipped)
+		badBuild = adtype.NewErrorResponse(nil, errors.Join(adtype.ErrResponseInvalidRequest, errors.New("encode")))
+	)
+	for range 64 {
+		at.ObserveResponse(src, ok, 50*time.Millisecond, time.Second)
+		// The instant responses of the rate limiter and the request build errors
+		for range 4 {
+			at.ObserveResponse(src, skipped, time.Microsecond, time.Second)
+			at.ObserveResponse(src, badBuild, time.Microsecond, time.Second)
+		}
+		at.ObserveResponse(src, nil, time.Microsecond, time.Second)
+	}
+	if want := 55 * time.Millisecond; src.timeout != want {
+		t.Fatalf("timeout=%s want=%s: the skipped responses must not be observed", src.timeout, want)
+	}
+}
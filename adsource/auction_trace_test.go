@@ -0,0 +1,74 @@
+package adsource
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/geniusrabbit/adcorelib/adsource/auctiontrace"
+	"github.com/geniusrabbit/adcorelib/adtype"
+)
+
+type throttledSource struct {
+	*tierSource
+}
+
+func (s throttledSource) Throttle() bool { return true }
+
+type listAccessor struct {
+	adtype.SourceAccessor
+	sources []adtype.Source
+}
+
+func (a *listAccessor) Iterator(adtype.BidRequester) adtype.SourceIterator {
+	return func(yield func(float32, adtype.Source) bool) {
+		for _, src := range a.sources {
+			if !yield(1, src) {
+				return
+			}
+		}
+	}
+}
+
+func TestMultisourceWrapper_AuctionTrace(t *testing.T) {
+	var (
+		bidder  = &tierSource{id: 1, bids: map[string]float64{"imp1": 3.}}
+		nobid   = &tierSource{id: 2}
+		skipped = &tierSource{id: 3}
+		wrp     = newTestWrapper(t, WithSourceAccessor(&listAccessor{sources: []adtype.Source{
+			bidder, nobid, throttledSource{skipped},
+		}}))
+	)
+
+	// Debug requests are not traced without the started trace
+	request := newWaterfallRequest()
+	request.Debug = true
+	_ = wrp.Bid(request)
+	if auctiontrace.FromRequest(request) != nil {
+		t.Fatal("the trace of the debug request")
+	}
+
+	request = newWaterfallRequest()
+	trace := auctiontrace.Start(request)
+	_ = wrp.Bid(request)
+
+	if auctiontrace.FromRequest(request) != trace {
+		t.Fatal("no trace of the traced request")
+	}
+	statuses := map[uint64]string{}
+	for _, src := range trace.Sources {
+		statuses[src.ID] = src.Status
+	}
+	if statuses[1] != auctiontrace.SourceStatusBid || statuses[2] != auctiontrace.SourceStatusNoBid ||
+		statuses[3] != auctiontrace.SourceStatusSkipped {
+		t.Fatalf("source statuses: %v", statuses)
+	}
+	if len(trace.Buckets) != 1 || len(trace.Buckets[0].Bids) != 1 || trace.Buckets[0].Bids[0].ImpID != "imp1" {
+		t.Fatalf("buckets: %+v", trace.Buckets)
+	}
+	if len(trace.Selection) != 1 || trace.Selection[0].AuctionCPM != 3 {
+		t.Fatalf("selection: %+v", trace.Selection)
+	}
+	if _, err := json.Marshal(trace); err != nil {
+		t.Fatal(err)
+	}
+}
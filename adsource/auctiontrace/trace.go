@@ -0,0 +1,367 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+// Package auctiontrace collects the structured trace of the debug auction.
+//
+// The trace is stored in the request context for the debug requests only (see Start),
+// all the methods are safe for the nil trace so the calls cost nothing for the
+// regular traffic:
+//
+//	auctiontrace.FromRequest(request).SourceSkipped(src, reason)
+package auctiontrace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+type ctxKey struct{}
+
+// Source statuses of the trace
+const (
+	SourceStatusDispatched = "dispatched"
+	SourceStatusSkipped    = "skipped"
+	SourceStatusBid        = "bid"
+	SourceStatusNoBid      = "nobid"
+	SourceStatusError      = "error"
+)
+
+// Bid of the source with the price factors
+type Bid struct {
+	ItemID                 string  `json:"item_id"`
+	ImpID                  string  `json:"imp_id"`
+	SourceID               uint64  `json:"source_id,omitempty"`
+	CampaignID             uint64  `json:"campaign_id,omitempty"`
+	AuctionCPM             float64 `json:"auction_cpm"`
+	Price                  float64 `json:"price"`
+	PurchasePrice          float64 `json:"purchase_price,omitempty"`
+	CommissionShareFactor  float64 `json:"commission_share_factor,omitempty"`
+	SourceCorrectionFactor float64 `json:"source_correction_factor,omitempty"`
+	TargetCorrectionFactor float64 `json:"target_correction_factor,omitempty"`
+}
+
+// Source of the auction with the result of the request
+type Source struct {
+	ID        uint64  `json:"id"`
+	Protocol  string  `json:"protocol,omitempty"`
+	Priority  float32 `json:"priority"`
+	Status    string  `json:"status"`
+	Reason    string  `json:"reason,omitempty"`
+	LatencyMs float64 `json:"latency_ms,omitempty"`
+	Bids      []Bid   `json:"bids,omitempty"`
+}
+
+// PriceChange of the bid by the price transformation stage (second price, bid shading, etc.)
+type PriceChange struct {
+	Stage    string  `json:"stage"`
+	ItemID   string  `json:"item_id"`
+	ImpID    string  `json:"imp_id"`
+	SourceID uint64  `json:"source_id,omitempty"`
+	From     float64 `json:"from"`
+	To       float64 `json:"to"`
+}
+
+// Bucket of the trafaret with the bids of the same priority for the impression
+type Bucket struct {
+	ImpID    string  `json:"imp_id"`
+	Priority float32 `json:"priority"`
+	Bids     []Bid   `json:"bids"`
+}
+
+// Removal of the ad from the response by the preprocessor
+type Removal struct {
+	Stage    string `json:"stage"`
+	ItemID   string `json:"item_id"`
+	ImpID    string `json:"imp_id"`
+	SourceID uint64 `json:"source_id,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+// Floor of the impression with the reason of the choice
+type Floor struct {
+	ImpID    string  `json:"imp_id"`
+	FloorCPM float64 `json:"floor_cpm"`
+	Reason   string  `json:"reason"`
+}
+
+// Router of the traffic selected for the auction
+type Router struct {
+	ID      uint64   `json:"id"`
+	Default bool     `json:"default,omitempty"`
+	Sources []uint64 `json:"sources"`
+}
+
+// Trace of the auction
+type Trace struct {
+	mx sync.Mutex
+
+	AuctionID string        `json:"auction_id"`
+	Seed      uint64        `json:"seed"`
+	Router    *Router       `json:"router,omitempty"`
+	Floors    []Floor       `json:"floors,omitempty"`
+	Sources   []*Source     `json:"sources"`
+	Prices    []PriceChange `json:"prices,omitempty"`
+	Buckets   []Bucket      `json:"buckets,omitempty"`
+	Removed   []Removal     `json:"removed,omitempty"`
+	Selection []Bid         `json:"selection"`
+}
+
+// NewContext returns the context with the trace
+func NewContext(ctx context.Context, trace *Trace) context.Context {
+	return context.WithValue(ctx, ctxKey{}, trace)
+}
+
+// FromContext returns the trace of the context or nil
+func FromContext(ctx context.Context) *Trace {
+	if ctx == nil {
+		return nil
+	}
+	trace, _ := ctx.Value(ctxKey{}).(*Trace)
+	return trace
+}
+
+// FromRequest returns the trace of the request or nil
+func FromRequest(request adtype.BidRequester) *Trace {
+	if request == nil {
+		return nil
+	}
+	return FromContext(request.Context())
+}
+
+// Start the trace of the request if it's not started yet.
+// The caller decides which requests are traced (e.g. by the trusted token),
+// the debug flag of the request doesn't enable the trace.
+func Start(request adtype.BidRequester) *Trace {
+	if request == nil {
+		return nil
+	}
+	if trace := FromRequest(request); trace != nil {
+		return trace
+	}
+	trace := &Trace{AuctionID: request.AuctionID()}
+	request.SetContext(NewContext(request.Context(), trace))
+	return trace
+}
+
+// SetSeed of the auction randomness to replay the auction
+func (t *Trace) SetSeed(seed uint64) {
+	if t == nil {
+		return
+	}
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	t.Seed = seed
+}
+
+// Routed records the traffic router selected for the auction
+func (t *Trace) Routed(routerID uint64, isDefault bool, sourceIDs []uint64) {
+	if t == nil {
+		return
+	}
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	t.Router = &Router{ID: routerID, Default: isDefault, Sources: sourceIDs}
+}
+
+// Floor records the floor of the impression with the reason of the choice
+func (t *Trace) Floor(impID string, floor billing.Money, reason string) {
+	if t == nil {
+		return
+	}
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	t.Floors = append(t.Floors, Floor{ImpID: impID, FloorCPM: floor.Float64(), Reason: reason})
+}
+
+// SourceDispatched marks the source as requested
+func (t *Trace) SourceDispatched(src adtype.Source, priority float32) {
+	if t == nil || src == nil {
+		return
+	}
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	source := t.source(src)
+	source.Priority = priority
+	source.Status = SourceStatusDispatched
+}
+
+// SourceSkipped marks the source as skipped with the reason
+func (t *Trace) SourceSkipped(src adtype.Source, reason error) {
+	if t == nil || src == nil {
+		return
+	}
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	source := t.source(src)
+	source.Status = SourceStatusSkipped
+	if reason != nil {
+		source.Reason = reason.Error()
+	}
+}
+
+// SourceResponse sets the latency and the bids of the source
+func (t *Trace) SourceResponse(src adtype.Source, response adtype.Response, latency time.Duration) {
+	if t == nil || src == nil {
+		return
+	}
+	var (
+		bids   []Bid
+		status = SourceStatusBid
+		reason string
+	)
+	if err := response.Error(); err != nil {
+		status, reason = SourceStatusError, err.Error()
+		if errors.Is(err, adtype.ErrResponseNoBid) {
+			status = SourceStatusNoBid
+		}
+	} else {
+		for ad := range response.IterAds() {
+			bids = append(bids, bidOf(ad))
+		}
+		if len(bids) == 0 {
+			status = SourceStatusNoBid
+		}
+	}
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	source := t.source(src)
+	source.Status = status
+	source.Reason = reason
+	source.LatencyMs = float64(latency) / float64(time.Millisecond)
+	source.Bids = bids
+}
+
+// PriceChanged records the price transformation of the ad
+func (t *Trace) PriceChanged(stage string, ad adtype.ResponseItem, from, to billing.Money) {
+	if t == nil || ad == nil || from == to {
+		return
+	}
+	bid := bidOf(ad)
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	t.Prices = append(t.Prices, PriceChange{
+		Stage:    stage,
+		ItemID:   bid.ItemID,
+		ImpID:    bid.ImpID,
+		SourceID: bid.SourceID,
+		From:     from.Float64(),
+		To:       to.Float64(),
+	})
+}
+
+// Bucket records the trafaret bucket of the impression
+func (t *Trace) Bucket(impID string, priority float32, ads []adtype.ResponseItemCommon) {
+	if t == nil {
+		return
+	}
+	bucket := Bucket{ImpID: impID, Priority: priority}
+	for _, it := range ads {
+		bucket.Bids = append(bucket.Bids, bidsOf(it)...)
+	}
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	t.Buckets = append(t.Buckets, bucket)
+}
+
+// AdRemoved records the ad removed from the response
+func (t *Trace) AdRemoved(removal Removal) {
+	if t == nil {
+		return
+	}
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	t.Removed = append(t.Removed, removal)
+}
+
+// Selected records the final ads of the auction
+func (t *Trace) Selected(response adtype.Response) {
+	if t == nil || response == nil {
+		return
+	}
+	var selection []Bid
+	for ad := range response.IterAds() {
+		selection = append(selection, bidOf(ad))
+	}
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	t.Selection = selection
+}
+
+// MarshalJSON of the trace. The late source responses can update the trace concurrently.
+func (t *Trace) MarshalJSON() ([]byte, error) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	return json.Marshal(struct {
+		AuctionID string        `json:"auction_id"`
+		Seed      uint64        `json:"seed"`
+		Router    *Router       `json:"router,omitempty"`
+		Floors    []Floor       `json:"floors,omitempty"`
+		Sources   []*Source     `json:"sources"`
+		Prices    []PriceChange `json:"prices,omitempty"`
+		Buckets   []Bucket      `json:"buckets,omitempty"`
+		Removed   []Removal     `json:"removed,omitempty"`
+		Selection []Bid         `json:"selection"`
+	}{
+		AuctionID: t.AuctionID,
+		Seed:      t.Seed,
+		Router:    t.Router,
+		Floors:    t.Floors,
+		Sources:   t.Sources,
+		Prices:    t.Prices,
+		Buckets:   t.Buckets,
+		Removed:   t.Removed,
+		Selection: t.Selection,
+	})
+}
+
+// source returns the trace of the source (must be called under the lock)
+func (t *Trace) source(src adtype.Source) *Source {
+	for _, source := range t.Sources {
+		if source.ID == src.ID() {
+			return source
+		}
+	}
+	source := &Source{ID: src.ID(), Protocol: src.Protocol()}
+	t.Sources = append(t.Sources, source)
+	return source
+}
+
+func bidsOf(it adtype.ResponseItemCommon) []Bid {
+	switch itV := it.(type) {
+	case adtype.ResponseItem:
+		return []Bid{bidOf(itV)}
+	case adtype.ResponseMultipleItem:
+		bids := make([]Bid, 0, itV.Count())
+		for _, ad := range itV.Ads() {
+			bids = append(bids, bidOf(ad))
+		}
+		return bids
+	}
+	return nil
+}
+
+func bidOf(ad adtype.ResponseItem) Bid {
+	bid := Bid{
+		ItemID:                 ad.ID(),
+		ImpID:                  ad.ImpressionID(),
+		CampaignID:             ad.CampaignID(),
+		AuctionCPM:             ad.InternalAuctionCPMBid().Float64(),
+		Price:                  ad.Price(adtype.ActionImpression).Float64(),
+		PurchasePrice:          ad.PurchasePrice(adtype.ActionImpression).Float64(),
+		CommissionShareFactor:  ad.CommissionShareFactor(),
+		SourceCorrectionFactor: ad.SourceCorrectionFactor(),
+		TargetCorrectionFactor: ad.TargetCorrectionFactor(),
+	}
+	if src := ad.Source(); src != nil {
+		bid.SourceID = src.ID()
+	}
+	return bid
+}
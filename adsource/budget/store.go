@@ -0,0 +1,79 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package budget
+
+import (
+	"context"
+	"sync"
+
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+// Spend of the source
+type Spend struct {
+	Total billing.Money `json:"total"`
+	Daily billing.Money `json:"daily"`
+}
+
+// Store of the source spends. It can be shared between several nodes
+// (e.g. Redis or database backend) to enforce the budget of the cluster.
+type Store interface {
+	// Add amount to the total and the daily spend of the source and returns the new spend
+	Add(ctx context.Context, sourceID uint64, day string, amount billing.Money) (Spend, error)
+
+	// Spend of the source by the day
+	Spend(ctx context.Context, sourceID uint64, day string) (Spend, error)
+}
+
+// MemoryStore keeps spends of the sources in the memory of the process
+type MemoryStore struct {
+	mx     sync.Mutex
+	spends map[uint64]*memorySpend
+}
+
+type memorySpend struct {
+	total billing.Money
+	day   string
+	daily billing.Money
+}
+
+// NewMemoryStore of spends
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{spends: map[uint64]*memorySpend{}}
+}
+
+// Add amount to the spend of the source
+func (s *MemoryStore) Add(_ context.Context, sourceID uint64, day string, amount billing.Money) (Spend, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	spend := s.spends[sourceID]
+	if spend == nil {
+		spend = &memorySpend{day: day}
+		s.spends[sourceID] = spend
+	}
+	if spend.day != day {
+		spend.day, spend.daily = day, 0
+	}
+	spend.total += amount
+	spend.daily += amount
+	return Spend{Total: spend.total, Daily: spend.daily}, nil
+}
+
+// Spend of the source by the day
+func (s *MemoryStore) Spend(_ context.Context, sourceID uint64, day string) (Spend, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	spend := s.spends[sourceID]
+	if spend == nil {
+		return Spend{}, nil
+	}
+	if spend.day != day {
+		return Spend{Total: spend.total}, nil
+	}
+	return Spend{Total: spend.total, Daily: spend.daily}, nil
+}
+
+var _ Store = (*MemoryStore)(nil)
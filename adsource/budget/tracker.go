@@ -0,0 +1,222 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+// Package budget tracks the spend of RTB sources and enforces
+// the total and the daily budgets of the sources.
+package budget
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/geniusrabbit/adcorelib/admodels"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
+	"github.com/geniusrabbit/adcorelib/eventtraking/events"
+)
+
+const dayFormat = "2006-01-02"
+
+// State of the source budget. Zero budget means no limit.
+type State struct {
+	Budget      billing.Money `json:"budget"`
+	DailyBudget billing.Money `json:"daily_budget"`
+	Spend       Spend         `json:"spend"`
+}
+
+// Remaining budget of the source (-1 if unlimited)
+func (s State) Remaining() billing.Money {
+	if s.Budget <= 0 {
+		return -1
+	}
+	return max(s.Budget-s.Spend.Total, 0)
+}
+
+// DailyRemaining budget of the source for the current day (-1 if unlimited)
+func (s State) DailyRemaining() billing.Money {
+	if s.DailyBudget <= 0 {
+		return -1
+	}
+	return max(s.DailyBudget-s.Spend.Daily, 0)
+}
+
+// Exhausted returns true if any of budgets is spent
+func (s State) Exhausted() bool {
+	return s.Remaining() == 0 || s.DailyRemaining() == 0
+}
+
+// Tracker books the purchase prices of the sources and checks their budgets.
+//
+// The spend of each source is cached locally and refreshed by every booking
+// (the store returns the spend of all the nodes) or by Refresh.
+type Tracker struct {
+	store    Store
+	location *time.Location
+	events   []events.Type
+	now      func() time.Time
+
+	mx      sync.RWMutex
+	sources map[uint64]*sourceBudget
+}
+
+type sourceBudget struct {
+	budget      billing.Money
+	dailyBudget billing.Money
+	day         string
+	spend       Spend
+}
+
+// Option of the tracker
+type Option func(t *Tracker)
+
+// WithStore sets the shared store of spends
+func WithStore(store Store) Option {
+	return func(t *Tracker) {
+		t.store = store
+	}
+}
+
+// WithLocation sets the timezone of the daily budget reset
+func WithLocation(location *time.Location) Option {
+	return func(t *Tracker) {
+		t.location = location
+	}
+}
+
+// WithBookingEvents sets the event types which book the purchase price.
+// Default is events.SourceWin; use events.Impression for the sources billed by impressions.
+func WithBookingEvents(eventTypes ...events.Type) Option {
+	return func(t *Tracker) {
+		t.events = eventTypes
+	}
+}
+
+// NewTracker of the source budgets with the memory store by default
+func NewTracker(opts ...Option) *Tracker {
+	t := &Tracker{
+		location: time.UTC,
+		events:   []events.Type{events.SourceWin},
+		now:      time.Now,
+		sources:  map[uint64]*sourceBudget{},
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.store == nil {
+		t.store = NewMemoryStore()
+	}
+	if t.location == nil {
+		t.location = time.UTC
+	}
+	return t
+}
+
+// SetBudget of the source. Zero value means no limit.
+func (t *Tracker) SetBudget(sourceID uint64, budget, dailyBudget billing.Money) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	src := t.sources[sourceID]
+	if src == nil {
+		src = &sourceBudget{}
+		t.sources[sourceID] = src
+	}
+	src.budget, src.dailyBudget = budget, dailyBudget
+}
+
+// SetSource budgets from the source model (on every model reload)
+func (t *Tracker) SetSource(source *admodels.RTBSource) {
+	if source != nil {
+		t.SetBudget(source.ID, source.Budget, source.DailyBudget)
+	}
+}
+
+// Book the purchase price of the source
+func (t *Tracker) Book(ctx context.Context, sourceID uint64, amount billing.Money) error {
+	if amount <= 0 {
+		return nil
+	}
+	day := t.day()
+	spend, err := t.store.Add(ctx, sourceID, day, amount)
+	if err != nil {
+		return err
+	}
+	t.updateSpend(sourceID, day, spend)
+	return nil
+}
+
+// BookEvent books the purchase price of the response item if the event type is the booking one
+func (t *Tracker) BookEvent(ctx context.Context, eventType events.Type, item adtype.ResponseItem) error {
+	if item == nil || !slices.Contains(t.events, eventType) {
+		return nil
+	}
+	src := item.Source()
+	if src == nil {
+		return nil
+	}
+	return t.Book(ctx, src.ID(), item.PurchasePrice(adtype.ActionImpression))
+}
+
+// Refresh spends of all the sources from the store
+func (t *Tracker) Refresh(ctx context.Context) error {
+	t.mx.RLock()
+	ids := make([]uint64, 0, len(t.sources))
+	for id := range t.sources {
+		ids = append(ids, id)
+	}
+	t.mx.RUnlock()
+
+	day := t.day()
+	for _, id := range ids {
+		spend, err := t.store.Spend(ctx, id, day)
+		if err != nil {
+			return err
+		}
+		t.updateSpend(id, day, spend)
+	}
+	return nil
+}
+
+// State of the source budget
+func (t *Tracker) State(sourceID uint64) State {
+	day := t.day()
+	t.mx.RLock()
+	defer t.mx.RUnlock()
+	src := t.sources[sourceID]
+	if src == nil {
+		return State{}
+	}
+	state := State{Budget: src.budget, DailyBudget: src.dailyBudget, Spend: src.spend}
+	if src.day != day {
+		// The daily spend of the previous day
+		state.Spend.Daily = 0
+	}
+	return state
+}
+
+// Exhausted returns true if the source has no budget
+func (t *Tracker) Exhausted(sourceID uint64) bool {
+	return t.State(sourceID).Exhausted()
+}
+
+func (t *Tracker) updateSpend(sourceID uint64, day string, spend Spend) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	src := t.sources[sourceID]
+	if src == nil {
+		src = &sourceBudget{}
+		t.sources[sourceID] = src
+	}
+	// Ignore the outdated values of concurrent updates
+	if src.day == day && src.spend.Total >= spend.Total && src.spend.Daily >= spend.Daily {
+		return
+	}
+	src.day, src.spend = day, spend
+}
+
+func (t *Tracker) day() string {
+	return t.now().In(t.location).Format(dayFormat)
+}
@@ -0,0 +1,90 @@
+package budget
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
+	"github.com/geniusrabbit/adcorelib/eventtraking/events"
+)
+
+func TestTracker(t *testing.T) {
+	var (
+		ctx      = context.Background()
+		location = time.FixedZone("UTC+3", 3*60*60)
+		now      = time.Date(2026, 1, 1, 20, 0, 0, 0, time.UTC) // 23:00 in UTC+3
+		tracker  = NewTracker(WithLocation(location))
+	)
+	tracker.now = func() time.Time { return now }
+	tracker.SetBudget(1, billing.MoneyFloat(10.), billing.MoneyFloat(3.))
+
+	var wg sync.WaitGroup
+	for range 30 {
+		wg.Go(func() {
+			if err := tracker.Book(ctx, 1, billing.MoneyFloat(0.1)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	state := tracker.State(1)
+	if state.Spend.Daily != billing.MoneyFloat(3.) || state.DailyRemaining() != 0 || !tracker.Exhausted(1) {
+		t.Fatalf("daily spend=%s remaining=%s", state.Spend.Daily, state.DailyRemaining())
+	}
+	if state.Remaining() != billing.MoneyFloat(7.) {
+		t.Fatalf("remaining=%s want=7", state.Remaining())
+	}
+
+	// The new day starts at 00:00 in UTC+3
+	now = now.Add(time.Hour)
+	if tracker.Exhausted(1) {
+		t.Fatal("expected the daily budget reset")
+	}
+	if err := tracker.Book(ctx, 1, billing.MoneyFloat(7.)); err != nil {
+		t.Fatal(err)
+	}
+	if state = tracker.State(1); state.Remaining() != 0 || !state.Exhausted() {
+		t.Fatalf("total spend=%s remaining=%s", state.Spend.Total, state.Remaining())
+	}
+
+	// Unlimited source
+	if err := tracker.Book(ctx, 2, billing.MoneyFloat(100.)); err != nil {
+		t.Fatal(err)
+	}
+	if state = tracker.State(2); state.Exhausted() || state.Remaining() != -1 {
+		t.Fatalf("unlimited source is exhausted: %+v", state)
+	}
+}
+
+type testItem struct {
+	adtype.ResponseItem
+	src adtype.Source
+}
+
+func (it *testItem) Source() adtype.Source { return it.src }
+
+func (it *testItem) PurchasePrice(adtype.Action) billing.Money { return billing.MoneyFloat(0.002) }
+
+type testSource struct{ adtype.Source }
+
+func (s *testSource) ID() uint64 { return 1 }
+
+func TestTracker_BookEvent(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		tracker = NewTracker()
+		item    = &testItem{src: &testSource{}}
+	)
+	for _, eventType := range []events.Type{events.SourceBid, events.SourceWin, events.Impression} {
+		if err := tracker.BookEvent(ctx, eventType, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if spend := tracker.State(1).Spend.Total; spend != billing.MoneyFloat(0.002) {
+		t.Fatalf("spend=%s want=0.002 (only win event)", spend)
+	}
+}
@@ -0,0 +1,212 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package adsource
+
+import (
+	"sync"
+
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+const defaultBidHistoryWindow = 100
+
+// EarlyExitPolicy decides if the auction can be finished before all the requested sources respond
+type EarlyExitPolicy interface {
+	// Observe the response of the source (including late ones)
+	Observe(response adtype.Response)
+
+	// Complete returns true if the pending sources are not needed anymore
+	Complete(progress *AuctionProgress) bool
+}
+
+// AuctionProgress describes the state of the running auction
+type AuctionProgress struct {
+	request adtype.BidRequester
+	bids    []progressBid
+	pending []respSource
+}
+
+type progressBid struct {
+	impID    string
+	priority float32
+	cpm      billing.Money
+}
+
+// Request of the auction
+func (p *AuctionProgress) Request() adtype.BidRequester { return p.request }
+
+// Count returns the number of bids not lower than the impression floor
+// received from the sources with the priority not lower than minPriority
+func (p *AuctionProgress) Count(imp *adtype.Impression, minPriority float32) int {
+	count := 0
+	for _, bid := range p.bids {
+		if bid.impID == imp.ID && bid.priority >= minPriority && bid.cpm >= imp.BidFloorCPM {
+			count++
+		}
+	}
+	return count
+}
+
+// BestBid returns the maximal CPM bid for the impression
+func (p *AuctionProgress) BestBid(impID string) (cpm billing.Money) {
+	for _, bid := range p.bids {
+		if bid.impID == impID {
+			cpm = max(cpm, bid.cpm)
+		}
+	}
+	return cpm
+}
+
+// Pending returns the sources which have not responded yet
+func (p *AuctionProgress) Pending() adtype.SourceIterator {
+	return func(yield func(float32, adtype.Source) bool) {
+		for _, it := range p.pending {
+			if !yield(it.priority, it.src) {
+				return
+			}
+		}
+	}
+}
+
+func (p *AuctionProgress) dispatch(priority float32, src adtype.Source) {
+	p.pending = append(p.pending, respSource{priority: priority, src: src})
+}
+
+func (p *AuctionProgress) push(item respItem) {
+	for i, it := range p.pending {
+		if it.src == item.src {
+			p.pending = append(p.pending[:i], p.pending[i+1:]...)
+			break
+		}
+	}
+	if item.resp.Error() != nil {
+		return
+	}
+	for _, ad := range item.resp.Ads() {
+		p.bids = append(p.bids, progressBid{
+			impID:    ad.ImpressionID(),
+			priority: item.priority,
+			cpm:      ad.InternalAuctionCPMBid(),
+		})
+	}
+}
+
+// FilledExitPolicy finishes the auction once each impression has Count bids
+// above the floor from the sources with the priority not lower than MinPriority
+type FilledExitPolicy struct {
+	MinPriority float32
+}
+
+// NewFilledExitPolicy by the minimal priority of the sources
+func NewFilledExitPolicy(minPriority float32) *FilledExitPolicy {
+	return &FilledExitPolicy{MinPriority: minPriority}
+}
+
+// Observe the response of the source
+func (p *FilledExitPolicy) Observe(adtype.Response) {}
+
+// Complete returns true if all the impressions are filled
+func (p *FilledExitPolicy) Complete(progress *AuctionProgress) bool {
+	for _, imp := range progress.Request().Impressions() {
+		if progress.Count(imp, p.MinPriority) < max(imp.Count, 1) {
+			return false
+		}
+	}
+	return true
+}
+
+// BidHistoryExitPolicy finishes the auction once none of the pending sources
+// can beat the current best bids by the history of their bids.
+// The source without enough history is always waited for.
+type BidHistoryExitPolicy struct {
+	window int
+
+	mx      sync.RWMutex
+	history map[uint64]*bidHistory
+}
+
+// NewBidHistoryExitPolicy with the window of the last responses of each source
+func NewBidHistoryExitPolicy(window int) *BidHistoryExitPolicy {
+	if window <= 0 {
+		window = defaultBidHistoryWindow
+	}
+	return &BidHistoryExitPolicy{window: window, history: map[uint64]*bidHistory{}}
+}
+
+// Observe the response of the source and remember its maximal bid
+func (p *BidHistoryExitPolicy) Observe(response adtype.Response) {
+	src := response.Source()
+	if src == nil {
+		return
+	}
+	var maxBid billing.Money
+	if response.Error() == nil {
+		for _, ad := range response.Ads() {
+			maxBid = max(maxBid, ad.InternalAuctionCPMBid())
+		}
+	}
+
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	history := p.history[src.ID()]
+	if history == nil {
+		history = &bidHistory{bids: make([]billing.Money, 0, p.window)}
+		p.history[src.ID()] = history
+	}
+	history.push(maxBid)
+}
+
+// Complete returns true if the historical maximal bids of the pending sources
+// are not higher than the current best bids of all impressions
+func (p *BidHistoryExitPolicy) Complete(progress *AuctionProgress) bool {
+	var bestBid billing.Money = -1
+	for _, imp := range progress.Request().Impressions() {
+		if cpm := progress.BestBid(imp.ID); bestBid < 0 || cpm < bestBid {
+			bestBid = cpm
+		}
+	}
+	if bestBid <= 0 {
+		return false
+	}
+
+	p.mx.RLock()
+	defer p.mx.RUnlock()
+	for _, src := range progress.Pending() {
+		history := p.history[src.ID()]
+		if history == nil || len(history.bids) < p.window || history.max() > bestBid {
+			return false
+		}
+	}
+	return true
+}
+
+// bidHistory is the ring of the maximal bids of the last responses
+type bidHistory struct {
+	bids []billing.Money
+	pos  int
+}
+
+func (h *bidHistory) push(bid billing.Money) {
+	if len(h.bids) < cap(h.bids) {
+		h.bids = append(h.bids, bid)
+		return
+	}
+	h.bids[h.pos] = bid
+	h.pos = (h.pos + 1) % len(h.bids)
+}
+
+func (h *bidHistory) max() (bid billing.Money) {
+	for _, it := range h.bids {
+		bid = max(bid, it)
+	}
+	return bid
+}
+
+var (
+	_ EarlyExitPolicy = (*FilledExitPolicy)(nil)
+	_ EarlyExitPolicy = (*BidHistoryExitPolicy)(nil)
+)
@@ -0,0 +1,159 @@
+package adsource
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/geniusrabbit/adcorelib/adquery/bidresponse"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/eventtraking/events"
+	"github.com/geniusrabbit/adcorelib/eventtraking/eventstream"
+)
+
+type recordEvent struct {
+	event    events.Type
+	status   uint8
+	sourceID uint64
+	impID    string
+	err      error
+}
+
+type recordStream struct {
+	testStream
+	mx     sync.Mutex
+	events []recordEvent
+}
+
+func (s *recordStream) Send(event events.Type, status uint8, response adtype.Response, it adtype.ResponseItem) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.events = append(s.events, recordEvent{
+		event:    event,
+		status:   status,
+		sourceID: response.Source().ID(),
+		impID:    it.ImpressionID(),
+	})
+	return nil
+}
+
+func (s *recordStream) SendSourceSkip(response adtype.Response) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.events = append(s.events, recordEvent{
+		event:    events.SourceSkip,
+		sourceID: response.Source().ID(),
+		err:      response.Error(),
+	})
+	return nil
+}
+
+// Events of the type
+func (s *recordStream) Events(event events.Type) (res []recordEvent) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	for _, it := range s.events {
+		if it.event == event {
+			res = append(res, it)
+		}
+	}
+	return res
+}
+
+// Statuses of the source bids
+func (s *recordStream) Statuses(sourceID uint64) (res []uint8) {
+	for _, it := range s.Events(events.SourceBid) {
+		if it.sourceID == sourceID {
+			res = append(res, it.status)
+		}
+	}
+	return res
+}
+
+func TestMultisourceWrapper_EarlyExit(t *testing.T) {
+	var (
+		fast    = &tierSource{id: 1, bids: map[string]float64{"imp1": 3., "imp2": 3., "imp3": 3.}}
+		slow    = &tierSource{id: 2, bids: map[string]float64{"imp1": 5.}, delay: 200 * time.Millisecond}
+		stream  = &recordStream{}
+		request = newWaterfallRequest()
+		wrp     = newTestWrapper(t,
+			WithSourceAccessor(&tierAccessor{tiers: [][]*tierSource{{fast}, {slow}}}),
+			WithEarlyExit(NewFilledExitPolicy(2)),
+		)
+	)
+	request.SetContext(eventstream.WithStream(context.Background(), stream))
+
+	startTime := time.Now()
+	if response := wrp.Bid(request); len(response.Ads()) != 3 {
+		t.Fatalf("ads=%d want=3", len(response.Ads()))
+	}
+	if duration := time.Since(startTime); duration >= slow.delay {
+		t.Fatalf("auction duration=%s, expected the early exit", duration)
+	}
+
+	time.Sleep(slow.delay + 50*time.Millisecond)
+	if statuses := stream.Statuses(slow.id); len(statuses) != 1 || statuses[0] != events.StatusLate {
+		t.Fatalf("slow source statuses=%v want=[late]", statuses)
+	}
+	if statuses := stream.Statuses(fast.id); len(statuses) != 3 || statuses[0] != events.StatusSuccess {
+		t.Fatalf("fast source statuses=%v", statuses)
+	}
+}
+
+func TestBidHistoryExitPolicy(t *testing.T) {
+	var (
+		policy   = NewBidHistoryExitPolicy(10)
+		src      = &tierSource{id: 1, bids: map[string]float64{"imp1": 1., "imp2": 2., "imp3": 1.5}}
+		request  = newWaterfallRequest()
+		progress = &AuctionProgress{request: request}
+	)
+	progress.dispatch(1, src)
+	progress.push(respItem{priority: 2, src: &tierSource{id: 2}, resp: bidresponse.NewResponse(request, nil,
+		[]adtype.ResponseItemCommon{
+			newTestItem(request.Impressions()[0], 3.),
+			newTestItem(request.Impressions()[1], 3.),
+			newTestItem(request.Impressions()[2], 2.5),
+		}, nil)})
+
+	for range 9 {
+		policy.Observe(src.Bid(request))
+	}
+	if policy.Complete(progress) {
+		t.Fatal("the source without enough history must be waited for")
+	}
+	policy.Observe(src.Bid(request))
+	if !policy.Complete(progress) {
+		t.Fatal("the source can't beat the current bids")
+	}
+
+	src.bids["imp1"] = 2.6
+	policy.Observe(src.Bid(request))
+	if policy.Complete(progress) {
+		t.Fatal("the source can beat the current bids")
+	}
+}
+
+func TestFilledExitPolicy(t *testing.T) {
+	var (
+		policy   = NewFilledExitPolicy(2)
+		request  = newWaterfallRequest()
+		imps     = request.Impressions()
+		progress = &AuctionProgress{request: request}
+	)
+	progress.push(respItem{priority: 1, resp: bidresponse.NewResponse(request, nil,
+		[]adtype.ResponseItemCommon{newTestItem(imps[0], 3.), newTestItem(imps[1], 3.), newTestItem(imps[2], 3.)}, nil)})
+	if policy.Complete(progress) {
+		t.Fatal("bids of the low priority source must be ignored")
+	}
+	progress.push(respItem{priority: 2, resp: bidresponse.NewResponse(request, nil,
+		[]adtype.ResponseItemCommon{newTestItem(imps[0], 3.), newTestItem(imps[1], 1.), newTestItem(imps[2], 3.)}, nil)})
+	if policy.Complete(progress) {
+		t.Fatal("the bid below the floor must be ignored")
+	}
+	progress.push(respItem{priority: 2, resp: bidresponse.NewResponse(request, nil,
+		[]adtype.ResponseItemCommon{newTestItem(imps[1], 2.)}, nil)})
+	if !policy.Complete(progress) {
+		t.Fatal("expected all the impressions filled")
+	}
+}
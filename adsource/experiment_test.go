@@ -0,0 +1,68 @@
+package adsource
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/geniusrabbit/adcorelib/adsource/experiments"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+func TestMultisourceWrapper_Experiment(t *testing.T) {
+	var (
+		control = &tierSource{id: 1, bids: map[string]float64{"imp1": 1.}}
+		variant = &tierSource{id: 2, bids: map[string]float64{"imp1": 1.}}
+	)
+	exp, err := experiments.NewExperiment("exp1", []*experiments.Arm{{
+		ID:       "variant",
+		Share:    1,
+		Sources:  &tierAccessor{tiers: [][]*tierSource{{variant}}},
+		BidFloor: billing.MoneyFloat(0.5),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrp := newTestWrapper(t,
+		WithSourceAccessor(&tierAccessor{tiers: [][]*tierSource{{control}}}),
+		WithExperiment(exp))
+
+	request := newWaterfallRequest()
+	if response := wrp.Bid(request); len(response.Ads()) != 1 {
+		t.Fatalf("ads=%d want=1", len(response.Ads()))
+	}
+	if len(control.requested) != 0 || len(variant.requested) != 1 {
+		t.Fatalf("control requested=%v variant requested=%v", control.requested, variant.requested)
+	}
+	if arm := adtype.ExperimentArmFromRequest(request); arm == nil || arm.ArmID != "variant" {
+		t.Fatalf("request arm: %+v", arm)
+	}
+	if floor := request.Impressions()[0].BidFloorCPM; floor != billing.MoneyFloat(0.5) {
+		t.Fatalf("imp1 floor=%v want=0.5", floor.Float64())
+	}
+}
+
+func TestMultisourceWrapper_ExperimentTimeout(t *testing.T) {
+	var (
+		control = &tierAccessor{}
+		variant = &tierAccessor{}
+		fixed   = &tierAccessor{}
+	)
+	exp, err := experiments.NewExperiment("exp1", []*experiments.Arm{
+		{ID: "variant", Share: 1, Sources: variant},
+		{ID: "fixed", Share: 1, Sources: fixed, Timeout: 300 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrp := newTestWrapper(t, WithSourceAccessor(control), WithExperiment(exp))
+	wrp.SetRequestTimeout(context.Background(), 200*time.Millisecond)
+
+	if control.timeout != 200*time.Millisecond || variant.timeout != 200*time.Millisecond {
+		t.Fatalf("control timeout=%v variant timeout=%v want=200ms", control.timeout, variant.timeout)
+	}
+	if fixed.timeout != 300*time.Millisecond {
+		t.Fatalf("fixed timeout=%v want=300ms", fixed.timeout)
+	}
+}
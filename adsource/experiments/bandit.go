@@ -0,0 +1,235 @@
+package experiments
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"sync"
+)
+
+const defaultExplorationFloor = 0.05
+
+var errBanditSnapshotArms = errors.New("[experiments] snapshot arms count mismatch")
+
+// RewardStrategy is the index strategy which learns by the rewards of the chosen indexes
+type RewardStrategy interface {
+	IndexStrategy
+
+	// Reward of the index (e.g. the win price of the source)
+	Reward(index uint, reward float64)
+}
+
+// BanditStrategy learns which variant earns the most revenue per request
+type BanditStrategy interface {
+	RewardStrategy
+
+	// Arms returns the state of the variants
+	Arms() []ArmState
+
+	// WriteSnapshot of the posterior state
+	WriteSnapshot(w io.Writer) error
+
+	// ReadSnapshot and replace the posterior state
+	ReadSnapshot(r io.Reader) error
+}
+
+// ArmState of the bandit variant
+type ArmState struct {
+	Pulls   uint64  `json:"pulls"`
+	Wins    uint64  `json:"wins"`
+	Rewards float64 `json:"rewards"`
+}
+
+// BanditOption of the bandit strategies
+type BanditOption func(b *bandit)
+
+// WithExplorationFloor sets the part of the traffic distributed uniformly across the variants
+func WithExplorationFloor(floor float64) BanditOption {
+	return func(b *bandit) {
+		b.explorationFloor = min(max(floor, 0), 1)
+	}
+}
+
+// bandit keeps the state of the arms which is common for all the bandit strategies
+type bandit struct {
+	mx               sync.Mutex
+	arms             []ArmState
+	maxReward        float64 // Maximal average win reward of the arms
+	explorationFloor float64
+}
+
+func newBandit(arms int, opts ...BanditOption) *bandit {
+	b := &bandit{arms: make([]ArmState, max(arms, 1)), explorationFloor: defaultExplorationFloor}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Reward of the arm
+func (b *bandit) Reward(index uint, reward float64) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	arm := &b.arms[index%uint(len(b.arms))]
+	arm.Wins++
+	arm.Rewards += reward
+	b.maxReward = max(b.maxReward, arm.Rewards/float64(arm.Wins))
+}
+
+// Arms returns the copy of the arms state
+func (b *bandit) Arms() []ArmState {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	return append([]ArmState(nil), b.arms...)
+}
+
+// WriteSnapshot of the arms state in JSON format
+func (b *bandit) WriteSnapshot(w io.Writer) error {
+	return json.NewEncoder(w).Encode(b.Arms())
+}
+
+// ReadSnapshot and replace the arms state
+func (b *bandit) ReadSnapshot(r io.Reader) error {
+	var arms []ArmState
+	if err := json.NewDecoder(r).Decode(&arms); err != nil {
+		return err
+	}
+	if len(arms) != len(b.arms) {
+		return errBanditSnapshotArms
+	}
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.arms = arms
+	b.maxReward = 0
+	for _, arm := range arms {
+		if arm.Wins > 0 {
+			b.maxReward = max(b.maxReward, arm.Rewards/float64(arm.Wins))
+		}
+	}
+	return nil
+}
+
+// index chooses the arm by the score function with the exploration floor
+func (b *bandit) index(score func(arm *ArmState, total uint64) float64) uint {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	index := 0
+	if b.explorationFloor > 0 && rand.Float64() < b.explorationFloor {
+		index = rand.IntN(len(b.arms))
+	} else {
+		var total uint64
+		for i := range b.arms {
+			total += b.arms[i].Pulls
+		}
+		bestScore := math.Inf(-1)
+		for i := range b.arms {
+			if s := score(&b.arms[i], total); s > bestScore {
+				index, bestScore = i, s
+			}
+		}
+	}
+	b.arms[index].Pulls++
+	return uint(index)
+}
+
+// averageWinReward of all the arms (1 if there are no wins)
+func (b *bandit) averageWinReward() float64 {
+	var (
+		wins    uint64
+		rewards float64
+	)
+	for _, arm := range b.arms {
+		wins += arm.Wins
+		rewards += arm.Rewards
+	}
+	if wins == 0 || rewards <= 0 {
+		return 1
+	}
+	return rewards / float64(wins)
+}
+
+// Thompson sampling strategy.
+// The expected revenue per request of the variant is the win rate sampled from
+// the Beta posterior multiplied by the average win reward of the variant.
+type thompsonStrategy struct {
+	*bandit
+}
+
+// NewThompsonStrategy for the number of variants
+func NewThompsonStrategy(arms int, opts ...BanditOption) BanditStrategy {
+	return &thompsonStrategy{bandit: newBandit(arms, opts...)}
+}
+
+func (st *thompsonStrategy) GetIndex() uint {
+	var avgReward float64
+	return st.index(func(arm *ArmState, _ uint64) float64 {
+		winReward := 0.
+		if arm.Wins > 0 {
+			winReward = arm.Rewards / float64(arm.Wins)
+		} else {
+			if avgReward == 0 {
+				avgReward = st.averageWinReward()
+			}
+			winReward = avgReward
+		}
+		losses := float64(arm.Pulls) - float64(arm.Wins)
+		return betaSample(float64(arm.Wins)+1, max(losses, 0)+1) * winReward
+	})
+}
+
+// UCB1 strategy by the revenue per request normalized by the maximal average win reward
+type ucb1Strategy struct {
+	*bandit
+}
+
+// NewUCB1Strategy for the number of variants
+func NewUCB1Strategy(arms int, opts ...BanditOption) BanditStrategy {
+	return &ucb1Strategy{bandit: newBandit(arms, opts...)}
+}
+
+func (st *ucb1Strategy) GetIndex() uint {
+	return st.index(func(arm *ArmState, total uint64) float64 {
+		if arm.Pulls == 0 {
+			return math.Inf(1)
+		}
+		mean := 0.
+		if st.maxReward > 0 {
+			mean = arm.Rewards / float64(arm.Pulls) / st.maxReward
+		}
+		return mean + math.Sqrt(2*math.Log(float64(total))/float64(arm.Pulls))
+	})
+}
+
+// betaSample from the Beta(a, b) distribution
+func betaSample(a, b float64) float64 {
+	x := gammaSample(a)
+	return x / (x + gammaSample(b))
+}
+
+// gammaSample from the Gamma(shape, 1) distribution (Marsaglia and Tsang method)
+func gammaSample(shape float64) float64 {
+	if shape < 1 {
+		return gammaSample(shape+1) * math.Pow(rand.Float64(), 1/shape)
+	}
+	d := shape - 1./3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := rand.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := rand.Float64()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
+
+var (
+	_ BanditStrategy = (*thompsonStrategy)(nil)
+	_ BanditStrategy = (*ucb1Strategy)(nil)
+)
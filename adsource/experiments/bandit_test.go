@@ -0,0 +1,126 @@
+package experiments
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/geniusrabbit/adcorelib/adquery/bidrequest"
+	"github.com/geniusrabbit/adcorelib/adquery/bidresponse"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/adtype/prices"
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+func simulateBandit(strategy RewardStrategy, winRates []float64, requests int) []int {
+	pulls := make([]int, len(winRates))
+	for range requests {
+		index := strategy.GetIndex()
+		pulls[index]++
+		if rand.Float64() < winRates[index] {
+			strategy.Reward(index, 2)
+		}
+	}
+	return pulls
+}
+
+func TestBanditStrategies(t *testing.T) {
+	const requests = 10000
+	tests := []struct {
+		name     string
+		strategy BanditStrategy
+	}{
+		{name: "thompson", strategy: NewThompsonStrategy(2, WithExplorationFloor(0.1))},
+		{name: "ucb1", strategy: NewUCB1Strategy(2, WithExplorationFloor(0.1))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pulls := simulateBandit(tt.strategy, []float64{0.1, 0.3}, requests)
+			if pulls[1] < requests*6/10 {
+				t.Fatalf("pulls=%v, expected the convergence to the better variant", pulls)
+			}
+			// Half of the exploration traffic goes to the worse variant
+			if pulls[0] < requests*4/100 {
+				t.Fatalf("pulls=%v, expected the exploration floor", pulls)
+			}
+		})
+	}
+}
+
+func TestBanditSnapshot(t *testing.T) {
+	var (
+		buf      bytes.Buffer
+		strategy = NewThompsonStrategy(3)
+		restored = NewUCB1Strategy(3)
+	)
+	simulateBandit(strategy, []float64{0.1, 0.2, 0.3}, 1000)
+	if err := strategy.WriteSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.ReadSnapshot(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	for i, arm := range strategy.Arms() {
+		if restored.Arms()[i] != arm {
+			t.Fatalf("arm %d: %+v != %+v", i, restored.Arms()[i], arm)
+		}
+	}
+	if err := NewUCB1Strategy(2).ReadSnapshot(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Fatal("expected the arms count mismatch error")
+	}
+}
+
+type testSource struct {
+	adtype.Source
+	id uint64
+}
+
+func (s *testSource) ID() uint64 { return s.id }
+
+func TestSourceWrapper_Reward(t *testing.T) {
+	var (
+		strategy     = NewUCB1Strategy(2, WithExplorationFloor(0))
+		sources      = []adtype.Source{&testSource{id: 1}, &testSource{id: 2}}
+		wrapper, err = NewStrategySourceWrapper(strategy, sources...)
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 100 {
+		src := wrapper.Next()
+		if src.ID() == 2 {
+			wrapper.Reward(src, billing.MoneyFloat(1.))
+		}
+	}
+	if arms := strategy.Arms(); arms[1].Wins == 0 || arms[1].Pulls <= arms[0].Pulls {
+		t.Fatalf("arms=%+v", arms)
+	}
+	if _, err := NewStrategySourceWrapper(NewUCB1Strategy(3), sources...); err == nil {
+		t.Fatal("expected the arms count mismatch error")
+	}
+}
+
+func TestSourceWrapper_ProcessResponse(t *testing.T) {
+	var (
+		strategy     = NewThompsonStrategy(2)
+		sources      = []adtype.Source{&testSource{id: 1}, &testSource{id: 2}}
+		wrapper, err = NewStrategySourceWrapper(strategy, sources...)
+		imp          = &adtype.Impression{ID: "imp"}
+		request      = &bidrequest.BidRequest{Imps: []*adtype.Impression{imp}}
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := &bidresponse.ResponseItemBlank{
+		Imp:        imp,
+		Src:        sources[1],
+		PriceScope: prices.PriceScope{ECPM: billing.MoneyFloat(2.)},
+	}
+	wrapper.ProcessResponse(bidresponse.NewResponse(request, sources[1], []adtype.ResponseItemCommon{item}, nil))
+	wrapper.ProcessResponse(bidresponse.NewEmptyResponse(request, sources[0], adtype.ErrResponseNoBid))
+
+	arms := strategy.Arms()
+	if arms[0].Wins != 0 || arms[1].Wins != 1 || arms[1].Rewards != 2 {
+		t.Fatalf("arms=%+v", arms)
+	}
+}
@@ -0,0 +1,144 @@
+package experiments
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"math/rand/v2"
+	"time"
+
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+var errExperimentNoArms = errors.New("[experiments] experiment has no arms with the traffic share")
+
+// ResponsePreprocessor of the arm responses
+type ResponsePreprocessor interface {
+	PreprocessResponse(response adtype.Response) (adtype.Response, error)
+}
+
+// KeyFunc returns the key of the sticky assignment (empty key means the random assignment)
+type KeyFunc func(request adtype.BidRequester) string
+
+// UserKey of the sticky assignment by the user ID (or the session if the user is unknown)
+func UserKey(request adtype.BidRequester) string {
+	if user := request.UserInfo(); user != nil {
+		if user.ID != "" {
+			return user.ID
+		}
+		return user.SessionID
+	}
+	return ""
+}
+
+// SessionKey of the sticky assignment by the session ID
+func SessionKey(request adtype.BidRequester) string {
+	if user := request.UserInfo(); user != nil {
+		return user.SessionID
+	}
+	return ""
+}
+
+// Arm of the experiment with the traffic share and the overrides of the auction settings.
+// Zero values of the overrides mean the default settings.
+type Arm struct {
+	ID           string
+	Share        float64 // Relative part of the traffic
+	Sources      adtype.SourceAccessor
+	Timeout      time.Duration
+	Preprocessor ResponsePreprocessor
+	BidFloor     billing.Money // Minimal bid floor CPM of the impressions
+}
+
+// Experiment splits the traffic between the arms by the sticky key
+type Experiment struct {
+	id    string
+	arms  []*Arm
+	total float64
+	key   KeyFunc
+}
+
+// ExperimentOption of the experiment
+type ExperimentOption func(e *Experiment)
+
+// WithExperimentKey sets the key of the sticky assignment (UserKey by default)
+func WithExperimentKey(key KeyFunc) ExperimentOption {
+	return func(e *Experiment) {
+		e.key = key
+	}
+}
+
+// NewExperiment with the arms
+func NewExperiment(id string, arms []*Arm, opts ...ExperimentOption) (*Experiment, error) {
+	e := &Experiment{id: id, key: UserKey}
+	for _, arm := range arms {
+		if arm != nil && arm.Share > 0 {
+			e.arms = append(e.arms, arm)
+			e.total += arm.Share
+		}
+	}
+	if len(e.arms) == 0 {
+		return nil, errExperimentNoArms
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e, nil
+}
+
+// ID of the experiment
+func (e *Experiment) ID() string { return e.id }
+
+// SetTimeout of the arm sources. The arms with their own timeout keep it.
+func (e *Experiment) SetTimeout(ctx context.Context, timeout time.Duration) {
+	for _, arm := range e.arms {
+		if arm.Sources == nil {
+			continue
+		}
+		if arm.Timeout > 0 {
+			arm.Sources.SetTimeout(ctx, arm.Timeout)
+		} else {
+			arm.Sources.SetTimeout(ctx, timeout)
+		}
+	}
+}
+
+// Assign the arm to the request. The same key is always assigned to the same arm
+// until the shares of the arms are changed.
+func (e *Experiment) Assign(request adtype.BidRequester) *Arm {
+	var point float64
+	if key := e.key(request); key != "" {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(e.id))
+		_, _ = h.Write([]byte{':'})
+		_, _ = h.Write([]byte(key))
+		point = float64(h.Sum64()%10000) / 10000 * e.total
+	} else {
+		point = rand.Float64() * e.total
+	}
+	for _, arm := range e.arms {
+		if point < arm.Share {
+			return arm
+		}
+		point -= arm.Share
+	}
+	return e.arms[len(e.arms)-1]
+}
+
+// Apply the experiment to the request: assigns the arm, stamps it into the request Ext
+// (see adtype.ExperimentArmFromRequest) and raises the bid floors of impressions.
+func (e *Experiment) Apply(request adtype.BidRequester) *Arm {
+	arm := e.Assign(request)
+	request.Set(adtype.ExtExperimentArm, &adtype.ExperimentArm{ExperimentID: e.id, ArmID: arm.ID})
+	if arm.BidFloor > 0 {
+		request.ImpressionUpdate(func(imp *adtype.Impression) bool {
+			if imp.BidFloorCPM >= arm.BidFloor {
+				return false
+			}
+			imp.BidFloorCPM = arm.BidFloor
+			return true
+		})
+	}
+	return arm
+}
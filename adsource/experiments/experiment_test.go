@@ -0,0 +1,70 @@
+package experiments
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/geniusrabbit/adcorelib/adquery/bidrequest"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+func newExperimentRequest(userID, sessionID string) *bidrequest.BidRequest {
+	return &bidrequest.BidRequest{
+		Imps: []*adtype.Impression{
+			{ID: "imp1", Count: 1},
+			{ID: "imp2", Count: 1, BidFloorCPM: billing.MoneyFloat(3.)},
+		},
+		User: &adtype.User{ID: userID, SessionID: sessionID},
+	}
+}
+
+func TestExperimentAssign(t *testing.T) {
+	exp, err := NewExperiment("exp1", []*Arm{{ID: "a", Share: 1}, {ID: "b", Share: 3}, {ID: "off", Share: 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int{}
+	for i := range 4000 {
+		request := newExperimentRequest(fmt.Sprintf("user%d", i), "")
+		arm := exp.Assign(request)
+		if exp.Assign(request) != arm {
+			t.Fatalf("user%d: assignment is not sticky", i)
+		}
+		counts[arm.ID]++
+	}
+	if counts["off"] != 0 || counts["a"] < 800 || counts["a"] > 1200 {
+		t.Fatalf("shares: %v", counts)
+	}
+
+	// The session is the key of the unknown user
+	arm := exp.Assign(newExperimentRequest("", "session1"))
+	if exp.Assign(newExperimentRequest("", "session1")) != arm {
+		t.Fatal("session assignment is not sticky")
+	}
+
+	if _, err := NewExperiment("exp2", []*Arm{{ID: "off"}}); err == nil {
+		t.Fatal("expected the error of the experiment without arms")
+	}
+}
+
+func TestExperimentApply(t *testing.T) {
+	exp, err := NewExperiment("exp1", []*Arm{{ID: "floor", Share: 1, BidFloor: billing.MoneyFloat(2.)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := newExperimentRequest("user1", "")
+	exp.Apply(request)
+
+	arm := adtype.ExperimentArmFromRequest(request)
+	if arm == nil || arm.ExperimentID != "exp1" || arm.ArmID != "floor" {
+		t.Fatalf("request arm: %+v", arm)
+	}
+	imps := request.Impressions()
+	if imps[0].BidFloorCPM != billing.MoneyFloat(2.) {
+		t.Fatalf("imp1 floor=%v, expected the arm floor", imps[0].BidFloorCPM.Float64())
+	}
+	if imps[1].BidFloorCPM != billing.MoneyFloat(3.) {
+		t.Fatalf("imp2 floor=%v, expected the impression floor", imps[1].BidFloorCPM.Float64())
+	}
+}
@@ -1,11 +1,15 @@
 package experiments
 
 import (
+	"errors"
 	"time"
 
 	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
 )
 
+var errBanditSourcesArms = errors.New("[experiments] bandit arms count doesn't match the sources count")
+
 // SourceMultiWrapper implements source functionality with source choicer
 type sourceMultiWrapper struct {
 	sources       []adtype.Source
@@ -28,6 +32,19 @@ func NewRandomSourceWrapper(sources ...adtype.Source) SourceWrapper {
 	}
 }
 
+// NewStrategySourceWrapper with the custom index strategy.
+// The RewardStrategy (e.g. NewThompsonStrategy, NewUCB1Strategy) learns by the source rewards
+// reported by ProcessResponse, the BanditStrategy must have the arm for every source.
+func NewStrategySourceWrapper(strategy IndexStrategy, sources ...adtype.Source) (SourceWrapper, error) {
+	if bandit, _ := strategy.(BanditStrategy); bandit != nil && len(bandit.Arms()) != len(sources) {
+		return nil, errBanditSourcesArms
+	}
+	return &sourceMultiWrapper{
+		sources:       sources,
+		rotateStategy: strategy,
+	}, nil
+}
+
 // Next returns source interface according to strategy
 func (w *sourceMultiWrapper) Next() adtype.Source {
 	return w.sources[w.rotateStategy.GetIndex()%uint(len(w.sources))]
@@ -42,4 +59,30 @@ func (w *sourceMultiWrapper) SetTimeout(timeout time.Duration) {
 	}
 }
 
+// Reward of the source if the strategy learns by the rewards
+func (w *sourceMultiWrapper) Reward(source adtype.Source, reward billing.Money) {
+	strategy, _ := w.rotateStategy.(RewardStrategy)
+	if strategy == nil || source == nil {
+		return
+	}
+	for i, src := range w.sources {
+		if src == source || src.ID() == source.ID() {
+			strategy.Reward(uint(i), reward.Float64())
+			return
+		}
+	}
+}
+
+// ProcessResponse rewards the sources of the won ads by their ECPM
+func (w *sourceMultiWrapper) ProcessResponse(response adtype.Response) {
+	if response == nil || response.Error() != nil {
+		return
+	}
+	for ad := range response.IterAds() {
+		if ad != nil && ad.Validate() == nil {
+			w.Reward(ad.Source(), ad.ECPM())
+		}
+	}
+}
+
 var _ SourceWrapper = (*sourceMultiWrapper)(nil)
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
 )
 
 type sourceSimpleWrapper struct {
@@ -27,4 +28,10 @@ func (w *sourceSimpleWrapper) SetTimeout(timeout time.Duration) {
 	}
 }
 
+// Reward of the source (nothing to learn for the single source)
+func (w *sourceSimpleWrapper) Reward(adtype.Source, billing.Money) {}
+
+// ProcessResponse of the source (nothing to learn for the single source)
+func (w *sourceSimpleWrapper) ProcessResponse(adtype.Response) {}
+
 var _ SourceWrapper = (*sourceSimpleWrapper)(nil)
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
 )
 
 // SourceWrapper advertisement accessor interface
@@ -13,4 +14,12 @@ type SourceWrapper interface {
 
 	// SetTimeout for sourcer
 	SetTimeout(timeout time.Duration)
+
+	// Reward of the source returned by Next (e.g. the win price)
+	Reward(source adtype.Source, reward billing.Money)
+
+	// ProcessResponse rewards the sources of the won ads by their ECPM.
+	// The owner of the wrapper must call it on the win path (e.g. from its own
+	// ProcessResponse), otherwise the learning strategy never receives the wins.
+	ProcessResponse(response adtype.Response)
 }
@@ -0,0 +1,328 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+// Package floors computes the bid floors of the impressions before the sources are requested.
+//
+// The floor is chosen by the static rules first (the most specific rule, the zone floor
+// of the country or the default zone floor) and then multiplied by the optional learned
+// multiplier of the recent bid landscape:
+//
+//	engine := floors.New(
+//		floors.WithZones(zones),
+//		floors.WithRules(rules...),
+//		floors.WithLearner(floors.NewLandscape()),
+//	)
+//	engine.Apply(request)
+//
+// The decisions are stored in the request (see DecisionsFromRequest) and in the
+// auction trace of the debug requests.
+package floors
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/geniusrabbit/adcorelib/adsource/auctiontrace"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/adtype/prices"
+	"github.com/geniusrabbit/adcorelib/billing"
+	"github.com/geniusrabbit/adcorelib/models"
+)
+
+// ExtFloorDecisions is the request Ext key of the floor decisions of the impressions
+const ExtFloorDecisions = "floor_decisions"
+
+// Sources of the floor
+const (
+	SourceNone    = ""
+	SourceRequest = "request"
+	SourceZone    = "zone"
+	SourceZoneGeo = "zone_geo"
+	SourceRule    = "rule"
+)
+
+// Key of the impression which is used for the rules and the learned multipliers
+type Key struct {
+	ZoneID     uint64 `json:"zone_id,omitempty"`
+	Country    string `json:"country,omitempty"`
+	DeviceType int    `json:"device_type,omitempty"`
+	Format     string `json:"format,omitempty"`
+	Hour       int    `json:"hour"`
+}
+
+// KeyOf the impression of the request.
+// The hour is taken in the time zone of the request geo.
+func KeyOf(request adtype.BidRequester, imp *adtype.Impression) Key {
+	var key Key
+	if imp.Target != nil {
+		key.ZoneID = imp.Target.ID()
+	}
+	if geo := request.GeoInfo(); geo != nil {
+		key.Country = strings.ToUpper(geo.Country)
+	}
+	if device := request.DeviceInfo(); device != nil {
+		key.DeviceType = int(device.DeviceType)
+	}
+	if formats := imp.Formats(); len(formats) > 0 {
+		key.Format = formats[0].Codename
+	}
+	key.Hour = request.CurrentGeoTime().Hour()
+	return key
+}
+
+// ZoneFloors of the zone (see models.Zone MinECPM and MinECPMByGeo)
+type ZoneFloors struct {
+	MinECPM billing.Money
+	ByGeo   map[string]billing.Money
+}
+
+// ZoneFloorsFromModel converts the floors of the zone model
+func ZoneFloorsFromModel(zone *models.Zone) *ZoneFloors {
+	if zone == nil {
+		return nil
+	}
+	floors := &ZoneFloors{MinECPM: billing.MoneyFloat(zone.MinECPM)}
+	for country, ecpm := range zone.MinECPMByGeo.DataOr(nil) {
+		if floors.ByGeo == nil {
+			floors.ByGeo = map[string]billing.Money{}
+		}
+		floors.ByGeo[strings.ToUpper(country)] = billing.MoneyFloat(ecpm)
+	}
+	return floors
+}
+
+// ZoneAccessor returns the floors of the zone by ID or nil
+type ZoneAccessor interface {
+	ZoneFloors(zoneID uint64) *ZoneFloors
+}
+
+// ZoneMap is the static ZoneAccessor
+type ZoneMap map[uint64]*ZoneFloors
+
+// ZoneFloors returns the floors of the zone by ID or nil
+func (m ZoneMap) ZoneFloors(zoneID uint64) *ZoneFloors { return m[zoneID] }
+
+// Rule of the static floor. The empty conditions match any value.
+// The most specific matching rule (with the most conditions) is used,
+// the first one of the same specificity wins.
+type Rule struct {
+	Name        string
+	ZoneIDs     []uint64
+	Countries   []string // ISO 3166-1 Alpha 2
+	DeviceTypes []int
+	Formats     []string // Format codenames
+	Hours       []int    // Hours of the day in the time zone of the request geo
+	FloorCPM    billing.Money
+}
+
+func (r *Rule) match(key Key) bool {
+	return (len(r.ZoneIDs) == 0 || slices.Contains(r.ZoneIDs, key.ZoneID)) &&
+		(len(r.Countries) == 0 || slices.ContainsFunc(r.Countries, func(c string) bool { return strings.EqualFold(c, key.Country) })) &&
+		(len(r.DeviceTypes) == 0 || slices.Contains(r.DeviceTypes, key.DeviceType)) &&
+		(len(r.Formats) == 0 || slices.Contains(r.Formats, key.Format)) &&
+		(len(r.Hours) == 0 || slices.Contains(r.Hours, key.Hour))
+}
+
+func (r *Rule) specificity() (n int) {
+	for _, l := range []int{len(r.ZoneIDs), len(r.Countries), len(r.DeviceTypes), len(r.Formats), len(r.Hours)} {
+		if l > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// Learner of the floor multipliers by the observed bid landscape
+type Learner interface {
+	// Multiplier of the static floor of the key (1 means no correction)
+	Multiplier(key Key, floor billing.Money) float64
+
+	// Observe the bid CPM of the key, zero CPM is the no-fill
+	Observe(key Key, cpm billing.Money)
+}
+
+// Decision of the impression floor with the explanation
+type Decision struct {
+	ImpID      string        `json:"imp_id"`
+	Key        Key           `json:"key"`
+	Source     string        `json:"source,omitempty"`
+	Rule       string        `json:"rule,omitempty"`
+	Static     billing.Money `json:"static"`
+	Multiplier float64       `json:"multiplier"`
+	Floor      billing.Money `json:"floor"`
+	Reason     string        `json:"reason"`
+}
+
+// Engine of the dynamic floors
+type Engine struct {
+	zones   ZoneAccessor
+	rules   []Rule
+	learner Learner
+}
+
+// Option of the engine
+type Option func(e *Engine)
+
+// WithZones sets the accessor of the zone floors
+func WithZones(zones ZoneAccessor) Option {
+	return func(e *Engine) {
+		e.zones = zones
+	}
+}
+
+// WithRules adds the static floor rules
+func WithRules(rules ...Rule) Option {
+	return func(e *Engine) {
+		e.rules = append(e.rules, rules...)
+	}
+}
+
+// WithLearner sets the learner of the floor multipliers
+func WithLearner(learner Learner) Option {
+	return func(e *Engine) {
+		e.learner = learner
+	}
+}
+
+// New floor engine
+func New(opts ...Option) *Engine {
+	e := &Engine{}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Floor decision of the impression of the request
+func (e *Engine) Floor(request adtype.BidRequester, imp *adtype.Impression) Decision {
+	key := KeyOf(request, imp)
+	decision := Decision{ImpID: imp.ID, Key: key, Multiplier: 1}
+
+	// Static floor: the most specific rule, the zone floor of the country or the default zone floor
+	if rule := e.rule(key); rule != nil {
+		decision.Source, decision.Rule, decision.Static = SourceRule, rule.Name, rule.FloorCPM
+	} else if e.zones != nil && key.ZoneID != 0 {
+		if zone := e.zones.ZoneFloors(key.ZoneID); zone != nil {
+			if floor, ok := zone.ByGeo[key.Country]; ok {
+				decision.Source, decision.Static = SourceZoneGeo, floor
+			} else if zone.MinECPM > 0 {
+				decision.Source, decision.Static = SourceZone, zone.MinECPM
+			}
+		}
+	}
+
+	floor := decision.Static
+	if e.learner != nil && floor > 0 {
+		if decision.Multiplier = e.learner.Multiplier(key, floor); decision.Multiplier != 1 {
+			floor = billing.MoneyFloat(floor.Float64() * decision.Multiplier)
+		}
+	}
+
+	// The floor of the request is never lowered
+	if decision.Floor = floor; imp.BidFloorCPM >= floor {
+		decision.Floor = imp.BidFloorCPM
+		if imp.BidFloorCPM > 0 {
+			decision.Source, decision.Rule = SourceRequest, ""
+		}
+	}
+	decision.Reason = decision.reason()
+	return decision
+}
+
+// Apply the floors to the impressions of the request
+func (e *Engine) Apply(request adtype.BidRequester) []Decision {
+	if e == nil || request == nil {
+		return nil
+	}
+	var (
+		trace     = auctiontrace.FromRequest(request)
+		decisions = make([]Decision, 0, len(request.Impressions()))
+	)
+	for _, imp := range request.Impressions() {
+		decision := e.Floor(request, imp)
+		imp.BidFloorCPM = decision.Floor
+		decisions = append(decisions, decision)
+		trace.Floor(decision.ImpID, decision.Floor, decision.Reason)
+	}
+	request.Set(ExtFloorDecisions, decisions)
+	return decisions
+}
+
+// Observe the bid landscape of the source response to learn the multipliers.
+// All the bids of the response are observed including the losing ones, the impressions
+// without the bids (no-bids and the bids below the floor dropped by the source)
+// are observed as the no-fills. The skipped and the failed responses are ignored.
+func (e *Engine) Observe(response adtype.Response) {
+	if e == nil || e.learner == nil || response == nil || response.Request() == nil {
+		return
+	}
+	if err := response.Error(); err != nil && !errors.Is(err, adtype.ErrResponseNoBid) {
+		return
+	}
+	var (
+		request = response.Request()
+		filled  = map[string]bool{}
+	)
+	for ad := range response.IterAds() {
+		if imp := ad.Impression(); imp != nil {
+			e.learner.Observe(KeyOf(request, imp), bidCPM(ad))
+			filled[imp.ID] = true
+		}
+	}
+	for _, imp := range request.Impressions() {
+		if !filled[imp.ID] {
+			e.learner.Observe(KeyOf(request, imp), 0)
+		}
+	}
+}
+
+// DecisionsFromRequest returns the floor decisions of the request impressions
+func DecisionsFromRequest(request adtype.BidRequester) []Decision {
+	if request == nil {
+		return nil
+	}
+	decisions, _ := request.Get(ExtFloorDecisions).([]Decision)
+	return decisions
+}
+
+func (e *Engine) rule(key Key) (rule *Rule) {
+	best := -1
+	for i := range e.rules {
+		if spec := e.rules[i].specificity(); spec > best && e.rules[i].match(key) {
+			rule, best = &e.rules[i], spec
+		}
+	}
+	return rule
+}
+
+func (d *Decision) reason() string {
+	var reason string
+	switch d.Source {
+	case SourceRequest:
+		reason = "floor of the request"
+	case SourceRule:
+		reason = fmt.Sprintf("rule %q", d.Rule)
+	case SourceZone:
+		reason = fmt.Sprintf("zone %d", d.Key.ZoneID)
+	case SourceZoneGeo:
+		reason = fmt.Sprintf("zone %d country %s", d.Key.ZoneID, d.Key.Country)
+	default:
+		return "no floor"
+	}
+	if d.Source != SourceRequest && d.Multiplier != 1 {
+		reason += fmt.Sprintf(" %s x%.3f", d.Static, d.Multiplier)
+	}
+	return reason
+}
+
+// bidCPM returns the current CPM of the ad
+func bidCPM(ad adtype.ResponseItem) billing.Money {
+	if price := ad.Price(adtype.ActionImpression); price > 0 {
+		return prices.CPMFromPrice(price)
+	}
+	return ad.ECPM()
+}
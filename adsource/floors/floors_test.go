@@ -0,0 +1,154 @@
+package floors
+
+import (
+	"testing"
+
+	"github.com/geniusrabbit/gosql/v2"
+	"github.com/geniusrabbit/udetect"
+
+	"github.com/geniusrabbit/adcorelib/adquery/bidrequest"
+	"github.com/geniusrabbit/adcorelib/adquery/bidresponse"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
+	"github.com/geniusrabbit/adcorelib/models"
+)
+
+type zoneTarget struct {
+	adtype.TargetEmpty
+	id uint64
+}
+
+func (z *zoneTarget) ID() uint64 { return z.id }
+
+type bidItem struct {
+	adtype.ResponseItemEmpty
+	cpm billing.Money
+}
+
+func (it *bidItem) Price(action adtype.Action) billing.Money {
+	if action == adtype.ActionImpression {
+		return it.cpm / 1000
+	}
+	return 0
+}
+
+func bidResponse(request *bidrequest.BidRequest, cpm billing.Money) adtype.Response {
+	if cpm <= 0 {
+		return bidresponse.NewEmptyResponse(request, nil, adtype.ErrResponseNoBid)
+	}
+	item := &bidItem{ResponseItemEmpty: adtype.ResponseItemEmpty{Imp: request.Imps[0], Req: request}, cpm: cpm}
+	return bidresponse.NewResponse(request, nil, []adtype.ResponseItemCommon{item}, nil)
+}
+
+func newRequest(country string, deviceType udetect.DeviceType, floor billing.Money) *bidrequest.BidRequest {
+	return &bidrequest.BidRequest{
+		Imps:   []*adtype.Impression{{ID: "imp1", Target: &zoneTarget{id: 1}, BidFloorCPM: floor}},
+		User:   &adtype.User{Geo: &udetect.Geo{Country: country}},
+		Device: &udetect.Device{DeviceType: deviceType},
+	}
+}
+
+func TestEngineStaticFloors(t *testing.T) {
+	zone := ZoneFloorsFromModel(&models.Zone{
+		MinECPM:      1,
+		MinECPMByGeo: *gosql.MustNullableJSON[map[string]float64](map[string]float64{"us": 2}),
+	})
+	engine := New(
+		WithZones(ZoneMap{1: zone}),
+		WithRules(
+			Rule{Name: "mobile", DeviceTypes: []int{int(udetect.DeviceTypeMobile)}, FloorCPM: billing.MoneyInt(3)},
+			Rule{Name: "mobile-de", Countries: []string{"de"}, DeviceTypes: []int{int(udetect.DeviceTypeMobile)}, FloorCPM: billing.MoneyInt(4)},
+		),
+	)
+	tests := []struct {
+		name    string
+		request *bidrequest.BidRequest
+		source  string
+		floor   billing.Money
+	}{
+		{name: "zone_geo", request: newRequest("US", 0, 0), source: SourceZoneGeo, floor: billing.MoneyInt(2)},
+		{name: "zone", request: newRequest("FR", 0, 0), source: SourceZone, floor: billing.MoneyInt(1)},
+		{name: "rule", request: newRequest("FR", udetect.DeviceTypeMobile, 0), source: SourceRule, floor: billing.MoneyInt(3)},
+		{name: "specific_rule", request: newRequest("DE", udetect.DeviceTypeMobile, 0), source: SourceRule, floor: billing.MoneyInt(4)},
+		{name: "request", request: newRequest("US", 0, billing.MoneyInt(5)), source: SourceRequest, floor: billing.MoneyInt(5)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decisions := engine.Apply(tt.request)
+			if len(decisions) != 1 || decisions[0].Source != tt.source || decisions[0].Floor != tt.floor {
+				t.Fatalf("decisions: %+v", decisions)
+			}
+			if floor := tt.request.Imps[0].BidFloorCPM; floor != tt.floor {
+				t.Fatalf("impression floor: %s != %s", floor, tt.floor)
+			}
+			if stored := DecisionsFromRequest(tt.request); len(stored) != 1 || stored[0].Reason == "" {
+				t.Fatalf("stored decisions: %+v", stored)
+			}
+		})
+	}
+}
+
+func TestEngineLearnedMultiplier(t *testing.T) {
+	var (
+		learner = NewLandscape(WithLandscapeWindow(10, 5))
+		engine  = New(
+			WithZones(ZoneMap{1: {MinECPM: billing.MoneyInt(2)}}),
+			WithLearner(learner),
+		)
+		request = newRequest("US", 0, 0)
+		key     = KeyOf(request, request.Imps[0])
+	)
+	for range 4 {
+		learner.Observe(key, billing.MoneyInt(3))
+	}
+	if decision := engine.Floor(request, request.Imps[0]); decision.Multiplier != 1 || decision.Floor != billing.MoneyInt(2) {
+		t.Fatalf("not enough samples: %+v", decision)
+	}
+
+	learner.Observe(key, billing.MoneyInt(3))
+	decision := engine.Floor(request, request.Imps[0])
+	if decision.Multiplier != 1.5 || decision.Floor != billing.MoneyInt(3) {
+		t.Fatalf("learned floor: %+v", decision)
+	}
+
+	// The static floor is never lowered by default
+	for range 10 {
+		learner.Observe(key, billing.MoneyInt(1))
+	}
+	if decision := engine.Floor(request, request.Imps[0]); decision.Multiplier != 1 || decision.Floor != billing.MoneyInt(2) {
+		t.Fatalf("lowered floor: %+v", decision)
+	}
+}
+
+func TestEngineObserveBidLandscape(t *testing.T) {
+	var (
+		engine = New(
+			WithZones(ZoneMap{1: {MinECPM: billing.MoneyInt(2)}}),
+			WithLearner(NewLandscape(WithLandscapeWindow(10, 5), WithLandscapeQuantile(0.5))),
+		)
+		request = newRequest("US", 0, 0)
+	)
+	for range 10 {
+		engine.Observe(bidResponse(request, billing.MoneyInt(4)))
+	}
+	if decision := engine.Floor(request, request.Imps[0]); decision.Multiplier != 2 {
+		t.Fatalf("learned floor: %+v", decision)
+	}
+
+	// The bids fall, so the floor goes down
+	for range 10 {
+		engine.Observe(bidResponse(request, billing.MoneyInt(3)))
+	}
+	if decision := engine.Floor(request, request.Imps[0]); decision.Multiplier != 1.5 {
+		t.Fatalf("floor after the bids fall: %+v", decision)
+	}
+
+	// The no-fills and the skipped responses
+	for range 6 {
+		engine.Observe(bidResponse(request, 0))
+		engine.Observe(bidresponse.NewEmptyResponse(request, nil, adtype.ErrResponseSkipped))
+	}
+	if decision := engine.Floor(request, request.Imps[0]); decision.Multiplier != 1 || decision.Floor != billing.MoneyInt(2) {
+		t.Fatalf("floor after the no-fills: %+v", decision)
+	}
+}
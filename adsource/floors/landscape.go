@@ -0,0 +1,128 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package floors
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+const (
+	defaultLandscapeWindow     = 200
+	defaultLandscapeMinSamples = 20
+	defaultLandscapeQuantile   = 0.2
+	defaultLandscapeMaxFactor  = 2.
+)
+
+// Landscape learns the floor multipliers from the recent bid landscape of the key.
+// The multiplier is the quantile of the recent bids divided by the static floor
+// limited by [MinFactor, MaxFactor]. The multiplier is 1 until the key has enough samples.
+//
+// The no-fills (no-bids and the bids below the floor) are observed as the zero bids,
+// so the multiplier goes back to MinFactor when the fill falls below the quantile.
+type Landscape struct {
+	mx    sync.Mutex
+	stats map[Key]*landscapeWindow
+
+	window     int
+	minSamples int
+	quantile   float64
+	minFactor  float64
+	maxFactor  float64
+}
+
+// LandscapeOption of the landscape learner
+type LandscapeOption func(l *Landscape)
+
+// WithLandscapeWindow sets the number of the recent bids of the key (200 by default)
+func WithLandscapeWindow(window, minSamples int) LandscapeOption {
+	return func(l *Landscape) {
+		l.window = max(window, 1)
+		l.minSamples = min(max(minSamples, 1), l.window)
+	}
+}
+
+// WithLandscapeQuantile sets the quantile of the bids (0.2 by default)
+func WithLandscapeQuantile(quantile float64) LandscapeOption {
+	return func(l *Landscape) {
+		l.quantile = min(max(quantile, 0), 1)
+	}
+}
+
+// WithLandscapeFactors limits the multiplier ([1, 2] by default, so the static floor is never lowered)
+func WithLandscapeFactors(minFactor, maxFactor float64) LandscapeOption {
+	return func(l *Landscape) {
+		l.minFactor = max(minFactor, 0)
+		l.maxFactor = max(maxFactor, l.minFactor)
+	}
+}
+
+// NewLandscape learner of the floor multipliers
+func NewLandscape(opts ...LandscapeOption) *Landscape {
+	l := &Landscape{
+		stats:      map[Key]*landscapeWindow{},
+		window:     defaultLandscapeWindow,
+		minSamples: defaultLandscapeMinSamples,
+		quantile:   defaultLandscapeQuantile,
+		minFactor:  1,
+		maxFactor:  defaultLandscapeMaxFactor,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Multiplier of the static floor of the key
+func (l *Landscape) Multiplier(key Key, floor billing.Money) float64 {
+	if floor <= 0 {
+		return 1
+	}
+	l.mx.Lock()
+	stat := l.stats[key]
+	if stat == nil || len(stat.prices) < l.minSamples {
+		l.mx.Unlock()
+		return 1
+	}
+	prices := slices.Clone(stat.prices)
+	l.mx.Unlock()
+
+	slices.Sort(prices)
+	quantile := prices[int(float64(len(prices)-1)*l.quantile)]
+	return min(max(quantile.Float64()/floor.Float64(), l.minFactor), l.maxFactor)
+}
+
+// Observe the bid CPM of the key, zero CPM is the no-fill
+func (l *Landscape) Observe(key Key, cpm billing.Money) {
+	cpm = max(cpm, 0)
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	stat := l.stats[key]
+	if stat == nil {
+		stat = &landscapeWindow{prices: make([]billing.Money, 0, l.window)}
+		l.stats[key] = stat
+	}
+	stat.push(cpm, l.window)
+}
+
+// landscapeWindow is the ring buffer of the recent bids
+type landscapeWindow struct {
+	prices []billing.Money
+	next   int
+}
+
+func (w *landscapeWindow) push(cpm billing.Money, size int) {
+	if len(w.prices) < size {
+		w.prices = append(w.prices, cpm)
+		return
+	}
+	w.prices[w.next] = cpm
+	w.next = (w.next + 1) % size
+}
+
+var _ Learner = (*Landscape)(nil)
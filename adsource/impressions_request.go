@@ -0,0 +1,110 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package adsource
+
+import (
+	"slices"
+
+	"github.com/geniusrabbit/adcorelib/admodels/types"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+// impressionsRequest restricts the bid request to the subset of impressions.
+// It's used by the waterfall strategy to request the next tier of sources
+// only for the unfilled impressions.
+type impressionsRequest struct {
+	adtype.BidRequester
+	imps         []*adtype.Impression
+	targetIDs    []uint64
+	extTargetIDs []string
+}
+
+func newImpressionsRequest(request adtype.BidRequester, imps []*adtype.Impression) *impressionsRequest {
+	req := &impressionsRequest{BidRequester: request, imps: imps}
+	for _, imp := range imps {
+		if imp.Target != nil && !slices.Contains(req.targetIDs, imp.Target.ID()) {
+			req.targetIDs = append(req.targetIDs, imp.Target.ID())
+		}
+		if imp.ExternalTargetID != "" && !slices.Contains(req.extTargetIDs, imp.ExternalTargetID) {
+			req.extTargetIDs = append(req.extTargetIDs, imp.ExternalTargetID)
+		}
+	}
+	return req
+}
+
+// WithFormats returns the request with the formats accessor and the same impressions subset
+func (r *impressionsRequest) WithFormats(formats types.FormatsAccessor) adtype.BidRequester {
+	request := r.BidRequester.WithFormats(formats)
+	if request == nil {
+		return nil
+	}
+	imps := make([]*adtype.Impression, 0, len(r.imps))
+	for _, imp := range r.imps {
+		if it := request.ImpressionByID(imp.ID); it != nil {
+			imps = append(imps, it)
+		}
+	}
+	return newImpressionsRequest(request, imps)
+}
+
+// MinECPM returns the max of bid floors of the impressions
+func (r *impressionsRequest) MinECPM() (minBid billing.Money) {
+	for _, imp := range r.imps {
+		minBid = max(minBid, imp.BidFloorCPM)
+	}
+	return minBid
+}
+
+// TargetID returns the target ID if there is exactly one impression with a target
+func (r *impressionsRequest) TargetID() uint64 {
+	if len(r.imps) == 1 && r.imps[0].Target != nil {
+		return r.imps[0].Target.ID()
+	}
+	return 0
+}
+
+// TargetIDs returns unique target IDs of the impressions
+func (r *impressionsRequest) TargetIDs() []uint64 { return r.targetIDs }
+
+// ExtTargetIDs returns unique external target IDs of the impressions
+func (r *impressionsRequest) ExtTargetIDs() []string { return r.extTargetIDs }
+
+// Impressions of the subset
+func (r *impressionsRequest) Impressions() []*adtype.Impression { return r.imps }
+
+// ImpressionUpdate applies the function to each impression of the subset
+func (r *impressionsRequest) ImpressionUpdate(fn func(imp *adtype.Impression) bool) {
+	for _, imp := range r.imps {
+		fn(imp)
+	}
+}
+
+// ImpressionByID returns the impression of the subset
+func (r *impressionsRequest) ImpressionByID(id string) *adtype.Impression {
+	for _, imp := range r.imps {
+		if imp.ID == id {
+			return imp
+		}
+	}
+	return nil
+}
+
+// TargetPointers returns the target pointers of the subset impressions
+func (r *impressionsRequest) TargetPointers() []types.TargetPointer {
+	var pointers []types.TargetPointer
+	for _, pointer := range r.BidRequester.TargetPointers() {
+		targetID, extTargetID := pointer.TargetID(), pointer.ExtarnalTargetID()
+		if (targetID == 0 && extTargetID == "") ||
+			(targetID != 0 && slices.Contains(r.targetIDs, targetID)) ||
+			(extTargetID != "" && slices.Contains(r.extTargetIDs, extTargetID)) {
+			pointers = append(pointers, pointer)
+		}
+	}
+	return pointers
+}
+
+var _ adtype.BidRequester = (*impressionsRequest)(nil)
@@ -0,0 +1,141 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package adsource
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/geniusrabbit/adcorelib/adtype"
+)
+
+// Default parameters of the load shedding
+const (
+	defaultShedSoftLimit       = 0.7
+	defaultShedHardLimit       = 0.95
+	defaultShedMaxQueueLatency = 5 * time.Millisecond
+	queueLatencySmoothing      = 0.1
+)
+
+// Skip reasons of the load shedding
+var (
+	ErrSourceShedByLoad = adtype.ErrResponseSkipped.WithMessage("source is shed by the load")
+	ErrSourcePoolFull   = adtype.ErrResponseSkipped.WithMessage("execution pool is full")
+)
+
+// Reason codes of the shedding metrics
+const (
+	shedReasonLoad     = "load"
+	shedReasonPoolFull = "pool_full"
+)
+
+var loadLevelGauge = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "multisource_load_level",
+	Help: "Load level of the execution pool (0 – idle, 1 – saturated)",
+})
+
+// loadShedder controls the admission of source requests to the execution pool.
+//
+// The load level is the maximum of the pool saturation (tasks in process by the limit)
+// and the smoothed queue latency by the maximal one. Above the soft limit the sources
+// with the lower priority (relative to the main one which comes first) are shed,
+// on the hard limit only the main source is requested.
+type loadShedder struct {
+	softLimit       float64
+	hardLimit       float64
+	maxQueueLatency time.Duration
+
+	queueLatency atomic.Int64 // Smoothed queue latency in nanoseconds
+}
+
+// LoadSheddingOption of the load shedding
+type LoadSheddingOption func(s *loadShedder)
+
+// WithShedLimits sets the load levels of the start of shedding and the main-source-only mode
+func WithShedLimits(soft, hard float64) LoadSheddingOption {
+	return func(s *loadShedder) {
+		s.softLimit, s.hardLimit = soft, hard
+	}
+}
+
+// WithShedMaxQueueLatency sets the queue latency which is considered as the full load
+func WithShedMaxQueueLatency(latency time.Duration) LoadSheddingOption {
+	return func(s *loadShedder) {
+		s.maxQueueLatency = latency
+	}
+}
+
+func newLoadShedder(opts ...LoadSheddingOption) *loadShedder {
+	s := &loadShedder{
+		softLimit:       defaultShedSoftLimit,
+		hardLimit:       defaultShedHardLimit,
+		maxQueueLatency: defaultShedMaxQueueLatency,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.softLimit = min(max(s.softLimit, 0), 1)
+	s.hardLimit = min(max(s.hardLimit, s.softLimit), 1)
+	if s.maxQueueLatency <= 0 {
+		s.maxQueueLatency = defaultShedMaxQueueLatency
+	}
+	return s
+}
+
+// Level of the load by the pool state
+func (s *loadShedder) Level(inProcess int64, limit int) float64 {
+	level := float64(s.queueLatency.Load()) / float64(s.maxQueueLatency)
+	if limit > 0 {
+		level = max(level, float64(inProcess)/float64(limit))
+	}
+	return min(level, 1)
+}
+
+// ObserveQueueLatency of the task start in the pool
+func (s *loadShedder) ObserveQueueLatency(latency time.Duration) {
+	for {
+		prev := s.queueLatency.Load()
+		next := int64(float64(prev)*(1-queueLatencySmoothing) + float64(latency)*queueLatencySmoothing)
+		if s.queueLatency.CompareAndSwap(prev, next) {
+			break
+		}
+	}
+}
+
+// admission of the sources for one request by the current load level
+func (s *loadShedder) admission(level float64) *loadAdmission {
+	loadLevelGauge.Set(level)
+	ratio := 0.
+	switch {
+	case level >= s.hardLimit:
+		ratio = 1
+	case level > s.softLimit:
+		ratio = (level - s.softLimit) / (s.hardLimit - s.softLimit)
+	}
+	return &loadAdmission{ratio: ratio, mainPriority: float32(math.NaN())}
+}
+
+type loadAdmission struct {
+	ratio        float64
+	mainPriority float32
+}
+
+// Allow returns the skip reason if the source must be shed
+func (a *loadAdmission) Allow(priority float32) error {
+	if math.IsNaN(float64(a.mainPriority)) {
+		// The main source is never shed by the load
+		a.mainPriority = priority
+		return nil
+	}
+	if a.ratio >= 1 || (a.ratio > 0 && float64(priority) < float64(a.mainPriority)*a.ratio) {
+		return ErrSourceShedByLoad
+	}
+	return nil
+}
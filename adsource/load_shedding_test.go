@@ -0,0 +1,74 @@
+package adsource
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/geniusrabbit/adcorelib/eventtraking/events"
+	"github.com/geniusrabbit/adcorelib/eventtraking/eventstream"
+)
+
+func TestLoadShedder(t *testing.T) {
+	shedder := newLoadShedder(WithShedLimits(0.5, 0.9), WithShedMaxQueueLatency(10*time.Millisecond))
+	if level := shedder.Level(3, 10); level != 0.3 {
+		t.Fatalf("level=%f want=0.3", level)
+	}
+	for range 100 {
+		shedder.ObserveQueueLatency(7 * time.Millisecond)
+	}
+	if level := shedder.Level(3, 10); level < 0.69 || level > 0.7 {
+		t.Fatalf("level=%f want=0.7 by the queue latency", level)
+	}
+
+	tests := []struct {
+		level   float64
+		allowed []float32
+	}{
+		{level: 0.3, allowed: []float32{10, 8, 5, 1}},
+		{level: 0.7, allowed: []float32{10, 8, 5}},
+		{level: 0.9, allowed: []float32{10}},
+	}
+	for _, tt := range tests {
+		var (
+			admission = shedder.admission(tt.level)
+			allowed   []float32
+		)
+		for _, priority := range []float32{10, 8, 5, 1} {
+			if admission.Allow(priority) == nil {
+				allowed = append(allowed, priority)
+			}
+		}
+		if len(allowed) != len(tt.allowed) {
+			t.Fatalf("level=%f allowed=%v want=%v", tt.level, allowed, tt.allowed)
+		}
+	}
+}
+
+func TestMultisourceWrapper_LoadShedding(t *testing.T) {
+	var (
+		main    = &tierSource{id: 1, bids: map[string]float64{"imp1": 1.}}
+		second  = &tierSource{id: 2, bids: map[string]float64{"imp2": 3.}}
+		stream  = &recordStream{}
+		request = newWaterfallRequest()
+		wrp     = newTestWrapper(t,
+			WithSourceAccessor(&tierAccessor{tiers: [][]*tierSource{{main}, {second}}}),
+			WithLoadShedding(WithShedMaxQueueLatency(time.Millisecond)),
+		)
+	)
+	request.SetContext(eventstream.WithStream(context.Background(), stream))
+
+	// Extreme queue latency
+	wrp.loadShedder.queueLatency.Store(int64(time.Second))
+
+	if response := wrp.Bid(request); len(response.Ads()) != 1 {
+		t.Fatalf("ads=%d want=1 (main source only)", len(response.Ads()))
+	}
+	if len(second.requested) != 0 {
+		t.Fatal("the low priority source must be shed")
+	}
+	skips := stream.Events(events.SourceSkip)
+	if len(skips) != 1 || skips[0].sourceID != second.id || skips[0].err != ErrSourceShedByLoad {
+		t.Fatalf("skip events=%+v", skips)
+	}
+}
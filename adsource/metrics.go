@@ -1,48 +1,286 @@
 package adsource
 
 import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/geniusrabbit/adcorelib/adtype"
-
 	"github.com/demdxx/gocast/v2"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/openlatency"
 )
 
-// Metrics wrapper
+// Default parameters of the metrics
+const (
+	defaultMetricsMaxSources = 100
+	defaultMetricsWindow     = time.Minute
+	metricsOtherSource       = "other"
+)
+
+// Optional labels of the metrics in addition to the source ID
+const (
+	MetricLabelDeviceType  = "device_type"
+	MetricLabelAuctionType = "auction_type"
+)
+
+// Results of the source responses
+const (
+	metricResultBid     = "bid"
+	metricResultNoBid   = "nobid"
+	metricResultSkip    = "skip"
+	metricResultTimeout = "timeout"
+	metricResultError   = "error"
+)
+
+// Metrics of the source requests: latency histograms, response results, error classes,
+// bid CPM and wins. The metrics are exported to Prometheus and kept in the memory
+// for the last window to describe the source (see FillSourceMetrics).
+//
+// The cardinality of the source label is bounded by the max sources count,
+// the rest of the sources are reported as "other".
 type Metrics struct {
-	requestCounter *prometheus.CounterVec
-}
-
-// IncrementBidRequestCount metric
-func (m *Metrics) IncrementBidRequestCount(source adtype.Source, request adtype.BidRequester, duration time.Duration) {
-	m.counter().WithLabelValues(
-		gocast.Str(source.ID()),
-		gocast.Str(request.TargetID()),
-		gocast.Str(request.DeviceInfo().DeviceType),
-		request.AuctionType().Name(),
-		"0",
-	).Inc()
-}
-
-// IncrementBidErrorCount metric
-func (m *Metrics) IncrementBidErrorCount(source adtype.Source, request adtype.BidRequester, err error) {
-	m.counter().WithLabelValues(
-		gocast.Str(source.ID()),
-		gocast.Str(request.TargetID()),
-		gocast.Str(request.DeviceInfo().DeviceType),
-		request.AuctionType().Name(),
-		gocast.IfThen(err != nil, "1", "0"),
-	).Inc()
-}
-
-func (m *Metrics) counter() *prometheus.CounterVec {
-	if m.requestCounter == nil {
-		m.requestCounter = promauto.NewCounterVec(prometheus.CounterOpts{
-			Name: "multisource_count",
-			Help: "Count of requests by source params",
-		}, []string{"source_id", "zone_id", "device_type", "auction_type", "error"})
-	}
-	return m.requestCounter
+	registerer prometheus.Registerer
+	maxSources int
+	labels     []string
+	window     time.Duration
+
+	latency   *prometheus.HistogramVec
+	responses *prometheus.CounterVec
+	errors    *prometheus.CounterVec
+	bidCPM    *prometheus.HistogramVec
+	wins      *prometheus.CounterVec
+	shed      *prometheus.CounterVec
+
+	sourceLabels     sync.Map // map[uint64]string
+	sourceLabelCount atomic.Int32
+	stats            sync.Map // map[uint64]*sourceStats
+}
+
+// MetricsOption of the metrics
+type MetricsOption func(m *Metrics)
+
+// WithMetricsRegisterer sets the Prometheus registerer (default is prometheus.DefaultRegisterer)
+func WithMetricsRegisterer(registerer prometheus.Registerer) MetricsOption {
+	return func(m *Metrics) {
+		m.registerer = registerer
+	}
+}
+
+// WithMetricsMaxSources sets the maximal number of the source label values
+func WithMetricsMaxSources(maxSources int) MetricsOption {
+	return func(m *Metrics) {
+		m.maxSources = maxSources
+	}
+}
+
+// WithMetricsLabels adds the optional labels (MetricLabelDeviceType, MetricLabelAuctionType)
+// to the latency and response metrics
+func WithMetricsLabels(labels ...string) MetricsOption {
+	return func(m *Metrics) {
+		m.labels = slices.DeleteFunc(labels, func(label string) bool {
+			return label != MetricLabelDeviceType && label != MetricLabelAuctionType
+		})
+	}
+}
+
+// WithMetricsWindow sets the window of the in-memory source stats
+func WithMetricsWindow(window time.Duration) MetricsOption {
+	return func(m *Metrics) {
+		m.window = window
+	}
+}
+
+// NewMetrics of the source requests
+func NewMetrics(opts ...MetricsOption) *Metrics {
+	m := &Metrics{
+		registerer: prometheus.DefaultRegisterer,
+		maxSources: defaultMetricsMaxSources,
+		window:     defaultMetricsWindow,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.window <= 0 {
+		m.window = defaultMetricsWindow
+	}
+	sourceLabels := append([]string{"source_id"}, m.labels...)
+	m.latency = registerCollector(m.registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "multisource_source_latency_seconds",
+		Help:    "Latency of the source responses",
+		Buckets: []float64{.005, .01, .025, .05, .075, .1, .15, .2, .3, .5, 1},
+	}, sourceLabels))
+	m.responses = registerCollector(m.registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "multisource_source_responses_total",
+		Help: "Count of the source responses by the result (bid, nobid, skip, timeout, error)",
+	}, append(sourceLabels, "result")))
+	m.errors = registerCollector(m.registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "multisource_source_errors_total",
+		Help: "Count of the source errors by the class",
+	}, []string{"source_id", "class"}))
+	m.bidCPM = registerCollector(m.registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "multisource_source_bid_cpm",
+		Help:    "CPM of the source bids",
+		Buckets: []float64{.05, .1, .25, .5, 1, 2, 5, 10, 20, 50},
+	}, []string{"source_id"}))
+	m.wins = registerCollector(m.registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "multisource_source_wins_total",
+		Help: "Count of the source bids which won the auction",
+	}, []string{"source_id"}))
+	m.shed = registerCollector(m.registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "multisource_source_shed_total",
+		Help: "Count of the sources skipped by the load shedding by the reason",
+	}, []string{"source_id", "reason"}))
+	return m
+}
+
+// ObserveResponse of the source with the response latency
+func (m *Metrics) ObserveResponse(source adtype.Source, request adtype.BidRequester, response adtype.Response, latency time.Duration) {
+	if m == nil || source == nil || isNil(response) {
+		return
+	}
+	var (
+		err         = response.Error()
+		result      = metricResultBid
+		errClass    openlatency.MetricErrorType
+		sourceLabel = m.sourceLabel(source.ID())
+		labels      = m.labelValues(sourceLabel, request)
+		bids        []float64
+	)
+	switch {
+	case err == nil && len(response.Ads()) > 0:
+		for _, ad := range response.Ads() {
+			if cpm := ad.InternalAuctionCPMBid(); cpm > 0 {
+				bids = append(bids, cpm.Float64())
+			}
+		}
+	case err == nil, errors.Is(err, adtype.ErrResponseNoBid), errors.Is(err, adtype.ErrResponseEmpty):
+		result = metricResultNoBid
+	case errors.Is(err, adtype.ErrResponseSkipped):
+		result = metricResultSkip
+	default:
+		if errClass = responseErrorClass(err); errClass == openlatency.MetricErrorTimeout {
+			result = metricResultTimeout
+		} else {
+			result = metricResultError
+		}
+		m.errors.WithLabelValues(sourceLabel, string(errClass)).Inc()
+	}
+
+	if result != metricResultSkip {
+		m.latency.WithLabelValues(labels...).Observe(latency.Seconds())
+	}
+	m.responses.WithLabelValues(append(labels, result)...).Inc()
+	for _, cpm := range bids {
+		m.bidCPM.WithLabelValues(sourceLabel).Observe(cpm)
+	}
+	m.sourceStats(source.ID()).observe(time.Now(), m.window, result, errClass, latency, bids)
+}
+
+// ObserveWin of the source response item
+func (m *Metrics) ObserveWin(source adtype.Source) {
+	if m == nil || source == nil {
+		return
+	}
+	m.wins.WithLabelValues(m.sourceLabel(source.ID())).Inc()
+	m.sourceStats(source.ID()).win(time.Now(), m.window)
+}
+
+// ObserveShed of the source skipped by the load shedding
+func (m *Metrics) ObserveShed(source adtype.Source, reason string) {
+	if m == nil || source == nil {
+		return
+	}
+	m.shed.WithLabelValues(m.sourceLabel(source.ID()), reason).Inc()
+}
+
+// FillSourceMetrics sets the rates of the source by the last window stats.
+// Latency values are set only if the source doesn't report them itself.
+func (m *Metrics) FillSourceMetrics(sourceID uint64, info *openlatency.MetricsInfo) {
+	if m == nil || info == nil {
+		return
+	}
+	if stats, ok := m.stats.Load(sourceID); ok {
+		stats.(*sourceStats).fill(time.Now(), m.window, info)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+/// Internal methods
+///////////////////////////////////////////////////////////////////////////////
+
+func (m *Metrics) sourceLabel(sourceID uint64) string {
+	if label, ok := m.sourceLabels.Load(sourceID); ok {
+		return label.(string)
+	}
+	if m.maxSources > 0 && int(m.sourceLabelCount.Add(1)) > m.maxSources {
+		m.sourceLabelCount.Add(-1)
+		return metricsOtherSource
+	}
+	label, loaded := m.sourceLabels.LoadOrStore(sourceID, gocast.Str(sourceID))
+	if loaded {
+		m.sourceLabelCount.Add(-1)
+	}
+	return label.(string)
+}
+
+func (m *Metrics) labelValues(sourceLabel string, request adtype.BidRequester) []string {
+	values := make([]string, 0, len(m.labels)+2)
+	values = append(values, sourceLabel)
+	for _, label := range m.labels {
+		switch label {
+		case MetricLabelDeviceType:
+			values = append(values, gocast.Str(request.DeviceInfo().DeviceType))
+		case MetricLabelAuctionType:
+			values = append(values, request.AuctionType().Name())
+		}
+	}
+	return values
+}
+
+func (m *Metrics) sourceStats(sourceID uint64) *sourceStats {
+	stats, ok := m.stats.Load(sourceID)
+	if !ok {
+		stats, _ = m.stats.LoadOrStore(sourceID, &sourceStats{})
+	}
+	return stats.(*sourceStats)
+}
+
+// responseErrorClass returns the class of the response error
+func responseErrorClass(err error) openlatency.MetricErrorType {
+	var (
+		timeoutErr interface{ Timeout() bool }
+		netErr     interface{ Temporary() bool }
+	)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &timeoutErr) && timeoutErr.Timeout():
+		return openlatency.MetricErrorTimeout
+	case errors.Is(err, adtype.ErrResponseInvalidRequest):
+		return openlatency.MetricErrorInvalid
+	case errors.As(err, &netErr):
+		return openlatency.MetricErrorNetwork
+	}
+	return openlatency.MetricErrorOther
+}
+
+// registerCollector or returns the already registered one
+func registerCollector[T prometheus.Collector](registerer prometheus.Registerer, collector T) T {
+	if registerer == nil {
+		return collector
+	}
+	if err := registerer.Register(collector); err != nil {
+		var regErr prometheus.AlreadyRegisteredError
+		if errors.As(err, &regErr) {
+			if existing, ok := regErr.ExistingCollector.(T); ok {
+				return existing
+			}
+		}
+		panic(err)
+	}
+	return collector
 }
@@ -0,0 +1,142 @@
+package adsource
+
+import (
+	"sync"
+	"time"
+
+	"github.com/geniusrabbit/adcorelib/openlatency"
+)
+
+// sourceStats keeps the counters of the current and the previous windows
+type sourceStats struct {
+	mx      sync.Mutex
+	started time.Time
+	current statsWindow
+	prev    statsWindow
+}
+
+type statsWindow struct {
+	responses    int // Responses except skipped
+	bids         int
+	noBids       int
+	timeouts     int
+	errors       map[openlatency.MetricErrorType]int
+	bidItems     int
+	cpmSum       float64
+	wins         int
+	latencySum   time.Duration
+	latencyMin   time.Duration
+	latencyMax   time.Duration
+	latencyCount int
+}
+
+func (s *sourceStats) observe(now time.Time, window time.Duration, result string, errClass openlatency.MetricErrorType, latency time.Duration, bids []float64) {
+	if result == metricResultSkip {
+		return
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.rotate(now, window)
+
+	w := &s.current
+	w.responses++
+	switch result {
+	case metricResultBid:
+		w.bids++
+	case metricResultNoBid:
+		w.noBids++
+	case metricResultTimeout:
+		w.timeouts++
+	}
+	if errClass != "" {
+		if w.errors == nil {
+			w.errors = map[openlatency.MetricErrorType]int{}
+		}
+		w.errors[errClass]++
+	}
+	for _, cpm := range bids {
+		w.bidItems++
+		w.cpmSum += cpm
+	}
+	if w.latencyCount == 0 || latency < w.latencyMin {
+		w.latencyMin = latency
+	}
+	w.latencyMax = max(w.latencyMax, latency)
+	w.latencySum += latency
+	w.latencyCount++
+}
+
+func (s *sourceStats) win(now time.Time, window time.Duration) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.rotate(now, window)
+	s.current.wins++
+}
+
+// fill the metrics info by the current and the previous windows
+func (s *sourceStats) fill(now time.Time, window time.Duration, info *openlatency.MetricsInfo) {
+	s.mx.Lock()
+	s.rotate(now, window)
+	w := s.current.merge(&s.prev)
+	s.mx.Unlock()
+
+	if w.responses > 0 {
+		info.BidRate = float64(w.bids) / float64(w.responses)
+		info.NoBidRate = float64(w.noBids) / float64(w.responses)
+		info.TimeoutRate = float64(w.timeouts) / float64(w.responses)
+		info.ErrorRates = info.ErrorRates[:0]
+		for class, count := range w.errors {
+			info.ErrorRates = append(info.ErrorRates, openlatency.MetricErrorRate{
+				Type: class,
+				Rate: float64(count) / float64(w.responses),
+			})
+		}
+	}
+	if w.bidItems > 0 {
+		info.WinRate = min(float64(w.wins)/float64(w.bidItems), 1)
+		info.AvgBidCPM = w.cpmSum / float64(w.bidItems)
+	}
+	if w.latencyCount > 0 && info.AvgLatency == 0 {
+		info.MinLatency = w.latencyMin.Milliseconds()
+		info.MaxLatency = w.latencyMax.Milliseconds()
+		info.AvgLatency = (w.latencySum / time.Duration(w.latencyCount)).Milliseconds()
+	}
+}
+
+func (s *sourceStats) rotate(now time.Time, window time.Duration) {
+	switch elapsed := now.Sub(s.started); {
+	case s.started.IsZero():
+		s.started = now
+	case elapsed >= 2*window:
+		s.prev, s.current, s.started = statsWindow{}, statsWindow{}, now
+	case elapsed >= window:
+		s.prev, s.current, s.started = s.current, statsWindow{}, s.started.Add(window)
+	}
+}
+
+func (w *statsWindow) merge(other *statsWindow) statsWindow {
+	res := *w
+	res.errors = make(map[openlatency.MetricErrorType]int, len(w.errors)+len(other.errors))
+	for class, count := range w.errors {
+		res.errors[class] += count
+	}
+	for class, count := range other.errors {
+		res.errors[class] += count
+	}
+	res.responses += other.responses
+	res.bids += other.bids
+	res.noBids += other.noBids
+	res.timeouts += other.timeouts
+	res.bidItems += other.bidItems
+	res.cpmSum += other.cpmSum
+	res.wins += other.wins
+	if other.latencyCount > 0 {
+		if res.latencyCount == 0 || other.latencyMin < res.latencyMin {
+			res.latencyMin = other.latencyMin
+		}
+		res.latencyMax = max(res.latencyMax, other.latencyMax)
+	}
+	res.latencySum += other.latencySum
+	res.latencyCount += other.latencyCount
+	return res
+}
@@ -0,0 +1,95 @@
+package adsource
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/geniusrabbit/adcorelib/adquery/bidresponse"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/openlatency"
+)
+
+func TestMetrics(t *testing.T) {
+	var (
+		registry = prometheus.NewRegistry()
+		metrics  = NewMetrics(WithMetricsRegisterer(registry), WithMetricsMaxSources(1))
+		request  = newWaterfallRequest()
+		src      = &tierSource{id: 1}
+		other    = &tierSource{id: 2}
+		bid      = bidresponse.NewResponse(request, src, []adtype.ResponseItemCommon{newTestItem(request.Impressions()[0], 2.)}, nil)
+	)
+	metrics.ObserveResponse(src, request, bid, 10*time.Millisecond)
+	metrics.ObserveResponse(src, request, bid, 30*time.Millisecond)
+	metrics.ObserveResponse(src, request, bidresponse.NewEmptyResponse(request, src, adtype.ErrResponseNoBid), 20*time.Millisecond)
+	metrics.ObserveResponse(src, request, bidresponse.NewEmptyResponse(request, src, context.DeadlineExceeded), 100*time.Millisecond)
+	metrics.ObserveResponse(src, request, bidresponse.NewEmptyResponse(request, src, ErrSourceThrottled), 0)
+	metrics.ObserveResponse(other, request, bidresponse.NewEmptyResponse(request, other, adtype.ErrResponseNoBid), time.Millisecond)
+	metrics.ObserveWin(src)
+	metrics.ObserveShed(other, shedReasonLoad)
+
+	info := &openlatency.MetricsInfo{}
+	metrics.FillSourceMetrics(src.ID(), info)
+	if info.BidRate != 0.5 || info.NoBidRate != 0.25 || info.TimeoutRate != 0.25 {
+		t.Fatalf("rates: bid=%f nobid=%f timeout=%f", info.BidRate, info.NoBidRate, info.TimeoutRate)
+	}
+	if info.WinRate != 0.5 || info.AvgBidCPM != 2 {
+		t.Fatalf("win rate=%f avg bid cpm=%f", info.WinRate, info.AvgBidCPM)
+	}
+	if info.MinLatency != 10 || info.MaxLatency != 100 || info.AvgLatency != 40 {
+		t.Fatalf("latency: min=%d max=%d avg=%d", info.MinLatency, info.MaxLatency, info.AvgLatency)
+	}
+	if len(info.ErrorRates) != 1 || info.ErrorRates[0].Type != openlatency.MetricErrorTimeout {
+		t.Fatalf("error rates: %+v", info.ErrorRates)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceLabels := func(name string) (sources []string) {
+		for _, family := range families {
+			if family.GetName() != name {
+				continue
+			}
+			for _, metric := range family.GetMetric() {
+				for _, label := range metric.GetLabel() {
+					if label.GetName() == "source_id" && !slices.Contains(sources, label.GetValue()) {
+						sources = append(sources, label.GetValue())
+					}
+				}
+			}
+		}
+		slices.Sort(sources)
+		return sources
+	}
+	if sources, want := sourceLabels("multisource_source_responses_total"), []string{"1", metricsOtherSource}; !slices.Equal(sources, want) {
+		t.Fatalf("source labels=%v want=%v", sources, want)
+	}
+	if sources, want := sourceLabels("multisource_source_shed_total"), []string{metricsOtherSource}; !slices.Equal(sources, want) {
+		t.Fatalf("shed source labels=%v want=%v", sources, want)
+	}
+
+	// Metrics with the same registerer reuse the collectors
+	_ = NewMetrics(WithMetricsRegisterer(registry))
+}
+
+func TestSourceStatsWindow(t *testing.T) {
+	var (
+		stats = &sourceStats{}
+		now   = time.Unix(1000, 0)
+		info  = &openlatency.MetricsInfo{}
+	)
+	stats.observe(now, time.Minute, metricResultBid, "", time.Millisecond, []float64{1})
+	stats.observe(now.Add(time.Minute), time.Minute, metricResultNoBid, "", time.Millisecond, nil)
+	if stats.fill(now.Add(time.Minute), time.Minute, info); info.BidRate != 0.5 {
+		t.Fatalf("bid rate=%f want=0.5 with the previous window", info.BidRate)
+	}
+	info = &openlatency.MetricsInfo{}
+	if stats.fill(now.Add(3*time.Minute), time.Minute, info); info.BidRate != 0 || info.AvgLatency != 0 {
+		t.Fatalf("expired stats: %+v", info)
+	}
+}
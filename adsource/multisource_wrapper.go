@@ -38,6 +38,7 @@ package adsource
 import (
 	"context"
 	"errors"
+	"iter"
 	"reflect"
 	"strings"
 	"sync/atomic"
@@ -48,17 +49,22 @@ import (
 	"github.com/opentracing/opentracing-go/ext"
 
 	"github.com/geniusrabbit/adcorelib/adquery/bidresponse"
+	"github.com/geniusrabbit/adcorelib/adsource/auctiontrace"
+	"github.com/geniusrabbit/adcorelib/adsource/experiments"
+	"github.com/geniusrabbit/adcorelib/adsource/floors"
 	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/auction"
 	"github.com/geniusrabbit/adcorelib/auction/trafaret"
 	"github.com/geniusrabbit/adcorelib/eventtraking/events"
 	"github.com/geniusrabbit/adcorelib/eventtraking/eventstream"
-	"github.com/geniusrabbit/adcorelib/fasttime"
 	"github.com/geniusrabbit/adcorelib/gtracing"
+	"github.com/geniusrabbit/adcorelib/openlatency"
 )
 
 // Error set...
 var (
 	ErrSourcesCantBeNil = errors.New("[SSP] seurces can`t be nil")
+	ErrSourceThrottled  = adtype.ErrResponseSkipped.WithMessage("source is throttled")
 )
 
 type ResponsePreprocessor interface {
@@ -72,9 +78,15 @@ const (
 
 type respItem struct {
 	priority float32
+	src      adtype.Source
 	resp     adtype.Response
 }
 
+type respSource struct {
+	priority float32
+	src      adtype.Source
+}
+
 // MultisourceWrapper describes the abstraction which can control where to send requests
 // and how to handle responses from different sources.
 type MultisourceWrapper struct {
@@ -90,14 +102,38 @@ type MultisourceWrapper struct {
 	// Request timeout duration
 	requestTimeout time.Duration
 
+	// Per-source timeouts by the observed latency (optional)
+	adaptiveTimeout *adaptiveTimeout
+
+	// A/B experiment which overrides the auction settings by the arm (optional)
+	experiment *experiments.Experiment
+
+	// Dynamic bid floors of the impressions (optional)
+	floors *floors.Engine
+
+	// Shadow sources which receive the copy of requests without the auction participation (optional)
+	shadow *shadowTraffic
+
+	// Admission control of the execution pool (optional)
+	loadShedder *loadShedder
+
+	// Policies of the auction finish before all the sources respond (optional)
+	earlyExit []EarlyExitPolicy
+
+	// Request strategy of the sources (asynchronous or waterfall)
+	requestStrategy adtype.RequestStrategy
+
+	// Maximal time budget of one waterfall tier (optional)
+	waterfallTierTimeout time.Duration
+
 	// Maximum number of parallel requests
 	maxParallelRequest int
 
 	// Number of ad servers
 	serversCount int
 
-	// Metrics accessor
-	metrics Metrics
+	// Metrics of the source requests
+	metrics *Metrics
 }
 
 // NewMultisourceWrapper initializes a new MultisourceWrapper with the given options
@@ -120,6 +156,10 @@ func NewMultisourceWrapper(options ...Option) (*MultisourceWrapper, error) {
 		wrp.serversCount = 1
 	}
 
+	if wrp.metrics == nil {
+		wrp.metrics = NewMetrics()
+	}
+
 	return wrp, nil
 }
 
@@ -158,12 +198,14 @@ func (wrp *MultisourceWrapper) Bid(request adtype.BidRequester) (response adtype
 		return bidresponse.NewEmptyResponse(request, nil, errors.New("wrapper is nil"))
 	}
 	var (
-		count         = wrp.maxParallelRequest
-		isQueueClosed atomic.Bool
-		queue         = make(chan respItem, count)
-		span, _       = gtracing.StartSpanFromContext(request.Context(), "ssp.bid")
-		trafaret      trafaret.Filler
-		err           error
+		trace        = auctiontrace.FromRequest(request) // Traced requests only
+		span, _      = gtracing.StartSpanFromContext(request.Context(), "ssp.bid")
+		trafaret     trafaret.Filler
+		shadow       *shadowAuction
+		sources      = wrp.sources
+		timeout      = wrp.requestTimeout
+		preprocessor = wrp.responsePreprocessor
+		err          error
 	)
 
 	if span != nil {
@@ -176,86 +218,52 @@ func (wrp *MultisourceWrapper) Bid(request adtype.BidRequester) (response adtype
 		}()
 	}
 
-	// Ensure that the queue is closed when the function exits
-	defer func() {
-		isQueueClosed.Store(true)
-		close(queue)
-	}()
-
-	// Source request loop
-	for prior, src := range wrp.sources.Iterator(request) {
-		count--
-		if isQueueClosed.Load() {
-			break
+	// Override the auction settings by the experiment arm
+	if wrp.experiment != nil {
+		arm := wrp.experiment.Apply(request)
+		if arm.Sources != nil {
+			sources = arm.Sources
 		}
-		wrp.execpool.Go(func() {
-			if isQueueClosed.Load() {
-				return
-			}
-
-			startTime := fasttime.UnixTimestampNano()
-
-			// Send request to the source for the advertising
-			resp := src.Bid(request)
-
-			// Update metrics
-			wrp.metrics.IncrementBidRequestCount(src,
-				request, time.Duration(startTime-fasttime.UnixTimestampNano()))
-
-			if !isQueueClosed.Load() {
-				// Send response to the channel if it is still open
-				select {
-				case queue <- respItem{priority: prior, resp: resp}:
-					// Successfully sent to the channel
-				default:
-					// Channel is closed or full, skip sending
-				}
-			}
+		if arm.Timeout > 0 {
+			timeout = max(arm.Timeout, minimalTimeout)
+		}
+		if arm.Preprocessor != nil {
+			preprocessor = arm.Preprocessor
+		}
+	}
 
-			// Store bidding information
-			wrp.sourceResponseLog(request, resp)
+	// The bid floors must be known before the sources are requested
+	if wrp.floors != nil {
+		wrp.floors.Apply(request)
+	}
 
-			if resp.Error() != nil {
-				wrp.metrics.IncrementBidErrorCount(src, request, resp.Error())
-			}
-		})
-		if src.RequestStrategy().IsSingle() || count < 1 {
-			break
+	if wrp.shadow != nil {
+		if shadow = wrp.shadow.dispatch(request); shadow != nil {
+			defer func() { shadow.complete(response) }()
 		}
 	}
 
-	// Auction loop processing with timeout
-	if count < wrp.maxParallelRequest {
-		timer := time.NewTimer(wrp.requestTimeout)
-		defer func() {
-			if !timer.Stop() {
-				select {
-				case <-timer.C: // Drain the channel if the timer already fired
-				default:
-				}
-			}
-		}()
+	// The priority choice is reproducible by the auction seed
+	if seed, ok := auction.RequestSeed(request); ok {
+		trafaret.SetRand(auction.NewRand(seed))
+		trace.SetSeed(seed)
+	}
 
-		for ; count < wrp.maxParallelRequest; count++ {
-			select {
-			case item := <-queue:
-				if respErr := item.resp.Error(); respErr != nil {
-					err = respErr
-				} else {
-					trafaret.Push(item.priority, item.resp.Ads()...)
-				}
-			case <-timer.C:
-				count = wrp.maxParallelRequest
-			case <-request.Done():
-				count = wrp.maxParallelRequest
-			}
-		}
+	if wrp.requestStrategy.IsWaterfall() {
+		err = wrp.bidWaterfall(request, sources, timeout, &trafaret)
+	} else {
+		err = wrp.bidSources(request, sources.Iterator(request), timeout, &trafaret)
 	}
 
 	// Prepare response
 	{
 		var items []adtype.ResponseItemCommon
 		for _, imp := range request.Impressions() {
+			if trace != nil {
+				for priority, ads := range trafaret.Buckets(imp.ID) {
+					trace.Bucket(imp.ID, priority, ads)
+				}
+			}
 			if impItems := trafaret.Fill(imp.ID, imp.Count); len(impItems) > 0 {
 				items = append(items, impItems...)
 			}
@@ -265,13 +273,14 @@ func (wrp *MultisourceWrapper) Bid(request adtype.BidRequester) (response adtype
 			response = bidresponse.NewEmptyResponse(request, wrp, err)
 		} else {
 			response = bidresponse.BorrowResponse(request, nil, items, nil)
-			if wrp.responsePreprocessor != nil {
-				response, err = wrp.responsePreprocessor.PreprocessResponse(response)
+			if preprocessor != nil {
+				response, err = preprocessor.PreprocessResponse(response)
 				if err != nil {
 					response = bidresponse.NewEmptyResponse(request, wrp, err)
 				}
 			}
 		}
+		trace.Selected(response)
 	}
 
 	return response
@@ -293,6 +302,7 @@ func (wrp *MultisourceWrapper) ProcessResponse(response adtype.Response) {
 // ProcessResponseItem processes an individual response item
 func (wrp *MultisourceWrapper) ProcessResponseItem(response adtype.Response, ad adtype.ResponseItem) {
 	if src := ad.Source(); src != nil {
+		wrp.metrics.ObserveWin(src)
 		src.ProcessResponseItem(response, ad)
 	}
 }
@@ -305,9 +315,33 @@ func (wrp *MultisourceWrapper) SetRequestTimeout(ctx context.Context, timeout ti
 	if wrp.requestTimeout != timeout {
 		wrp.requestTimeout = timeout
 		wrp.sources.SetTimeout(ctx, timeout)
+		if wrp.experiment != nil {
+			wrp.experiment.SetTimeout(ctx, timeout)
+		}
+		if wrp.adaptiveTimeout != nil {
+			// Source timeouts will be recalculated by the new limit
+			wrp.adaptiveTimeout.Reset()
+		}
 	}
 }
 
+// SourceTimeout returns the current timeout of the source.
+// If the adaptive timeouts are disabled or the source has not enough
+// observations it returns the request timeout of the wrapper.
+func (wrp *MultisourceWrapper) SourceTimeout(sourceID uint64) time.Duration {
+	if wrp.adaptiveTimeout != nil {
+		if timeout := wrp.adaptiveTimeout.Timeout(sourceID); timeout > 0 {
+			return min(timeout, wrp.requestTimeout)
+		}
+	}
+	return wrp.requestTimeout
+}
+
+// FillSourceMetrics sets the rates and the latency of the source observed by the wrapper
+func (wrp *MultisourceWrapper) FillSourceMetrics(sourceID uint64, info *openlatency.MetricsInfo) {
+	wrp.metrics.FillSourceMetrics(sourceID, info)
+}
+
 // Sources returns the source accessor
 func (wrp *MultisourceWrapper) Sources() adtype.SourceAccessor {
 	return wrp.sources
@@ -315,7 +349,7 @@ func (wrp *MultisourceWrapper) Sources() adtype.SourceAccessor {
 
 // RequestStrategy returns the request strategy
 func (wrp *MultisourceWrapper) RequestStrategy() adtype.RequestStrategy {
-	return adtype.AsynchronousRequestStrategy
+	return wrp.requestStrategy
 }
 
 // RevenueShareReduceFactor returns the revenue share reduce factor
@@ -329,7 +363,259 @@ func (wrp *MultisourceWrapper) PriceCorrectionReduceFactor() float64 { return 0
 /// Internal methods
 ///////////////////////////////////////////////////////////////////////////////
 
+// bidSources sends the request to the sources in parallel and collects
+// responses into the filler until all of them respond or the timeout is over.
+// Returns the last error of the source responses.
+func (wrp *MultisourceWrapper) bidSources(request adtype.BidRequester, sources adtype.SourceIterator, timeout time.Duration, filler *trafaret.Filler) (err error) {
+	var (
+		count         = wrp.maxParallelRequest
+		isQueueClosed atomic.Bool
+		queue         = make(chan respItem, count)
+		waitTimeout   time.Duration
+		progress      *AuctionProgress
+		admission     *loadAdmission
+	)
+
+	// Responses after the function exit are logged as late ones.
+	// The queue is never closed because the late response can be sent
+	// concurrently, the buffer is enough for all the dispatched sources.
+	defer isQueueClosed.Store(true)
+
+	if len(wrp.earlyExit) > 0 {
+		progress = &AuctionProgress{request: request}
+	}
+	if wrp.loadShedder != nil {
+		admission = wrp.loadShedder.admission(
+			wrp.loadShedder.Level(wrp.execpool.InProcess(), wrp.maxParallelRequest))
+	}
+
+	// Source request loop
+	for prior, src := range sources {
+		if isQueueClosed.Load() {
+			break
+		}
+		// Skip throttled sources without the pool slot occupation
+		if throttler, _ := src.(adtype.SourceThrottler); throttler != nil && throttler.Throttle() {
+			wrp.skipSource(request, src, ErrSourceThrottled)
+			continue
+		}
+		// Shed the low priority sources under the high load
+		if admission != nil {
+			if reason := admission.Allow(prior); reason != nil {
+				wrp.metrics.ObserveShed(src, shedReasonLoad)
+				wrp.skipSource(request, src, reason)
+				continue
+			}
+		}
+		// Must be marked before the task to keep the response status in the trace
+		auctiontrace.FromRequest(request).SourceDispatched(src, prior)
+
+		dispatchTime := time.Now()
+		dispatched := wrp.execpool.Go(func() {
+			if wrp.loadShedder != nil {
+				wrp.loadShedder.ObserveQueueLatency(time.Since(dispatchTime))
+			}
+			if isQueueClosed.Load() {
+				return
+			}
+
+			startTime := time.Now()
+
+			// Send request to the source for the advertising
+			resp := src.Bid(request)
+			latency := time.Since(startTime)
+
+			// Update metrics
+			wrp.metrics.ObserveResponse(src, request, resp, latency)
+			auctiontrace.FromRequest(request).SourceResponse(src, resp, latency)
+			if wrp.adaptiveTimeout != nil {
+				wrp.adaptiveTimeout.ObserveResponse(src, resp, latency, wrp.requestTimeout)
+			}
+			wrp.floors.Observe(resp)
+
+			for _, policy := range wrp.earlyExit {
+				policy.Observe(resp)
+			}
+
+			late := isQueueClosed.Load()
+			if !late {
+				// Send response to the channel if the auction is still running
+				select {
+				case queue <- respItem{priority: prior, src: src, resp: resp}:
+					// Successfully sent to the channel
+				default:
+					// Channel is full, skip sending
+					late = true
+				}
+			}
+
+			// Store bidding information
+			if late {
+				wrp.logSourceResponse(resp, events.StatusLate)
+			} else {
+				wrp.sourceResponseLog(request, resp)
+			}
+		})
+		if !dispatched {
+			// The pool rejects the task, so there is no response to wait for
+			if wrp.loadShedder != nil {
+				wrp.metrics.ObserveShed(src, shedReasonPoolFull)
+			}
+			wrp.skipSource(request, src, ErrSourcePoolFull)
+			continue
+		}
+		count--
+		waitTimeout = max(waitTimeout, wrp.SourceTimeout(src.ID()))
+		if progress != nil {
+			progress.dispatch(prior, src)
+		}
+		if src.RequestStrategy().IsSingle() || count < 1 {
+			break
+		}
+	}
+
+	// Auction loop processing with timeout
+	if count < wrp.maxParallelRequest {
+		timer := time.NewTimer(min(timeout, wrp.waitTimeout(request, waitTimeout)))
+		defer func() {
+			if !timer.Stop() {
+				select {
+				case <-timer.C: // Drain the channel if the timer already fired
+				default:
+				}
+			}
+		}()
+
+		for ; count < wrp.maxParallelRequest; count++ {
+			select {
+			case item := <-queue:
+				if respErr := item.resp.Error(); respErr != nil {
+					err = respErr
+				} else {
+					filler.Push(item.priority, item.resp.Ads()...)
+				}
+				if progress != nil {
+					progress.push(item)
+					if count+1 < wrp.maxParallelRequest && wrp.isAuctionComplete(progress) {
+						count = wrp.maxParallelRequest
+					}
+				}
+			case <-timer.C:
+				count = wrp.maxParallelRequest
+			case <-request.Done():
+				count = wrp.maxParallelRequest
+			}
+		}
+	}
+	return err
+}
+
+// bidWaterfall requests the sources tier by tier. The tier is the sequence of
+// sources with the same priority. Every tier is limited by the timeouts of its sources,
+// the tier budget (see WithWaterfallTierTimeout) and the remaining request time,
+// so the slow tier doesn't starve the backfill tiers. The next tier receives only
+// the impressions which are not filled with bids above the floor by the previous tiers.
+//
+// The sources are pulled from the accessor lazily while the tier is dispatched,
+// so the accessor side effects (skip events, circuit breaker probes) are not
+// triggered for the tiers which are never requested. Only the first source of
+// the next tier is pulled to detect the end of the current one.
+func (wrp *MultisourceWrapper) bidWaterfall(request adtype.BidRequester, sources adtype.SourceAccessor, timeout time.Duration, filler *trafaret.Filler) (err error) {
+	var (
+		startTime   = time.Now()
+		tierRequest = request
+		next, stop  = iter.Pull2(sources.Iterator(request))
+	)
+	defer stop()
+
+	prior, src, ok := next()
+	for ok {
+		remaining := timeout - time.Since(startTime)
+		if remaining <= 0 {
+			break
+		}
+		if wrp.waterfallTierTimeout > 0 {
+			remaining = min(remaining, wrp.waterfallTierTimeout)
+		}
+		tierPriority := prior
+		tierErr := wrp.bidSources(tierRequest, func(yield func(float32, adtype.Source) bool) {
+			for ok && prior == tierPriority {
+				cont := yield(prior, src)
+				prior, src, ok = next()
+				if !cont {
+					return
+				}
+			}
+		}, remaining, filler)
+		if tierErr != nil {
+			err = tierErr
+		}
+
+		// Select impressions for the next tier
+		var unfilled []*adtype.Impression
+		for _, imp := range request.Impressions() {
+			if filler.Count(imp.ID, imp.BidFloorCPM) < max(imp.Count, 1) {
+				unfilled = append(unfilled, imp)
+			}
+		}
+		if len(unfilled) == 0 {
+			break
+		}
+		select {
+		case <-request.Done():
+			return err
+		default:
+		}
+		if len(unfilled) < len(request.Impressions()) {
+			tierRequest = newImpressionsRequest(request, unfilled)
+		}
+
+		// The rest of the tier which was not requested (e.g. by the single request strategy)
+		for ok && prior == tierPriority {
+			prior, src, ok = next()
+		}
+	}
+	return err
+}
+
+// waitTimeout of the auction loop: the slowest timeout of requested sources
+// with the minimal gap for the response delivery, capped by the remaining
+// time budget of the request.
+func (wrp *MultisourceWrapper) waitTimeout(request adtype.BidRequester, sourceTimeout time.Duration) time.Duration {
+	timeout := wrp.requestTimeout
+	if sourceTimeout > 0 {
+		timeout = min(timeout, sourceTimeout+minimalTimeout)
+	}
+	if deadline, ok := request.Context().Deadline(); ok {
+		timeout = max(min(timeout, time.Until(deadline)), 0)
+	}
+	return timeout
+}
+
+// skipSource logs the source skip with the reason
+func (wrp *MultisourceWrapper) skipSource(request adtype.BidRequester, src adtype.Source, reason error) {
+	response := bidresponse.NewEmptyResponse(request, src, reason)
+	wrp.metrics.ObserveResponse(src, request, response, 0)
+	wrp.sourceResponseLog(request, response)
+	auctiontrace.FromRequest(request).SourceSkipped(src, reason)
+}
+
+// isAuctionComplete returns true if any of the early exit policies finishes the auction
+func (wrp *MultisourceWrapper) isAuctionComplete(progress *AuctionProgress) bool {
+	for _, policy := range wrp.earlyExit {
+		if policy.Complete(progress) {
+			return true
+		}
+	}
+	return false
+}
+
 func (wrp *MultisourceWrapper) sourceResponseLog( /* bidRequest */ _ adtype.BidRequester, response adtype.Response) {
+	wrp.logSourceResponse(response, events.StatusSuccess)
+}
+
+// logSourceResponse sends the source events with the status of the successful bids
+func (wrp *MultisourceWrapper) logSourceResponse(response adtype.Response, bidStatus uint8) {
 	if isNil(response) {
 		return
 	}
@@ -346,7 +632,7 @@ func (wrp *MultisourceWrapper) sourceResponseLog( /* bidRequest */ _ adtype.BidR
 			}
 			var (
 				eventType   events.Type
-				eventStatus = events.StatusSuccess
+				eventStatus = bidStatus
 			)
 			// Check ad response item
 			if err := ad.Validate(); err != nil {
@@ -363,7 +649,7 @@ func (wrp *MultisourceWrapper) sourceResponseLog( /* bidRequest */ _ adtype.BidR
 			}
 
 			// Send event to event stream
-			_ = eventStream.Send(eventType, uint8(eventStatus), response, ad)
+			_ = eventStream.Send(eventType, eventStatus, response, ad)
 		}
 
 		// Send no bid for each empty slot (zone, adunit)
@@ -50,7 +50,7 @@ func TestMultisourceWrapper_sourceResponseLog(t *testing.T) {
 		execpool           *rpool.Pool
 		requestTimeout     time.Duration
 		maxParallelRequest int
-		metrics            Metrics
+		metrics            *Metrics
 	}
 	type args struct {
 		in0      *bidrequest.BidRequest
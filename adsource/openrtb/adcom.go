@@ -0,0 +1,278 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package openrtb
+
+// AdCOM 1.0 domain objects which are used by the OpenRTB 3.0 layer.
+// Only the subset required by the driver is defined.
+// Reference: https://github.com/InteractiveAdvertisingBureau/AdCOM/blob/main/AdCOM%20v1.0%20FINAL.md
+
+// AdCOM event types and tracking methods
+const (
+	adcomEventImpression = 1
+	adcomMethodImage     = 1
+)
+
+// AdCOM operating systems (list: Operating Systems)
+const (
+	adcomOSOther   = 0
+	adcomOSAndroid = 2
+	adcomOSIOS     = 13
+	adcomOSLinux   = 14
+	adcomOSMacOS   = 15
+	adcomOSWindows = 28
+)
+
+// adcomPlacement describes the placement of the item
+type adcomPlacement struct {
+	TagID   string                 `json:"tagid,omitempty"`
+	Secure  int                    `json:"secure,omitempty"`
+	Display *adcomDisplayPlacement `json:"display,omitempty"`
+	Video   *adcomVideoPlacement   `json:"video,omitempty"`
+}
+
+// adcomDisplayPlacement describes display placement (banner and native)
+type adcomDisplayPlacement struct {
+	Pos        int                  `json:"pos,omitempty"`
+	Instl      int                  `json:"instl,omitempty"`
+	PType      int                  `json:"ptype,omitempty"`
+	Context    int                  `json:"context,omitempty"`
+	W          int                  `json:"w,omitempty"`
+	H          int                  `json:"h,omitempty"`
+	DisplayFmt []adcomDisplayFormat `json:"displayfmt,omitempty"`
+	NativeFmt  *adcomNativeFormat   `json:"nativefmt,omitempty"`
+}
+
+// adcomDisplayFormat represents an allowed size of the display placement
+type adcomDisplayFormat struct {
+	W      int `json:"w,omitempty"`
+	H      int `json:"h,omitempty"`
+	WRatio int `json:"wratio,omitempty"`
+	HRatio int `json:"hratio,omitempty"`
+}
+
+// adcomNativeFormat describes the native assets of the placement
+type adcomNativeFormat struct {
+	Asset []adcomAssetFormat `json:"asset"`
+}
+
+// adcomAssetFormat is the requirement of the single native asset
+type adcomAssetFormat struct {
+	ID    int                  `json:"id"`
+	Req   int                  `json:"req,omitempty"`
+	Title *adcomTitleFormat    `json:"title,omitempty"`
+	Img   *adcomImageFormat    `json:"img,omitempty"`
+	Video *adcomVideoPlacement `json:"video,omitempty"`
+	Data  *adcomDataFormat     `json:"data,omitempty"`
+}
+
+type adcomTitleFormat struct {
+	Len int `json:"len"`
+}
+
+type adcomImageFormat struct {
+	Type int `json:"type,omitempty"`
+	W    int `json:"w,omitempty"`
+	H    int `json:"h,omitempty"`
+	WMin int `json:"wmin,omitempty"`
+	HMin int `json:"hmin,omitempty"`
+}
+
+type adcomDataFormat struct {
+	Type int `json:"type"`
+	Len  int `json:"len,omitempty"`
+}
+
+// adcomVideoPlacement describes video placement
+type adcomVideoPlacement struct {
+	PType      int   `json:"ptype,omitempty"`
+	Pos        int   `json:"pos,omitempty"`
+	MinDur     int   `json:"mindur,omitempty"`
+	MaxDur     int   `json:"maxdur,omitempty"`
+	Linear     int   `json:"linear,omitempty"`
+	PlayMethod []int `json:"playmethod,omitempty"`
+	API        []int `json:"api,omitempty"`
+	CType      []int `json:"ctype,omitempty"`
+	W          int   `json:"w,omitempty"`
+	H          int   `json:"h,omitempty"`
+}
+
+// adcomContext contains the objects which describe the context of the request
+type adcomContext struct {
+	Site   *adcomSite   `json:"site,omitempty"`
+	App    *adcomApp    `json:"app,omitempty"`
+	Device *adcomDevice `json:"device,omitempty"`
+	User   *adcomUser   `json:"user,omitempty"`
+}
+
+type adcomPublisher struct {
+	ID     string `json:"id,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Domain string `json:"domain,omitempty"`
+}
+
+// adcomDistributionChannel contains the common fields of the site and application
+type adcomDistributionChannel struct {
+	ID       string          `json:"id,omitempty"`
+	Name     string          `json:"name,omitempty"`
+	Domain   string          `json:"domain,omitempty"`
+	Cat      []string        `json:"cat,omitempty"`
+	Keywords string          `json:"keywords,omitempty"`
+	Pub      *adcomPublisher `json:"pub,omitempty"`
+}
+
+type adcomSite struct {
+	adcomDistributionChannel
+	Page   string `json:"page,omitempty"`
+	Ref    string `json:"ref,omitempty"`
+	Search string `json:"search,omitempty"`
+	Mobile int    `json:"mobile,omitempty"`
+}
+
+type adcomApp struct {
+	adcomDistributionChannel
+	Bundle   string `json:"bundle,omitempty"`
+	StoreURL string `json:"storeurl,omitempty"`
+	Ver      string `json:"ver,omitempty"`
+	Paid     int    `json:"paid,omitempty"`
+}
+
+type adcomDevice struct {
+	Type    int       `json:"type,omitempty"`
+	UA      string    `json:"ua,omitempty"`
+	IFA     string    `json:"ifa,omitempty"`
+	DNT     int       `json:"dnt,omitempty"`
+	LMT     int       `json:"lmt,omitempty"`
+	Make    string    `json:"make,omitempty"`
+	Model   string    `json:"model,omitempty"`
+	OS      int       `json:"os,omitempty"`
+	OSV     string    `json:"osv,omitempty"`
+	HWV     string    `json:"hwv,omitempty"`
+	H       int       `json:"h,omitempty"`
+	W       int       `json:"w,omitempty"`
+	PPI     int       `json:"ppi,omitempty"`
+	PxRatio float64   `json:"pxratio,omitempty"`
+	JS      int       `json:"js,omitempty"`
+	Lang    string    `json:"lang,omitempty"`
+	IP      string    `json:"ip,omitempty"`
+	IPv6    string    `json:"ipv6,omitempty"`
+	Carrier string    `json:"carrier,omitempty"`
+	MCCMNC  string    `json:"mccmnc,omitempty"`
+	ConType int       `json:"contype,omitempty"`
+	Geo     *adcomGeo `json:"geo,omitempty"`
+}
+
+type adcomGeo struct {
+	Type      int     `json:"type,omitempty"`
+	Lat       float64 `json:"lat,omitempty"`
+	Lon       float64 `json:"lon,omitempty"`
+	Accur     int     `json:"accur,omitempty"`
+	LastFix   int     `json:"lastfix,omitempty"`
+	Country   string  `json:"country,omitempty"`
+	Region    string  `json:"region,omitempty"`
+	Metro     string  `json:"metro,omitempty"`
+	City      string  `json:"city,omitempty"`
+	ZIP       string  `json:"zip,omitempty"`
+	UTCOffset int     `json:"utcoffset,omitempty"`
+}
+
+type adcomUser struct {
+	ID       string      `json:"id,omitempty"`
+	YOB      int         `json:"yob,omitempty"`
+	Gender   string      `json:"gender,omitempty"`
+	Keywords string      `json:"keywords,omitempty"`
+	Geo      *adcomGeo   `json:"geo,omitempty"`
+	Data     []adcomData `json:"data,omitempty"`
+}
+
+type adcomData struct {
+	ID      string         `json:"id,omitempty"`
+	Name    string         `json:"name,omitempty"`
+	Segment []adcomSegment `json:"segment,omitempty"`
+}
+
+type adcomSegment struct {
+	ID    string `json:"id,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+// adcomAd is the root of the ad media object
+type adcomAd struct {
+	ID      string        `json:"id,omitempty"`
+	ADomain []string      `json:"adomain,omitempty"`
+	Bundle  []string      `json:"bundle,omitempty"`
+	IURL    string        `json:"iurl,omitempty"`
+	Cat     []string      `json:"cat,omitempty"`
+	Display *adcomDisplay `json:"display,omitempty"`
+	Video   *adcomVideo   `json:"video,omitempty"`
+}
+
+// adcomDisplay is the display media of the ad: markup, banner or native
+type adcomDisplay struct {
+	Mime   string       `json:"mime,omitempty"`
+	W      int          `json:"w,omitempty"`
+	H      int          `json:"h,omitempty"`
+	Adm    string       `json:"adm,omitempty"`
+	CURL   string       `json:"curl,omitempty"`
+	Banner *adcomBanner `json:"banner,omitempty"`
+	Native *adcomNative `json:"native,omitempty"`
+	Event  []adcomEvent `json:"event,omitempty"`
+}
+
+type adcomBanner struct {
+	Img  string     `json:"img"`
+	Link *adcomLink `json:"link,omitempty"`
+}
+
+type adcomNative struct {
+	Link  *adcomLink   `json:"link,omitempty"`
+	Asset []adcomAsset `json:"asset,omitempty"`
+}
+
+type adcomAsset struct {
+	ID    int              `json:"id,omitempty"`
+	Req   int              `json:"req,omitempty"`
+	Title *adcomTitleAsset `json:"title,omitempty"`
+	Img   *adcomImageAsset `json:"img,omitempty"`
+	Video *adcomVideo      `json:"video,omitempty"`
+	Data  *adcomDataAsset  `json:"data,omitempty"`
+	Link  *adcomLink       `json:"link,omitempty"`
+}
+
+type adcomTitleAsset struct {
+	Text string `json:"text"`
+}
+
+type adcomImageAsset struct {
+	URL  string `json:"url,omitempty"`
+	W    int    `json:"w,omitempty"`
+	H    int    `json:"h,omitempty"`
+	Type int    `json:"type,omitempty"`
+}
+
+type adcomDataAsset struct {
+	Value string `json:"value"`
+	Type  int    `json:"type,omitempty"`
+}
+
+type adcomLink struct {
+	URL   string   `json:"url"`
+	URLFB string   `json:"urlfb,omitempty"`
+	Trkr  []string `json:"trkr,omitempty"`
+}
+
+type adcomVideo struct {
+	Mime []string `json:"mime,omitempty"`
+	Dur  int      `json:"dur,omitempty"`
+	Adm  string   `json:"adm,omitempty"`
+	CURL string   `json:"curl,omitempty"`
+}
+
+type adcomEvent struct {
+	Type   int    `json:"type"`
+	Method int    `json:"method"`
+	URL    string `json:"url,omitempty"`
+}
@@ -0,0 +1,455 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package openrtb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/demdxx/gocast/v2"
+
+	"github.com/geniusrabbit/adcorelib/adformat"
+	"github.com/geniusrabbit/adcorelib/admodels"
+	"github.com/geniusrabbit/adcorelib/admodels/types"
+	"github.com/geniusrabbit/adcorelib/adquery/bidresponse"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/adtype/prices"
+	"github.com/geniusrabbit/adcorelib/billing"
+	"github.com/geniusrabbit/adcorelib/net/httpclient"
+	"github.com/geniusrabbit/adcorelib/openlatency"
+)
+
+// codec converts the internal request into the protocol specific request
+// and the protocol response back into the internal response items
+type codec interface {
+	// Version of the protocol which is sent in the headers
+	Version() string
+
+	// Request object of the protocol
+	Request(drv *driver, request adtype.BidRequester) (any, error)
+
+	// Response items decoded from the response body
+	Response(drv *driver, request adtype.BidRequester, body io.Reader) ([]adtype.ResponseItemCommon, error)
+}
+
+type driver struct {
+	source  *admodels.RTBSource
+	client  httpclient.Driver
+	codec   codec
+	formats adformat.Accessor
+	headers map[string]string
+	timeout atomic.Int64
+
+	// Currency of the source bids and the rates to convert them into the base currency
+	currency billing.Currency
+	rates    billing.ExchangeRates
+
+	latencyMetrics *openlatency.MetricsCounter
+}
+
+func newDriver(_ context.Context, source *admodels.RTBSource, client httpclient.Driver, formats adformat.Accessor, rates billing.ExchangeRates) (*driver, error) {
+	if source.URL == "" {
+		return nil, ErrSourceURLEmpty
+	}
+	switch source.RequestType {
+	case admodels.RTBRequestTypeUndefined, admodels.RTBRequestTypeJSON:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedRequestType, source.RequestType.Name())
+	}
+	codec, err := codecByProtocol(source.Protocol)
+	if err != nil {
+		return nil, err
+	}
+	drv := &driver{
+		source:         source,
+		client:         client,
+		codec:          codec,
+		formats:        formats,
+		headers:        source.Headers.DataOr(nil),
+		currency:       billing.CurrencyOf(source.Config.Currency),
+		rates:          rates,
+		latencyMetrics: openlatency.NewMetricsCounter(),
+	}
+	// The bids of the source currency can't be accepted and the floors
+	// can't be converted without the rate of the currency
+	if base := drv.baseCurrency(); drv.currency != base {
+		if rates == nil {
+			return nil, fmt.Errorf("%w: %s", ErrNoCurrencyRate, drv.currency)
+		}
+		if _, err := rates.Convert(billing.MoneyInt(1), base, drv.currency); err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrNoCurrencyRate, drv.currency, err)
+		}
+	}
+	drv.SetTimeout(time.Duration(source.Timeout) * time.Millisecond)
+	return drv, nil
+}
+
+// ID of source
+func (d *driver) ID() uint64 { return d.source.ID }
+
+// ObjectKey of source
+func (d *driver) ObjectKey() uint64 { return d.source.ID }
+
+// AccountID of the source owner
+func (d *driver) AccountID() uint64 {
+	if d.source.Account == nil {
+		return 0
+	}
+	return d.source.Account.ID()
+}
+
+// Protocol of source
+func (d *driver) Protocol() string { return d.source.Protocol }
+
+// Info returns information about the source platform and the source protocol
+func (d *driver) Info() *adtype.SourceInfo {
+	return &adtype.SourceInfo{
+		ID:       gocast.Str(d.source.ID),
+		Protocol: d.source.Protocol,
+		Name:     "OpenRTB " + d.codec.Version(),
+		Domain:   d.source.Domain(),
+		URL:      d.source.URL,
+	}
+}
+
+// RequestStrategy description
+func (d *driver) RequestStrategy() adtype.RequestStrategy {
+	return adtype.AsynchronousRequestStrategy
+}
+
+// PriceCorrectionReduceFactor which is a potential
+// Returns percent from 0 to 1 for reducing of the value
+// If there is 10% of price correction, it means that 10% of the final price must be ignored
+func (d *driver) PriceCorrectionReduceFactor() float64 {
+	return d.source.PriceCorrectionReduceFactor()
+}
+
+// SetTimeout for the source requests
+func (d *driver) SetTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	d.timeout.Store(int64(timeout))
+}
+
+// Timeout of the source requests
+func (d *driver) Timeout() time.Duration {
+	return time.Duration(d.timeout.Load())
+}
+
+// Test request before processing
+func (d *driver) Test(request adtype.BidRequester) error {
+	if !request.SourceFilterCheck(d.ID()) {
+		return adtype.ErrResponseSkipped
+	}
+	var err error
+	for _, target := range request.TargetPointers() {
+		if err = d.source.Test(target); err == nil {
+			return nil
+		}
+	}
+	if err == nil {
+		err = ErrNoImpressionsForRequest
+	}
+	return err
+}
+
+// Bid request for the external platform
+func (d *driver) Bid(request adtype.BidRequester) adtype.Response {
+	d.latencyMetrics.BeginQuery()
+
+	httpRequest, err := d.request(request)
+	if err != nil {
+		return bidresponse.NewEmptyResponse(request, d,
+			fmt.Errorf("%w: %w", adtype.ErrResponseInvalidRequest, err))
+	}
+
+	startTime := time.Now()
+	resp, err := d.execute(request.Context(), httpRequest)
+	d.latencyMetrics.UpdateQueryLatency(time.Since(startTime))
+
+	if err != nil {
+		if errors.Is(err, ErrTimeout) {
+			d.latencyMetrics.IncTimeout()
+		} else {
+			d.latencyMetrics.IncError(openlatency.MetricErrorNetwork, "")
+		}
+		return bidresponse.NewEmptyResponse(request, d, err)
+	}
+	defer func() { _ = resp.Close() }()
+
+	switch resp.StatusCode() {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		d.latencyMetrics.IncNobid()
+		return bidresponse.NewEmptyResponse(request, d, adtype.ErrResponseNoBid)
+	default:
+		d.latencyMetrics.IncError(openlatency.MetricErrorHTTP, strconv.Itoa(resp.StatusCode()))
+		return bidresponse.NewEmptyResponse(request, d,
+			fmt.Errorf("%w: %d", ErrInvalidResponseStatus, resp.StatusCode()))
+	}
+
+	items, err := d.codec.Response(d, request, resp.Body())
+	if err != nil {
+		d.latencyMetrics.IncError(openlatency.MetricErrorInvalid, "decode")
+		return bidresponse.NewEmptyResponse(request, d, err)
+	}
+	if len(items) == 0 {
+		d.latencyMetrics.IncNobid()
+		return bidresponse.NewEmptyResponse(request, d, adtype.ErrResponseNoBid)
+	}
+
+	d.latencyMetrics.IncSuccess()
+	return bidresponse.BorrowResponse(request, d, items, nil)
+}
+
+// ProcessResponseItem sends the win notice of the item which won the auction
+func (d *driver) ProcessResponseItem(response adtype.Response, item adtype.ResponseItem) {
+	bidItem, _ := item.(*ResponseBidItem)
+	if bidItem == nil || bidItem.Bid.WinURL == "" {
+		return
+	}
+	go d.notify(bidItem.PrepareURL(bidItem.Bid.WinURL))
+}
+
+// Metrics information of the platform
+func (d *driver) Metrics() *openlatency.MetricsInfo {
+	var info openlatency.MetricsInfo
+	d.latencyMetrics.FillMetrics(&info)
+	info.ID = d.ID()
+	info.Protocol = d.source.Protocol
+	info.QPSLimit = d.source.RPS
+	info.Timeout = d.Timeout().Milliseconds()
+	return &info
+}
+
+///////////////////////////////////////////////////////////////////////////////
+/// Internal methods
+///////////////////////////////////////////////////////////////////////////////
+
+func (d *driver) request(request adtype.BidRequester) (httpclient.Request, error) {
+	rtbRequest, err := d.codec.Request(d, request)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(rtbRequest)
+	if err != nil {
+		return nil, err
+	}
+	method := strings.ToUpper(d.source.Method)
+	if method == "" {
+		method = defaultMethod
+	}
+	httpRequest, err := d.client.Request(method, d.source.URL, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.SetHeader("Content-Type", "application/json")
+	httpRequest.SetHeader("X-Openrtb-Version", d.codec.Version())
+	for key, value := range d.headers {
+		httpRequest.SetHeader(key, value)
+	}
+	return httpRequest, nil
+}
+
+// newItem creates the response item from the bid with price limits of the source
+func (d *driver) newItem(request adtype.BidRequester, bid *BidItem) (*ResponseBidItem, error) {
+	imp := request.ImpressionByID(bid.ImpID)
+	if imp == nil {
+		return nil, adtype.ErrInvalidItemInitialisation
+	}
+	format := bidFormat(imp, bid)
+	if format == nil {
+		return nil, adtype.ErrInvalidViewType
+	}
+	bidCPM, err := d.toBase(billing.MoneyFloat(bid.Price), billing.CurrencyOf(bid.Currency))
+	if err != nil {
+		return nil, adtype.ErrInvalidCur
+	}
+	if bidCPM <= 0 || bidCPM < d.bidFloorCPM(imp) {
+		return nil, adtype.ErrLowPrice
+	}
+	if d.source.MaxBid > 0 && bidCPM > d.source.MaxBid {
+		bidCPM = d.source.MaxBid
+	}
+	item := &ResponseBidItem{
+		ItemID:    bid.BidID,
+		Bid:       *bid,
+		Src:       d,
+		Req:       request,
+		base:      d.baseCurrency(),
+		rates:     d.rates,
+		Imp:       imp,
+		FormatVal: format,
+		PriceScope: prices.PriceScope{
+			CPMScope: prices.CPMScope{MaxBidCPM: bidCPM, BidCPM: bidCPM},
+			ECPM:     bidCPM,
+		},
+	}
+	if item.ItemID == "" {
+		item.ItemID = imp.IDByFormat(format)
+	}
+	if format.IsNative() {
+		native, err := decodeNativeMarkup(bid.Markup)
+		if err != nil {
+			return nil, err
+		}
+		item.Native = native
+	}
+	return item, nil
+}
+
+// bidFloorCPM returns the minimal acceptable bid of the impression
+func (d *driver) bidFloorCPM(imp *adtype.Impression) billing.Money {
+	return max(imp.BidFloorCPM, d.source.MinBid)
+}
+
+// bidFloor returns the bid floor of the impression in the source currency.
+// The floor stays in the base currency if there is no rate for the source currency.
+func (d *driver) bidFloor(imp *adtype.Impression) (float64, string) {
+	floor := d.bidFloorCPM(imp)
+	if d.rates == nil || d.currency == d.baseCurrency() {
+		return floor.Float64(), d.baseCurrency().String()
+	}
+	if value, err := d.rates.Convert(floor, d.baseCurrency(), d.currency); err == nil {
+		return value.Float64(), d.currency.String()
+	}
+	return floor.Float64(), d.baseCurrency().String()
+}
+
+// currencies accepted by the source in the bids
+func (d *driver) currencies() []string {
+	if base := d.baseCurrency(); d.rates != nil && d.currency != base {
+		return []string{d.currency.String(), base.String()}
+	}
+	return []string{d.baseCurrency().String()}
+}
+
+// acceptsCurrency of the bids if it's the base currency or it can be converted
+func (d *driver) acceptsCurrency(currency billing.Currency) bool {
+	return currency == d.baseCurrency() || (d.rates != nil && currency == d.currency)
+}
+
+// baseCurrency of the auction prices
+func (d *driver) baseCurrency() billing.Currency {
+	if d.rates == nil {
+		return billing.DefaultCurrency
+	}
+	return d.rates.Base()
+}
+
+// toBase converts the amount of the currency into the base currency
+func (d *driver) toBase(amount billing.Money, currency billing.Currency) (billing.Money, error) {
+	if base := d.baseCurrency(); currency == base {
+		return amount, nil
+	} else if d.rates == nil {
+		return 0, adtype.ErrInvalidCur
+	} else {
+		return d.rates.Convert(amount, currency, base)
+	}
+}
+
+// bidFormat detects the format of the bid by the markup type or by the size
+func bidFormat(imp *adtype.Impression, bid *BidItem) *types.Format {
+	markupType := bid.MarkupType
+	if markupType == types.FormatUndefinedType {
+		markupType = types.FormatBannerType
+		if strings.HasPrefix(strings.TrimSpace(bid.Markup), "{") && imp.FormatByType(types.FormatNativeType) != nil {
+			markupType = types.FormatNativeType
+		}
+	}
+	if markupType != types.FormatBannerType {
+		return imp.FormatByType(markupType)
+	}
+	var banner *types.Format
+	for _, format := range imp.Formats() {
+		if !format.IsBanner() && !format.IsProxy() {
+			continue
+		}
+		if bid.Width <= 0 || (format.Width == bid.Width && format.Height == bid.Height) {
+			return format
+		}
+		if banner == nil {
+			banner = format
+		}
+	}
+	return banner
+}
+
+// adFormat returns the extended format description by the format codename
+func (d *driver) adFormat(format *types.Format) *adformat.Format {
+	if d.formats == nil || format == nil {
+		return nil
+	}
+	return d.formats.FormatByCode(format.Codename)
+}
+
+// codecByProtocol returns the protocol codec by the source protocol name
+func codecByProtocol(protocol string) (codec, error) {
+	switch protocol {
+	case "", ProtocolOpenRTB, ProtocolOpenRTB2:
+		return codecV2{}, nil
+	case ProtocolOpenRTB3:
+		return codecV3{}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedProtocol, protocol)
+}
+
+type executeResult struct {
+	resp httpclient.Response
+	err  error
+}
+
+// execute the request with the source timeout or until the context is done
+func (d *driver) execute(ctx context.Context, req httpclient.Request) (httpclient.Response, error) {
+	result := make(chan executeResult, 1)
+	go func() {
+		resp, err := d.client.Do(req)
+		result <- executeResult{resp: resp, err: err}
+	}()
+
+	timer := time.NewTimer(d.Timeout())
+	defer timer.Stop()
+
+	select {
+	case res := <-result:
+		return res.resp, res.err
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	// Release the late response
+	go func() {
+		if res := <-result; res.resp != nil {
+			_ = res.resp.Close()
+		}
+	}()
+	return nil, ErrTimeout
+}
+
+func (d *driver) notify(url string) {
+	req, err := d.client.Request(http.MethodGet, url, nil)
+	if err != nil {
+		return
+	}
+	if resp, err := d.client.Do(req); err == nil {
+		_ = resp.Close()
+	}
+}
+
+var (
+	_ adtype.SourceTester        = (*driver)(nil)
+	_ adtype.SourceTimeoutSetter = (*driver)(nil)
+)
@@ -0,0 +1,319 @@
+package openrtb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	openrtb2 "github.com/bsm/openrtb/v3"
+	natreq "github.com/bsm/openrtb/v3/native/request"
+	"github.com/geniusrabbit/gosql/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/geniusrabbit/adcorelib/admodels"
+	"github.com/geniusrabbit/adcorelib/admodels/types"
+	"github.com/geniusrabbit/adcorelib/adquery/bidrequest"
+	"github.com/geniusrabbit/adcorelib/adsource/ratelimit"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
+	"github.com/geniusrabbit/adcorelib/net/httpclient/stdhttpclient"
+)
+
+var testFormats = types.NewSimpleFormatAccessor([]*types.Format{
+	{ID: 1, Codename: "banner_300x250", Types: *types.NewFormatTypeBitset(types.FormatBannerType), Width: 300, Height: 250},
+	{ID: 2, Codename: "native", Types: *types.NewFormatTypeBitset(types.FormatNativeType)},
+})
+
+func newTestRequest(codes ...string) *bidrequest.BidRequest {
+	imp := &adtype.Impression{ID: "imp1", FormatCodes: codes, BidFloorCPM: billing.MoneyFloat(0.5)}
+	imp.InitFormats(testFormats)
+	return &bidrequest.BidRequest{
+		IDVal: "req1",
+		Ctx:   context.Background(),
+		Imps:  []*adtype.Impression{imp},
+	}
+}
+
+func newTestSource(url string) *admodels.RTBSource {
+	return &admodels.RTBSource{
+		ID:      1,
+		URL:     url,
+		Headers: *gosql.MustNullableJSON[map[string]string](map[string]string{"X-Token": "secret"}),
+		MinBid:  billing.MoneyFloat(1.),
+		MaxBid:  billing.MoneyFloat(2.),
+		Timeout: 100,
+
+		PriceCorrectionReduce: 0.1,
+	}
+}
+
+func TestDriverBid(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("X-Token"))
+		assert.Equal(t, "2.5", r.Header.Get("X-Openrtb-Version"))
+
+		var req openrtb2.BidRequest
+		if !assert.NoError(t, json.NewDecoder(r.Body).Decode(&req)) || !assert.Len(t, req.Impressions, 1) {
+			return
+		}
+		imp := req.Impressions[0]
+		assert.Equal(t, 1.0, imp.BidFloor, "floor must be raised to the MinBid")
+		if assert.NotNil(t, imp.Banner) {
+			assert.Equal(t, 300, imp.Banner.Width)
+		}
+
+		_ = json.NewEncoder(w).Encode(&openrtb2.BidResponse{
+			ID: req.ID,
+			SeatBids: []openrtb2.SeatBid{{Seat: "dsp", Bids: []openrtb2.Bid{
+				{ID: "b1", ImpID: "imp1", Price: 3, AdMarkup: "<b>${AUCTION_PRICE}</b>", Width: 300, Height: 250},
+				{ID: "b2", ImpID: "imp1", Price: 0.7, AdMarkup: "<i>low</i>"},
+				{ID: "b3", ImpID: "unknown", Price: 1.5, AdMarkup: "<i>unknown</i>"},
+			}}},
+		})
+	}))
+	defer server.Close()
+
+	drv, err := NewFactory(stdhttpclient.NewDriver()).New(context.Background(), newTestSource(server.URL))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	resp := drv.Bid(newTestRequest("banner_300x250"))
+	if !assert.NoError(t, resp.Error()) || !assert.Equal(t, 1, resp.Count()) {
+		return
+	}
+	item := resp.Ads()[0].(*ResponseBidItem)
+	assert.NoError(t, item.Validate())
+	assert.Equal(t, "dsp", item.NetworkName())
+	assert.Equal(t, billing.MoneyFloat(2.), item.ECPM(), "bid must be limited by the MaxBid")
+	assert.Equal(t, billing.MoneyFloat(1.8), item.InternalAuctionCPMBid())
+	assert.Equal(t, "<b>2</b>", item.ContentItemString(adtype.ContentItemContent))
+}
+
+func TestDriverCurrency(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openrtb2.BidRequest
+		if !assert.NoError(t, json.NewDecoder(r.Body).Decode(&req)) || !assert.Len(t, req.Impressions, 1) {
+			return
+		}
+		assert.Equal(t, []string{"EUR", "USD"}, req.Currencies)
+		assert.Equal(t, "EUR", req.Impressions[0].BidFloorCurrency)
+		assert.Equal(t, 0.5, req.Impressions[0].BidFloor, "floor must be converted into the source currency")
+
+		currency := "EUR"
+		if r.URL.Path == "/gbp" {
+			currency = "GBP"
+		}
+		_ = json.NewEncoder(w).Encode(&openrtb2.BidResponse{
+			ID:       req.ID,
+			Currency: currency,
+			SeatBids: []openrtb2.SeatBid{{Bids: []openrtb2.Bid{
+				{ID: "b1", ImpID: "imp1", Price: 0.8, AdMarkup: "<b>${AUCTION_PRICE} ${AUCTION_CURRENCY}</b>"},
+			}}},
+		})
+	}))
+	defer server.Close()
+
+	rates, err := billing.NewRateTable("USD", map[billing.Currency]billing.Money{"EUR": billing.MoneyInt(2)})
+	if !assert.NoError(t, err) {
+		return
+	}
+	newSource := func(url string) *admodels.RTBSource {
+		source := newTestSource(url)
+		source.Config.Currency = "eur"
+		return source
+	}
+
+	drv, _ := NewFactory(stdhttpclient.NewDriver()).New(context.Background(), newSource(server.URL), rates)
+	resp := drv.Bid(newTestRequest("banner_300x250"))
+	if !assert.NoError(t, resp.Error()) || !assert.Equal(t, 1, resp.Count()) {
+		return
+	}
+	item := resp.Ads()[0].(*ResponseBidItem)
+	assert.Equal(t, billing.MoneyFloat(1.6), item.ECPM(), "bid must be converted into the base currency")
+	assert.Equal(t, billing.AmountOf(billing.MoneyFloat(0.8), "EUR"), item.OriginalBidCPM())
+	assert.Equal(t, billing.Currency("USD"), item.BaseCurrency())
+	assert.Equal(t, "<b>0.8 EUR</b>", item.ContentItemString(adtype.ContentItemContent))
+
+	drv, _ = NewFactory(stdhttpclient.NewDriver()).New(context.Background(), newSource(server.URL+"/gbp"), rates)
+	assert.ErrorIs(t, drv.Bid(newTestRequest("banner_300x250")).Error(), adtype.ErrInvalidCur)
+
+	// The source currency without the exchange rate is the misconfiguration
+	_, err = NewFactory(stdhttpclient.NewDriver()).New(context.Background(), newSource(server.URL))
+	assert.ErrorIs(t, err, ErrNoCurrencyRate)
+
+	source := newSource(server.URL)
+	source.Config.Currency = "GBP"
+	_, err = NewFactory(stdhttpclient.NewDriver()).New(context.Background(), source, rates)
+	assert.ErrorIs(t, err, ErrNoCurrencyRate)
+}
+
+func TestDriverNativeBid(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openrtb2.BidRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if !assert.NotNil(t, req.Impressions[0].Native) {
+			return
+		}
+		_ = json.NewEncoder(w).Encode(&openrtb2.BidResponse{
+			ID: req.ID,
+			SeatBids: []openrtb2.SeatBid{{Bids: []openrtb2.Bid{{
+				ID: "b1", ImpID: "imp1", Price: 1.5, MarkupType: openrtb2.MarkupNative,
+				AdMarkup: `{"native":{"assets":[{"id":1,"title":{"text":"Title"}},{"id":2,"img":{"url":"https://img","w":100,"h":100}},{"id":3,"data":{"value":"Desc"}}],"link":{"url":"https://click"},"imptrackers":["https://imp"]}}`,
+			}}}},
+		})
+	}))
+	defer server.Close()
+
+	drv, _ := NewFactory(stdhttpclient.NewDriver()).New(context.Background(), newTestSource(server.URL))
+	resp := drv.Bid(newTestRequest("native"))
+	if !assert.NoError(t, resp.Error()) || !assert.Equal(t, 1, resp.Count()) {
+		return
+	}
+	item := resp.Ads()[0].(*ResponseBidItem)
+	assert.NoError(t, item.Validate())
+	assert.Equal(t, "Title", item.ContentItemString(types.FormatFieldTitle))
+	assert.Equal(t, "Desc", item.ContentItemString(types.FormatFieldDescription))
+	assert.Equal(t, "https://click", item.ActionURL())
+	assert.Equal(t, []string{"https://imp"}, item.ImpressionTrackerLinks())
+	if assert.NotNil(t, item.MainAsset()) {
+		assert.Equal(t, "https://img", item.MainAsset().URL)
+	}
+}
+
+func TestDriverNativePreparedRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openrtb2.BidRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		_ = json.NewEncoder(w).Encode(&openrtb2.BidResponse{
+			ID: req.ID,
+			SeatBids: []openrtb2.SeatBid{{Bids: []openrtb2.Bid{{
+				ID: "b1", ImpID: "imp1", Price: 1.5, MarkupType: openrtb2.MarkupNative,
+				AdMarkup: `{"native":{"assets":[{"id":3,"data":{"value":"4.5"}},{"id":4,"img":{"url":"https://main"}},` +
+					`{"id":5,"data":{"value":"Desc"}},{"id":7,"title":{"text":"Title"}},{"id":8,"img":{"url":"https://icon"}},` +
+					`{"id":9,"data":{"value":"Brand"}}],"link":{"url":"https://click"}}}`,
+			}}}},
+		})
+	}))
+	defer server.Close()
+
+	request := newTestRequest("native")
+	request.Imps[0].Request = &natreq.Request{Assets: []natreq.Asset{
+		{ID: 3, Data: &natreq.Data{TypeID: natreq.DataTypeRating}},
+		{ID: 4, Image: &natreq.Image{TypeID: natreq.ImageTypeMain}},
+		{ID: 5, Data: &natreq.Data{TypeID: natreq.DataTypeDesc}},
+		{ID: 7, Title: &natreq.Title{Length: 90}},
+		{ID: 8, Image: &natreq.Image{TypeID: natreq.ImageTypeIcon}},
+		{ID: 9, Data: &natreq.Data{TypeID: natreq.DataTypeSponsored}},
+	}}
+
+	drv, _ := NewFactory(stdhttpclient.NewDriver()).New(context.Background(), newTestSource(server.URL))
+	resp := drv.Bid(request)
+	if !assert.NoError(t, resp.Error()) || !assert.Equal(t, 1, resp.Count()) {
+		return
+	}
+	item := resp.Ads()[0].(*ResponseBidItem)
+	assert.Equal(t, "Title", item.ContentItemString(types.FormatFieldTitle))
+	assert.Equal(t, "Desc", item.ContentItemString(types.FormatFieldDescription))
+	assert.Equal(t, "Brand", item.ContentItemString(types.FormatFieldSponsored))
+	if assert.NotNil(t, item.MainAsset()) {
+		assert.Equal(t, "https://main", item.MainAsset().URL)
+	}
+	if icon := item.Asset(types.FormatAssetIcon); assert.NotNil(t, icon) {
+		assert.Equal(t, "https://icon", icon.URL)
+	}
+}
+
+func TestDriverUserYearOfBirth(t *testing.T) {
+	var yob []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openrtb2.BidRequest
+		if assert.NoError(t, json.NewDecoder(r.Body).Decode(&req)) && assert.NotNil(t, req.User) {
+			yob = append(yob, req.User.YearOfBirth)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	drv, err := NewFactory(stdhttpclient.NewDriver()).New(context.Background(), newTestSource(server.URL))
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, age := range []int{25, 0} {
+		request := newTestRequest("banner_300x250")
+		request.User = &adtype.User{ID: "user1", AgeStart: age}
+		drv.Bid(request)
+	}
+	assert.Equal(t, []int{time.Now().Year() - 25, 0}, yob)
+}
+
+func TestDriverErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/nobid":
+			w.WriteHeader(http.StatusNoContent)
+		case "/fail":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path string
+		err  error
+	}{
+		{path: "/nobid", err: adtype.ErrResponseNoBid},
+		{path: "/fail", err: ErrInvalidResponseStatus},
+		{path: "/slow", err: ErrTimeout},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			drv, _ := NewFactory(stdhttpclient.NewDriver()).New(context.Background(), newTestSource(server.URL+test.path))
+			resp := drv.Bid(newTestRequest("banner_300x250"))
+			assert.True(t, errors.Is(resp.Error(), test.err), resp.Error())
+		})
+	}
+}
+
+func TestDriverRPS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	source := newTestSource(server.URL)
+	drv, _ := NewFactory(stdhttpclient.NewDriver()).New(context.Background(), source)
+
+	// The source without the RPS limit is not wrapped
+	_, limited := drv.(*ratelimit.Source)
+	assert.False(t, limited)
+	for range 3 {
+		assert.ErrorIs(t, drv.Bid(newTestRequest("banner_300x250")).Error(), adtype.ErrResponseNoBid)
+	}
+
+	source.RPS = 1
+	drv, _ = NewFactory(stdhttpclient.NewDriver()).New(context.Background(), source)
+	if !assert.IsType(t, &ratelimit.Source{}, drv) {
+		return
+	}
+	assert.ErrorIs(t, drv.Bid(newTestRequest("banner_300x250")).Error(), adtype.ErrResponseNoBid)
+	assert.True(t, drv.(*ratelimit.Source).Throttle())
+	assert.ErrorIs(t, drv.Bid(newTestRequest("banner_300x250")).Error(), adtype.ErrResponseSkipped)
+	assert.Equal(t, 1, drv.(*ratelimit.Source).Metrics().QPSLimit)
+}
+
+func TestNewDriverValidation(t *testing.T) {
+	_, err := NewFactory(nil).New(context.Background(), &admodels.RTBSource{})
+	assert.ErrorIs(t, err, ErrSourceURLEmpty)
+
+	_, err = NewFactory(nil).New(context.Background(), &admodels.RTBSource{
+		URL: "http://localhost", RequestType: admodels.RTBRequestTypeXML})
+	assert.ErrorIs(t, err, ErrUnsupportedRequestType)
+}
@@ -0,0 +1,121 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package openrtb
+
+import (
+	"context"
+
+	"github.com/geniusrabbit/adcorelib/adformat"
+	"github.com/geniusrabbit/adcorelib/admodels"
+	"github.com/geniusrabbit/adcorelib/adsource/ratelimit"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
+	"github.com/geniusrabbit/adcorelib/net/httpclient"
+	"github.com/geniusrabbit/adcorelib/net/httpclient/stdhttpclient"
+	"github.com/geniusrabbit/adcorelib/platform/info"
+)
+
+// Factory of the OpenRTB source drivers
+type Factory struct {
+	client httpclient.Driver
+}
+
+// NewFactory returns the source factory with the given HTTP client.
+// If client is nil the default high-performance client is used.
+func NewFactory(client httpclient.Driver) *Factory {
+	if client == nil {
+		client = stdhttpclient.NewDriver()
+	}
+	return &Factory{client: client}
+}
+
+// Info returns information about the source platform
+func (f *Factory) Info() info.Platform {
+	return info.Platform{
+		Name:         "OpenRTB",
+		Protocol:     ProtocolOpenRTB,
+		AllProtocols: f.Protocols(),
+		Versions:     []string{"2.5", "2.6", "3.0"},
+		Description:  "OpenRTB client driver which sends bid requests to the external DSP platforms",
+		Docs: []info.Documentation{
+			{
+				Title: "OpenRTB 2.6",
+				Link:  "https://github.com/InteractiveAdvertisingBureau/openrtb2.x/blob/main/2.6.md",
+			},
+			{
+				Title: "OpenRTB 3.0",
+				Link:  "https://github.com/InteractiveAdvertisingBureau/openrtb/blob/main/OpenRTB%20v3.0%20FINAL.md",
+			},
+		},
+		Subprotocols: []info.Subprotocol{
+			{
+				Name:     "Native",
+				Protocol: "native",
+				Versions: []string{"1.1", "1.2"},
+				Docs: []info.Documentation{
+					{
+						Title: "OpenRTB Native 1.2",
+						Link:  "https://www.iab.com/wp-content/uploads/2018/03/OpenRTB-Native-Ads-Specification-Final-1.2.pdf",
+					},
+				},
+			},
+			{
+				Name:     "AdCOM",
+				Protocol: "adcom",
+				Versions: []string{"1.0"},
+				Docs: []info.Documentation{
+					{
+						Title: "AdCOM 1.0",
+						Link:  "https://github.com/InteractiveAdvertisingBureau/AdCOM/blob/main/AdCOM%20v1.0%20FINAL.md",
+					},
+				},
+			},
+		},
+	}
+}
+
+// Protocols returns list of supported protocols of the source
+func (f *Factory) Protocols() []string {
+	return []string{ProtocolOpenRTB, ProtocolOpenRTB2, ProtocolOpenRTB3}
+}
+
+// New creates a new source instance for the given RTBSource.
+// The protocol version is selected by the RTBSource.Protocol value.
+// The HTTP client of the factory can be replaced by the httpclient.Driver option,
+// the adformat.Accessor option provides the extended format descriptions
+// which are used for the OpenRTB 3.0 placements and the billing.ExchangeRates option
+// converts the bids of the source currency (RTBSource.Config.Currency) into the base currency.
+// The source of the currency other than the base one can't be created without the rates.
+// The source with the positive RTBSource.RPS is wrapped by the rate limit (see ratelimit.Wrap).
+func (f *Factory) New(ctx context.Context, source *admodels.RTBSource, opts ...any) (adtype.SourceTester, error) {
+	var (
+		client  = f.client
+		formats adformat.Accessor
+		rates   billing.ExchangeRates
+	)
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case httpclient.Driver:
+			if o != nil {
+				client = o
+			}
+		case adformat.Accessor:
+			formats = o
+		case billing.ExchangeRates:
+			rates = o
+		}
+	}
+	drv, err := newDriver(ctx, source, client, formats, rates)
+	if err != nil {
+		return nil, err
+	}
+	if source.RPS > 0 {
+		return ratelimit.Wrap(drv, source.RPS), nil
+	}
+	return drv, nil
+}
+
+var _ adtype.SourceFactory = (*Factory)(nil)
@@ -0,0 +1,39 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+// Package openrtb implements the OpenRTB client source driver.
+//
+// The driver converts the internal bid request into the protocol request,
+// sends it to the external platform with the httpclient.Driver and converts
+// the bids back into the response items of the internal auction.
+package openrtb
+
+import (
+	"errors"
+	"time"
+)
+
+// Protocol names supported by the factory
+const (
+	ProtocolOpenRTB  = "openrtb"
+	ProtocolOpenRTB2 = "openrtb2"
+	ProtocolOpenRTB3 = "openrtb3"
+)
+
+const (
+	defaultTimeout = 150 * time.Millisecond
+	defaultMethod  = "POST"
+)
+
+// Set of driver errors
+var (
+	ErrSourceURLEmpty          = errors.New("[openrtb] source URL is empty")
+	ErrUnsupportedRequestType  = errors.New("[openrtb] unsupported request type")
+	ErrUnsupportedProtocol     = errors.New("[openrtb] unsupported protocol")
+	ErrTimeout                 = errors.New("[openrtb] request timeout")
+	ErrInvalidResponseStatus   = errors.New("[openrtb] invalid response status")
+	ErrNoImpressionsForRequest = errors.New("[openrtb] no impressions for the request")
+	ErrNoCurrencyRate          = errors.New("[openrtb] no exchange rate of the source currency")
+)
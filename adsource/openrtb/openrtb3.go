@@ -0,0 +1,101 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package openrtb
+
+// OpenRTB 3.0 transport layer objects.
+// The bsm/openrtb library implements only 2.x protocol versions so
+// the minimal subset of the 3.0 specification is defined here.
+// Reference: https://github.com/InteractiveAdvertisingBureau/openrtb/blob/main/OpenRTB%20v3.0%20FINAL.md
+
+const (
+	openrtb3Version    = "3.0"
+	adcomDomainSpec    = "adcom"
+	adcomDomainVersion = "1.0"
+)
+
+// rtb3Root is the top-level object of the OpenRTB 3.0 payload
+type rtb3Root struct {
+	OpenRTB rtb3Openrtb `json:"openrtb"`
+}
+
+// rtb3Openrtb object of the OpenRTB 3.0 specification
+type rtb3Openrtb struct {
+	Ver        string        `json:"ver,omitempty"`
+	DomainSpec string        `json:"domainspec,omitempty"`
+	DomainVer  string        `json:"domainver,omitempty"`
+	Request    *rtb3Request  `json:"request,omitempty"`
+	Response   *rtb3Response `json:"response,omitempty"`
+}
+
+// rtb3Request object contains a globally unique bid request ID
+// and the list of items which are offered for sale
+type rtb3Request struct {
+	ID      string        `json:"id"`
+	Test    int           `json:"test,omitempty"`
+	TMax    int           `json:"tmax,omitempty"`
+	At      int           `json:"at,omitempty"`
+	Cur     []string      `json:"cur,omitempty"`
+	Item    []rtb3Item    `json:"item"`
+	Context *adcomContext `json:"context,omitempty"`
+}
+
+// rtb3Item represents a unit of goods being offered for sale
+type rtb3Item struct {
+	ID     string       `json:"id"`
+	Qty    int          `json:"qty,omitempty"`
+	Flr    float64      `json:"flr,omitempty"`
+	FlrCur string       `json:"flrcur,omitempty"`
+	Spec   rtb3ItemSpec `json:"spec"`
+}
+
+// rtb3ItemSpec contains the domain specification of the item
+type rtb3ItemSpec struct {
+	Placement *adcomPlacement `json:"placement,omitempty"`
+}
+
+// rtb3Response object is the bid response object under the openrtb root
+type rtb3Response struct {
+	ID      string        `json:"id"`
+	BidID   string        `json:"bidid,omitempty"`
+	NBR     int           `json:"nbr,omitempty"`
+	Cur     string        `json:"cur,omitempty"`
+	SeatBid []rtb3SeatBid `json:"seatbid,omitempty"`
+}
+
+// rtb3SeatBid is a collection of bids made by the bidder on behalf of a specific seat
+type rtb3SeatBid struct {
+	Seat    string    `json:"seat,omitempty"`
+	Package int       `json:"package,omitempty"`
+	Bid     []rtb3Bid `json:"bid"`
+}
+
+// rtb3Bid object conveys a bid for the item of the request
+type rtb3Bid struct {
+	ID     string      `json:"id,omitempty"`
+	Item   string      `json:"item"`
+	Price  float64     `json:"price"`
+	Deal   string      `json:"deal,omitempty"`
+	CID    string      `json:"cid,omitempty"`
+	Tactic string      `json:"tactic,omitempty"`
+	PURL   string      `json:"purl,omitempty"`
+	BURL   string      `json:"burl,omitempty"`
+	LURL   string      `json:"lurl,omitempty"`
+	Exp    int         `json:"exp,omitempty"`
+	MID    string      `json:"mid,omitempty"`
+	Macro  []rtb3Macro `json:"macro,omitempty"`
+	Media  *rtb3Media  `json:"media,omitempty"`
+}
+
+// rtb3Macro object enables the bidder to pass the custom macro values
+type rtb3Macro struct {
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+}
+
+// rtb3Media contains the domain specification of the media
+type rtb3Media struct {
+	Ad *adcomAd `json:"ad,omitempty"`
+}
@@ -0,0 +1,321 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package openrtb
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	openrtb2 "github.com/bsm/openrtb/v3"
+	natreq "github.com/bsm/openrtb/v3/native/request"
+	natresp "github.com/bsm/openrtb/v3/native/response"
+	"github.com/demdxx/gocast/v2"
+	uopenrtb "github.com/geniusrabbit/udetect/openrtb3"
+
+	"github.com/geniusrabbit/adcorelib/admodels/types"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+const nativeVersion = "1.2"
+
+// Default native asset IDs which are used if the impression
+// does not contain the prepared native request. The response asset IDs
+// of the prepared request are mapped to them by the asset type.
+const (
+	nativeAssetTitleID = iota + 1
+	nativeAssetMainImageID
+	nativeAssetDescriptionID
+	nativeAssetIconID
+	nativeAssetSponsoredID
+)
+
+// codecV2 implements OpenRTB 2.5/2.6 protocol
+type codecV2 struct{}
+
+// Version of the protocol
+func (codecV2) Version() string { return "2.5" }
+
+// Request object of the protocol
+func (codecV2) Request(drv *driver, request adtype.BidRequester) (any, error) {
+	imps := make([]openrtb2.Impression, 0, len(request.Impressions()))
+	for _, imp := range request.Impressions() {
+		if rtbImp := impressionV2(drv, request, imp); rtbImp != nil {
+			imps = append(imps, *rtbImp)
+		}
+	}
+	if len(imps) == 0 {
+		return nil, ErrNoImpressionsForRequest
+	}
+	rtbRequest := &openrtb2.BidRequest{
+		ID:          request.ID(),
+		Impressions: imps,
+		Device:      uopenrtb.DeviceFrom(request.DeviceInfo(), request.GeoInfo()),
+		User:        userV2(request.UserInfo()),
+		AuctionType: auctionType(drv.source.AuctionType),
+		TimeMax:     int(drv.Timeout() / time.Millisecond),
+		Currencies:  drv.currencies(),
+	}
+	if app := request.AppInfo(); app != nil {
+		rtbRequest.App = uopenrtb.ApplicationFrom(app)
+	} else {
+		rtbRequest.Site = uopenrtb.SiteFrom(request.SiteInfo())
+	}
+	if drv.source.Options.TestMode != 0 || request.IsDebug() {
+		rtbRequest.Test = 1
+	}
+	return rtbRequest, nil
+}
+
+// Response items decoded from the response body
+func (codecV2) Response(drv *driver, request adtype.BidRequester, body io.Reader) ([]adtype.ResponseItemCommon, error) {
+	var rtbResponse openrtb2.BidResponse
+	if err := json.NewDecoder(body).Decode(&rtbResponse); err != nil {
+		return nil, err
+	}
+	currency := billing.CurrencyOf(rtbResponse.Currency)
+	if !drv.acceptsCurrency(currency) {
+		return nil, adtype.ErrInvalidCur
+	}
+	var items []adtype.ResponseItemCommon
+	for _, seat := range rtbResponse.SeatBids {
+		for i := range seat.Bids {
+			bid := &seat.Bids[i]
+			item, err := drv.newItem(request, &BidItem{
+				BidID:      bid.ID,
+				ImpID:      bid.ImpID,
+				AdID:       bid.AdID,
+				CampaignID: string(bid.CampaignID),
+				CreativeID: bid.CreativeID,
+				Seat:       seat.Seat,
+				Price:      bid.Price,
+				Currency:   currency.String(),
+				Markup:     bid.AdMarkup,
+				MarkupType: markupTypeV2(bid.MarkupType),
+				WinURL:     bid.NoticeURL,
+				BillingURL: bid.BillingURL,
+				LossURL:    bid.LossURL,
+				AdvDomains: bid.AdvDomains,
+				Categories: categoriesV2(bid.Categories),
+				Width:      bid.Width,
+				Height:     bid.Height,
+				DealID:     bid.DealID,
+			})
+			if err != nil {
+				continue
+			}
+			if item.Native != nil {
+				remapNativeAssets(item.Native, nativeRequestAssetIDs(item.Imp))
+			}
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func impressionV2(drv *driver, request adtype.BidRequester, imp *adtype.Impression) *openrtb2.Impression {
+	bidFloor, bidFloorCurrency := drv.bidFloor(imp)
+	rtbImp := &openrtb2.Impression{
+		ID:               imp.ID,
+		TagID:            gocast.Str(imp.TargetID()),
+		BidFloor:         bidFloor,
+		BidFloorCurrency: bidFloorCurrency,
+		Interstitial:     b2i(imp.Interstitial),
+		Secure:           openrtb2.NumberOrString(b2i(request.IsSecure())),
+	}
+	for _, format := range imp.Formats() {
+		switch {
+		case format.IsNative():
+			if rtbImp.Native == nil {
+				rtbImp.Native = nativeV2(imp, format)
+			}
+		case format.IsBanner(), format.IsProxy():
+			if rtbImp.Banner == nil {
+				rtbImp.Banner = &openrtb2.Banner{
+					Width:    format.Width,
+					Height:   format.Height,
+					Position: openrtb2.AdPosition(imp.Pos),
+				}
+			}
+			rtbImp.Banner.Formats = append(rtbImp.Banner.Formats,
+				openrtb2.Format{Width: format.Width, Height: format.Height})
+		}
+	}
+	if rtbImp.Banner == nil && rtbImp.Native == nil {
+		return nil
+	}
+	return rtbImp
+}
+
+func nativeV2(imp *adtype.Impression, format *types.Format) *openrtb2.Native {
+	var nativeRequest any
+	switch {
+	case imp.RTBNativeRequestV3() != nil:
+		nativeRequest = imp.RTBNativeRequestV3()
+	case imp.RTBNativeRequest() != nil:
+		nativeRequest = imp.RTBNativeRequest()
+	default:
+		nativeRequest = defaultNativeRequest(imp, format)
+	}
+	data, err := json.Marshal(nativeRequest)
+	if err != nil {
+		return nil
+	}
+	// Native request is transferred as a JSON-encoded string
+	if data, err = json.Marshal(string(data)); err != nil {
+		return nil
+	}
+	return &openrtb2.Native{Request: data, Version: nativeVersion}
+}
+
+// nativeRequestAssetIDs returns the mapping of the asset IDs of the native request
+// prepared by the impression to the internal default IDs which are used by the response item.
+// The assets are matched by the type. Returns nil if the default native request is sent.
+func nativeRequestAssetIDs(imp *adtype.Impression) map[int]int {
+	var ids map[int]int
+	switch {
+	case imp.RTBNativeRequestV3() != nil:
+		assets := imp.RTBNativeRequestV3().Assets
+		ids = make(map[int]int, len(assets))
+		for _, asset := range assets {
+			var imageType, dataType int
+			if asset.Image != nil {
+				imageType = int(asset.Image.TypeID)
+			}
+			if asset.Data != nil {
+				dataType = int(asset.Data.TypeID)
+			}
+			ids[asset.ID] = nativeInternalAssetID(asset.Title != nil, asset.Image != nil, imageType, dataType)
+		}
+	case imp.RTBNativeRequest() != nil:
+		assets := imp.RTBNativeRequest().Assets
+		ids = make(map[int]int, len(assets))
+		for _, asset := range assets {
+			var imageType, dataType int
+			if asset.Image != nil {
+				imageType = int(asset.Image.TypeID)
+			}
+			if asset.Data != nil {
+				dataType = int(asset.Data.TypeID)
+			}
+			ids[asset.ID] = nativeInternalAssetID(asset.Title != nil, asset.Image != nil, imageType, dataType)
+		}
+	}
+	return ids
+}
+
+// nativeInternalAssetID by the type of the asset or 0 if the asset is not used by the response item
+func nativeInternalAssetID(title, image bool, imageType, dataType int) int {
+	switch {
+	case title:
+		return nativeAssetTitleID
+	case image && (imageType == int(natreq.ImageTypeIcon) || imageType == int(natreq.ImageTypeLogo)):
+		return nativeAssetIconID
+	case image:
+		return nativeAssetMainImageID
+	case dataType == int(natreq.DataTypeDesc):
+		return nativeAssetDescriptionID
+	case dataType == int(natreq.DataTypeSponsored):
+		return nativeAssetSponsoredID
+	}
+	return 0
+}
+
+// remapNativeAssets replaces the asset IDs of the native response by the internal ones.
+// The assets which are not present in the request are not used.
+func remapNativeAssets(native *natresp.Response, ids map[int]int) {
+	if ids == nil {
+		return
+	}
+	for i := range native.Assets {
+		native.Assets[i].ID = ids[native.Assets[i].ID]
+	}
+}
+
+func defaultNativeRequest(imp *adtype.Impression, format *types.Format) *natreq.Request {
+	return &natreq.Request{
+		Version:         nativeVersion,
+		ContextTypeID:   natreq.ContextTypeID(imp.ContextType()),
+		PlacementTypeID: natreq.PlacementTypeID(imp.PlacementType()),
+		PlacementCount:  max(imp.Count, 1),
+		Assets: []natreq.Asset{
+			{ID: nativeAssetTitleID, Required: 1, Title: &natreq.Title{Length: 90}},
+			{ID: nativeAssetMainImageID, Required: 1, Image: &natreq.Image{
+				TypeID:    natreq.ImageTypeMain,
+				WidthMin:  max(format.MinWidth, format.Width),
+				HeightMin: max(format.MinHeight, format.Height),
+			}},
+			{ID: nativeAssetDescriptionID, Data: &natreq.Data{TypeID: natreq.DataTypeDesc}},
+			{ID: nativeAssetIconID, Image: &natreq.Image{TypeID: natreq.ImageTypeIcon}},
+			{ID: nativeAssetSponsoredID, Data: &natreq.Data{TypeID: natreq.DataTypeSponsored}},
+		},
+	}
+}
+
+func userV2(user *adtype.User) *openrtb2.User {
+	if user == nil {
+		return nil
+	}
+	rtbUser := &openrtb2.User{
+		ID:       user.ID,
+		Gender:   user.Gender,
+		Keywords: user.Keywords,
+	}
+	// AgeStart keeps the age of the user, the year of birth is sent only if it's known
+	if user.AgeStart > 0 {
+		rtbUser.YearOfBirth = time.Now().Year() - user.AgeStart
+	}
+	if user.Geo != nil {
+		rtbUser.Geo = uopenrtb.GeoFrom(user.Geo)
+	}
+	for _, data := range user.Data {
+		rtbData := openrtb2.Data{Name: data.Name}
+		for _, segment := range data.Segment {
+			rtbData.Segment = append(rtbData.Segment,
+				openrtb2.Segment{Name: segment.Name, Value: segment.Value})
+		}
+		rtbUser.Data = append(rtbUser.Data, rtbData)
+	}
+	return rtbUser
+}
+
+func markupTypeV2(mtype openrtb2.MarkupType) types.FormatType {
+	switch mtype {
+	case openrtb2.MarkupBanner:
+		return types.FormatBannerType
+	case openrtb2.MarkupVideo:
+		return types.FormatVideoType
+	case openrtb2.MarkupNative:
+		return types.FormatNativeType
+	}
+	return types.FormatUndefinedType
+}
+
+func categoriesV2(cats []openrtb2.ContentCategory) []string {
+	if len(cats) == 0 {
+		return nil
+	}
+	categories := make([]string, 0, len(cats))
+	for _, cat := range cats {
+		categories = append(categories, string(cat))
+	}
+	return categories
+}
+
+func auctionType(at types.AuctionType) int {
+	if at.IsSecondPrice() {
+		return 2
+	}
+	return 1
+}
+
+func b2i(v bool) int {
+	if v {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,508 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package openrtb
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	openrtb2 "github.com/bsm/openrtb/v3"
+	natreq "github.com/bsm/openrtb/v3/native/request"
+	natresp "github.com/bsm/openrtb/v3/native/response"
+	"github.com/demdxx/gocast/v2"
+	uopenrtb "github.com/geniusrabbit/udetect/openrtb3"
+
+	"github.com/geniusrabbit/adcorelib/adformat"
+	"github.com/geniusrabbit/adcorelib/adformat/openrtbnative"
+	"github.com/geniusrabbit/adcorelib/adformat/openrtbvideo"
+	"github.com/geniusrabbit/adcorelib/admodels/types"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+const defaultNativeTitleLength = 90
+
+// codecV3 implements OpenRTB 3.0 protocol with AdCOM 1.0 domain objects
+type codecV3 struct{}
+
+// Version of the protocol
+func (codecV3) Version() string { return openrtb3Version }
+
+// Request object of the protocol
+func (codecV3) Request(drv *driver, request adtype.BidRequester) (any, error) {
+	items := make([]rtb3Item, 0, len(request.Impressions()))
+	for _, imp := range request.Impressions() {
+		if item := itemV3(drv, request, imp); item != nil {
+			items = append(items, *item)
+		}
+	}
+	if len(items) == 0 {
+		return nil, ErrNoImpressionsForRequest
+	}
+	rtbRequest := &rtb3Request{
+		ID:      request.ID(),
+		TMax:    int(drv.Timeout() / time.Millisecond),
+		At:      auctionType(drv.source.AuctionType),
+		Cur:     drv.currencies(),
+		Item:    items,
+		Context: contextV3(request),
+	}
+	if drv.source.Options.TestMode != 0 || request.IsDebug() {
+		rtbRequest.Test = 1
+	}
+	return &rtb3Root{OpenRTB: rtb3Openrtb{
+		Ver:        openrtb3Version,
+		DomainSpec: adcomDomainSpec,
+		DomainVer:  adcomDomainVersion,
+		Request:    rtbRequest,
+	}}, nil
+}
+
+// Response items decoded from the response body
+func (codecV3) Response(drv *driver, request adtype.BidRequester, body io.Reader) ([]adtype.ResponseItemCommon, error) {
+	var root rtb3Root
+	if err := json.NewDecoder(body).Decode(&root); err != nil {
+		return nil, err
+	}
+	rtbResponse := root.OpenRTB.Response
+	if rtbResponse == nil {
+		return nil, nil
+	}
+	currency := billing.CurrencyOf(rtbResponse.Cur)
+	if !drv.acceptsCurrency(currency) {
+		return nil, adtype.ErrInvalidCur
+	}
+	var items []adtype.ResponseItemCommon
+	for _, seat := range rtbResponse.SeatBid {
+		for i := range seat.Bid {
+			bid := &seat.Bid[i]
+			bidItem := &BidItem{
+				BidID:      bid.ID,
+				ImpID:      bid.Item,
+				CampaignID: bid.CID,
+				Seat:       seat.Seat,
+				DealID:     bid.Deal,
+				Price:      bid.Price,
+				Currency:   currency.String(),
+				WinURL:     bid.PURL,
+				BillingURL: bid.BURL,
+				LossURL:    bid.LURL,
+			}
+			if bid.Media == nil || bid.Media.Ad == nil {
+				continue
+			}
+			if err := adV3(drv, request, bidItem, bid.Media.Ad); err != nil {
+				continue
+			}
+			if item, err := drv.newItem(request, bidItem); err == nil {
+				items = append(items, item)
+			}
+		}
+	}
+	return items, nil
+}
+
+func itemV3(drv *driver, request adtype.BidRequester, imp *adtype.Impression) *rtb3Item {
+	placement := &adcomPlacement{
+		TagID:  gocast.Str(imp.TargetID()),
+		Secure: b2i(request.IsSecure()),
+	}
+	for _, format := range imp.Formats() {
+		placementV3(placement, imp, format, drv.adFormat(format))
+	}
+	if placement.Display == nil && placement.Video == nil {
+		return nil
+	}
+	flr, flrCur := drv.bidFloor(imp)
+	return &rtb3Item{
+		ID:     imp.ID,
+		Qty:    max(imp.Count, 1),
+		Flr:    flr,
+		FlrCur: flrCur,
+		Spec:   rtb3ItemSpec{Placement: placement},
+	}
+}
+
+// placementV3 extends the placement by the format.
+// The adformat.Format (if present) refines the sizes, native assets
+// and video requirements of the legacy format model.
+func placementV3(placement *adcomPlacement, imp *adtype.Impression, format *types.Format, adFormat *adformat.Format) {
+	switch {
+	case format.IsNative():
+		display := displayPlacementV3(placement, imp)
+		if display.NativeFmt == nil {
+			display.NativeFmt = nativeFormatV3(format, adFormat)
+			display.Context = int(imp.ContextType())
+			display.PType = int(imp.PlacementType())
+		}
+	case format.IsBanner(), format.IsProxy():
+		display := displayPlacementV3(placement, imp)
+		if adFormat != nil && len(adFormat.Sizes) > 0 {
+			for _, size := range adFormat.Sizes {
+				if !size.Flexible {
+					display.DisplayFmt = append(display.DisplayFmt,
+						adcomDisplayFormat{W: size.Width, H: size.Height})
+				}
+			}
+		} else if format.Width > 0 && format.Height > 0 {
+			display.DisplayFmt = append(display.DisplayFmt,
+				adcomDisplayFormat{W: format.Width, H: format.Height})
+		}
+		if display.W == 0 && len(display.DisplayFmt) > 0 {
+			display.W, display.H = display.DisplayFmt[0].W, display.DisplayFmt[0].H
+		}
+	case format.IsVideo():
+		if placement.Video == nil {
+			placement.Video = videoPlacementV3(imp, format, adFormat)
+		}
+	}
+}
+
+func displayPlacementV3(placement *adcomPlacement, imp *adtype.Impression) *adcomDisplayPlacement {
+	if placement.Display == nil {
+		placement.Display = &adcomDisplayPlacement{
+			Pos:   imp.Pos,
+			Instl: b2i(imp.Interstitial),
+		}
+	}
+	return placement.Display
+}
+
+func nativeFormatV3(format *types.Format, adFormat *adformat.Format) *adcomNativeFormat {
+	if cfg := adFormat.GetConfig(); cfg != nil {
+		var assets []adcomAssetFormat
+		for _, asset := range cfg.Assets() {
+			// Bindings are decoded by the shape of params, so the image
+			// binding is recognized by the image type
+			if params, ok := openrtbnative.GetImage(asset); ok && params.ImageType != 0 {
+				assets = append(assets, adcomAssetFormat{ID: params.ID, Req: b2i(asset.Required),
+					Img: &adcomImageFormat{
+						Type: params.ImageType,
+						W:    asset.Width,
+						H:    asset.Height,
+						WMin: asset.MinWidth,
+						HMin: asset.MinHeight,
+					}})
+			} else if params, ok := openrtbnative.GetVideo(asset); ok {
+				assets = append(assets, adcomAssetFormat{ID: params.ID, Req: b2i(asset.Required),
+					Video: &adcomVideoPlacement{
+						MinDur: int(asset.DurationMin),
+						MaxDur: int(asset.DurationMax),
+						API:    params.APIs,
+					}})
+			}
+		}
+		for _, field := range cfg.Fields() {
+			if params, ok := openrtbnative.GetData(field); ok && params.DataType != 0 {
+				assets = append(assets, adcomAssetFormat{ID: params.ID, Req: b2i(field.Required),
+					Data: &adcomDataFormat{Type: params.DataType, Len: int(field.Max)}})
+			} else if params, ok := openrtbnative.GetTitle(field); ok {
+				assets = append(assets, adcomAssetFormat{ID: params.ID, Req: b2i(field.Required),
+					Title: &adcomTitleFormat{Len: gocast.IfThen(field.Max > 0, int(field.Max), defaultNativeTitleLength)}})
+			}
+		}
+		if len(assets) > 0 {
+			return &adcomNativeFormat{Asset: assets}
+		}
+	}
+	return &adcomNativeFormat{Asset: []adcomAssetFormat{
+		{ID: nativeAssetTitleID, Req: 1, Title: &adcomTitleFormat{Len: defaultNativeTitleLength}},
+		{ID: nativeAssetMainImageID, Req: 1, Img: &adcomImageFormat{
+			Type: int(natreq.ImageTypeMain),
+			WMin: max(format.MinWidth, format.Width),
+			HMin: max(format.MinHeight, format.Height),
+		}},
+		{ID: nativeAssetDescriptionID, Data: &adcomDataFormat{Type: int(natreq.DataTypeDesc)}},
+		{ID: nativeAssetIconID, Img: &adcomImageFormat{Type: int(natreq.ImageTypeIcon)}},
+		{ID: nativeAssetSponsoredID, Data: &adcomDataFormat{Type: int(natreq.DataTypeSponsored)}},
+	}}
+}
+
+func videoPlacementV3(imp *adtype.Impression, format *types.Format, adFormat *adformat.Format) *adcomVideoPlacement {
+	video := &adcomVideoPlacement{Pos: imp.Pos, W: format.Width, H: format.Height}
+	if adFormat == nil {
+		return video
+	}
+	if params, ok := openrtbvideo.Get(*adFormat); ok {
+		video.API = params.APIs
+		video.Linear = params.Linearity
+		video.PlayMethod = params.PlaybackMethod
+	}
+	if size, ok := adFormat.DefaultSize(); ok && !size.Flexible && video.W == 0 {
+		video.W, video.H = size.Width, size.Height
+	}
+	if cfg := adFormat.GetConfig(); cfg != nil {
+		if asset := cfg.MainAsset(); asset != nil {
+			video.MinDur = int(asset.DurationMin)
+			video.MaxDur = int(asset.DurationMax)
+		}
+	}
+	return video
+}
+
+// adV3 fills the bid item by the AdCOM ad object
+func adV3(drv *driver, request adtype.BidRequester, bid *BidItem, ad *adcomAd) error {
+	bid.AdID = ad.ID
+	bid.CreativeID = ad.ID
+	bid.AdvDomains = ad.ADomain
+	bid.Categories = ad.Cat
+	switch {
+	case ad.Display != nil:
+		display := ad.Display
+		bid.Width, bid.Height = display.W, display.H
+		switch {
+		case display.Native != nil:
+			var ids map[int]int
+			if imp := request.ImpressionByID(bid.ImpID); imp != nil {
+				if format := imp.FormatByType(types.FormatNativeType); format != nil {
+					ids = nativeAssetIDs(drv.adFormat(format))
+				}
+			}
+			markup, err := json.Marshal(nativeResponseV3(display, ids))
+			if err != nil {
+				return err
+			}
+			bid.Markup = string(markup)
+			bid.MarkupType = types.FormatNativeType
+		case display.Adm != "":
+			bid.Markup = display.Adm
+			bid.MarkupType = types.FormatBannerType
+		case display.Banner != nil:
+			bid.Markup = bannerMarkupV3(display)
+			bid.MarkupType = types.FormatBannerType
+		default:
+			return adtype.ErrInvalidViewType
+		}
+	case ad.Video != nil && ad.Video.Adm != "":
+		bid.Markup = ad.Video.Adm
+		bid.MarkupType = types.FormatVideoType
+	default:
+		return adtype.ErrInvalidViewType
+	}
+	return nil
+}
+
+// nativeResponseV3 converts AdCOM native object into the Native 1.2 response
+// which is used by the response item. Asset IDs of the format bindings are
+// replaced by the internal ones.
+func nativeResponseV3(display *adcomDisplay, ids map[int]int) *natresp.Response {
+	native := display.Native
+	resp := &natresp.Response{Version: nativeVersion}
+	if native.Link != nil {
+		resp.Link = natresp.Link{URL: native.Link.URL, FallbackURL: native.Link.URLFB, ClickTrackers: native.Link.Trkr}
+	}
+	for _, event := range display.Event {
+		if event.Type == adcomEventImpression && event.Method == adcomMethodImage && event.URL != "" {
+			resp.ImpTrackers = append(resp.ImpTrackers, event.URL)
+		}
+	}
+	for _, asset := range native.Asset {
+		id := asset.ID
+		if internalID, ok := ids[id]; ok {
+			id = internalID
+		}
+		respAsset := natresp.Asset{ID: id, Required: asset.Req}
+		switch {
+		case asset.Title != nil:
+			respAsset.Title = &natresp.Title{Text: asset.Title.Text}
+		case asset.Img != nil:
+			respAsset.Image = &natresp.Image{URL: asset.Img.URL, Width: asset.Img.W, Height: asset.Img.H}
+		case asset.Data != nil:
+			respAsset.Data = &natresp.Data{Value: asset.Data.Value}
+		case asset.Video != nil:
+			respAsset.Video = &natresp.Video{VASTTag: asset.Video.Adm}
+		}
+		if asset.Link != nil {
+			respAsset.Link = &natresp.Link{URL: asset.Link.URL, FallbackURL: asset.Link.URLFB, ClickTrackers: asset.Link.Trkr}
+		}
+		resp.Assets = append(resp.Assets, respAsset)
+	}
+	return resp
+}
+
+// nativeAssetIDs returns the mapping of the format binding asset IDs
+// to the internal default IDs which are used by the response item
+func nativeAssetIDs(adFormat *adformat.Format) map[int]int {
+	cfg := adFormat.GetConfig()
+	if cfg == nil {
+		return nil
+	}
+	ids := map[int]int{}
+	for _, asset := range cfg.Assets() {
+		params, ok := openrtbnative.GetImage(asset)
+		if !ok || params.ImageType == 0 {
+			continue
+		}
+		switch {
+		case params.ImageType == openrtbnative.ImageTypeMain || asset.Name == types.FormatAssetMain:
+			ids[params.ID] = nativeAssetMainImageID
+		case params.ImageType == openrtbnative.ImageTypeIcon || params.ImageType == openrtbnative.ImageTypeLogo:
+			ids[params.ID] = nativeAssetIconID
+		}
+	}
+	for _, field := range cfg.Fields() {
+		if params, ok := openrtbnative.GetData(field); ok && params.DataType != 0 {
+			switch params.DataType {
+			case openrtbnative.DataTypeDesc:
+				ids[params.ID] = nativeAssetDescriptionID
+			case openrtbnative.DataTypeSponsored:
+				ids[params.ID] = nativeAssetSponsoredID
+			}
+		} else if params, ok := openrtbnative.GetTitle(field); ok {
+			ids[params.ID] = nativeAssetTitleID
+		}
+	}
+	return ids
+}
+
+func bannerMarkupV3(display *adcomDisplay) string {
+	img := fmt.Sprintf(`<img src="%s" width="%d" height="%d" border="0" />`,
+		html.EscapeString(display.Banner.Img), display.W, display.H)
+	if display.Banner.Link == nil || display.Banner.Link.URL == "" {
+		return img
+	}
+	return fmt.Sprintf(`<a href="%s" target="_blank">%s</a>`,
+		html.EscapeString(display.Banner.Link.URL), img)
+}
+
+func contextV3(request adtype.BidRequester) *adcomContext {
+	ctx := &adcomContext{
+		Device: deviceV3(uopenrtb.DeviceFrom(request.DeviceInfo(), request.GeoInfo())),
+		User:   userV3(userV2(request.UserInfo())),
+	}
+	if app := request.AppInfo(); app != nil {
+		if rtbApp := uopenrtb.ApplicationFrom(app); rtbApp != nil {
+			ctx.App = &adcomApp{
+				adcomDistributionChannel: distributionChannelV3(&rtbApp.Inventory),
+				Bundle:                   rtbApp.Bundle,
+				StoreURL:                 rtbApp.StoreURL,
+				Ver:                      rtbApp.Version,
+				Paid:                     rtbApp.Paid,
+			}
+		}
+	} else if rtbSite := uopenrtb.SiteFrom(request.SiteInfo()); rtbSite != nil {
+		ctx.Site = &adcomSite{
+			adcomDistributionChannel: distributionChannelV3(&rtbSite.Inventory),
+			Page:                     rtbSite.Page,
+			Ref:                      rtbSite.Referrer,
+			Search:                   rtbSite.Search,
+			Mobile:                   rtbSite.Mobile,
+		}
+	}
+	return ctx
+}
+
+func distributionChannelV3(inv *openrtb2.Inventory) adcomDistributionChannel {
+	channel := adcomDistributionChannel{
+		ID:       inv.ID,
+		Name:     inv.Name,
+		Domain:   inv.Domain,
+		Keywords: inv.Keywords,
+	}
+	for _, cat := range inv.Categories {
+		channel.Cat = append(channel.Cat, string(cat))
+	}
+	if inv.Publisher != nil {
+		channel.Pub = &adcomPublisher{
+			ID:     inv.Publisher.ID,
+			Name:   inv.Publisher.Name,
+			Domain: inv.Publisher.Domain,
+		}
+	}
+	return channel
+}
+
+func deviceV3(device *openrtb2.Device) *adcomDevice {
+	if device == nil {
+		return nil
+	}
+	return &adcomDevice{
+		Type:    int(device.DeviceType),
+		UA:      device.UA,
+		IFA:     device.IFA,
+		DNT:     device.DNT,
+		LMT:     device.LMT,
+		Make:    device.Make,
+		Model:   device.Model,
+		OS:      osV3(device.OS),
+		OSV:     device.OSVersion,
+		HWV:     device.HWVersion,
+		H:       device.Height,
+		W:       device.Width,
+		PPI:     device.PPI,
+		PxRatio: device.PixelRatio,
+		JS:      device.JS,
+		Lang:    device.Language,
+		IP:      device.IP,
+		IPv6:    device.IPv6,
+		Carrier: device.Carrier,
+		MCCMNC:  device.MCCMNC,
+		ConType: int(device.ConnType),
+		Geo:     geoV3(device.Geo),
+	}
+}
+
+func geoV3(geo *openrtb2.Geo) *adcomGeo {
+	if geo == nil {
+		return nil
+	}
+	return &adcomGeo{
+		Type:      int(geo.Type),
+		Lat:       geo.Latitude,
+		Lon:       geo.Longitude,
+		Accur:     geo.Accuracy,
+		LastFix:   geo.LastFix,
+		Country:   geo.Country,
+		Region:    geo.Region,
+		Metro:     geo.Metro,
+		City:      geo.City,
+		ZIP:       geo.ZIP,
+		UTCOffset: geo.UTCOffset,
+	}
+}
+
+func userV3(user *openrtb2.User) *adcomUser {
+	if user == nil {
+		return nil
+	}
+	adcomUser := &adcomUser{
+		ID:       user.ID,
+		YOB:      user.YearOfBirth,
+		Gender:   user.Gender,
+		Keywords: user.Keywords,
+		Geo:      geoV3(user.Geo),
+	}
+	for _, data := range user.Data {
+		adcomData := adcomData{ID: data.ID, Name: data.Name}
+		for _, segment := range data.Segment {
+			adcomData.Segment = append(adcomData.Segment,
+				adcomSegment{ID: segment.ID, Name: segment.Name, Value: segment.Value})
+		}
+		adcomUser.Data = append(adcomUser.Data, adcomData)
+	}
+	return adcomUser
+}
+
+// osV3 converts the OS name into the AdCOM operating system code
+func osV3(name string) int {
+	switch name = strings.ToLower(name); {
+	case strings.Contains(name, "android"):
+		return adcomOSAndroid
+	case strings.Contains(name, "ios"), strings.Contains(name, "iphone"), strings.Contains(name, "ipad"):
+		return adcomOSIOS
+	case strings.Contains(name, "mac"):
+		return adcomOSMacOS
+	case strings.Contains(name, "windows"):
+		return adcomOSWindows
+	case strings.Contains(name, "linux"):
+		return adcomOSLinux
+	}
+	return adcomOSOther
+}
@@ -0,0 +1,161 @@
+package openrtb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/geniusrabbit/adcorelib/adformat"
+	"github.com/geniusrabbit/adcorelib/adformat/adformattest"
+	"github.com/geniusrabbit/adcorelib/admodels"
+	"github.com/geniusrabbit/adcorelib/admodels/types"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
+	"github.com/geniusrabbit/adcorelib/net/httpclient/stdhttpclient"
+)
+
+func newTestServerV3(t *testing.T, check func(req *rtb3Request), ad *adcomAd) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "3.0", r.Header.Get("X-Openrtb-Version"))
+
+		var root rtb3Root
+		if !assert.NoError(t, json.NewDecoder(r.Body).Decode(&root)) || !assert.NotNil(t, root.OpenRTB.Request) {
+			return
+		}
+		assert.Equal(t, "adcom", root.OpenRTB.DomainSpec)
+		req := root.OpenRTB.Request
+		if !assert.Len(t, req.Item, 1) {
+			return
+		}
+		check(req)
+
+		_ = json.NewEncoder(w).Encode(&rtb3Root{OpenRTB: rtb3Openrtb{
+			Ver: openrtb3Version,
+			Response: &rtb3Response{
+				ID: req.ID,
+				SeatBid: []rtb3SeatBid{{Seat: "dsp", Bid: []rtb3Bid{
+					{ID: "b1", Item: "imp1", Price: 1.5, PURL: "https://win?p=${OPENRTB_PRICE}", Media: &rtb3Media{Ad: ad}},
+					{ID: "b2", Item: "imp1", Price: 1.2},
+				}}},
+			},
+		}})
+	}))
+}
+
+func TestDriverV3BannerBid(t *testing.T) {
+	server := newTestServerV3(t, func(req *rtb3Request) {
+		item := req.Item[0]
+		assert.Equal(t, 1.0, item.Flr)
+		if assert.NotNil(t, item.Spec.Placement) && assert.NotNil(t, item.Spec.Placement.Display) {
+			assert.Equal(t, []adcomDisplayFormat{{W: 300, H: 250}}, item.Spec.Placement.Display.DisplayFmt)
+		}
+	}, &adcomAd{ID: "ad1", ADomain: []string{"adv.com"}, Display: &adcomDisplay{
+		W: 300, H: 250, Banner: &adcomBanner{Img: "https://img", Link: &adcomLink{URL: "https://click"}},
+	}})
+	defer server.Close()
+
+	source := newTestSource(server.URL)
+	source.Protocol = ProtocolOpenRTB3
+	drv, err := NewFactory(stdhttpclient.NewDriver()).New(context.Background(), source)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "OpenRTB 3.0", drv.(*driver).Info().Name)
+
+	resp := drv.Bid(newTestRequest("banner_300x250"))
+	if !assert.NoError(t, resp.Error()) || !assert.Equal(t, 1, resp.Count()) {
+		return
+	}
+	item := resp.Ads()[0].(*ResponseBidItem)
+	assert.NoError(t, item.Validate())
+	assert.Equal(t, "ad1", item.Bid.AdID)
+	assert.Equal(t, []string{"adv.com"}, item.Bid.AdvDomains)
+	assert.Contains(t, item.ContentItemString(adtype.ContentItemContent), `src="https://img"`)
+	assert.Equal(t, "https://win?p=1.5", item.ContentItemString(adtype.ContentItemNotifyWinURL))
+}
+
+func TestDriverV3NativeBid(t *testing.T) {
+	server := newTestServerV3(t, func(req *rtb3Request) {
+		display := req.Item[0].Spec.Placement.Display
+		if !assert.NotNil(t, display) || !assert.NotNil(t, display.NativeFmt) {
+			return
+		}
+		// Asset IDs are taken from the openrtb_native bindings of the format
+		ids := map[int]bool{}
+		for _, asset := range display.NativeFmt.Asset {
+			ids[asset.ID] = true
+			if asset.Title != nil {
+				assert.Equal(t, 3, asset.ID)
+				assert.Equal(t, 40, asset.Title.Len)
+			}
+		}
+		assert.Len(t, ids, 7)
+	}, &adcomAd{ID: "ad1", Display: &adcomDisplay{
+		Native: &adcomNative{
+			Link: &adcomLink{URL: "https://click"},
+			Asset: []adcomAsset{
+				{ID: 3, Title: &adcomTitleAsset{Text: "Title"}},
+				{ID: 1, Img: &adcomImageAsset{URL: "https://img", W: 100, H: 100}},
+				{ID: 4, Data: &adcomDataAsset{Value: "Desc"}},
+				{ID: 5, Data: &adcomDataAsset{Value: "Brand"}},
+			},
+		},
+		Event: []adcomEvent{{Type: adcomEventImpression, Method: adcomMethodImage, URL: "https://imp"}},
+	}})
+	defer server.Close()
+
+	source := newTestSource(server.URL)
+	source.Protocol = ProtocolOpenRTB3
+	drv, err := NewFactory(nil).New(context.Background(), source,
+		adformat.Accessor(adformattest.NewAccessor()))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	resp := drv.Bid(newTestRequest("native"))
+	if !assert.NoError(t, resp.Error()) || !assert.Equal(t, 1, resp.Count()) {
+		return
+	}
+	item := resp.Ads()[0].(*ResponseBidItem)
+	assert.NoError(t, item.Validate())
+	assert.Equal(t, "Title", item.ContentItemString(types.FormatFieldTitle))
+	assert.Equal(t, "Desc", item.ContentItemString(types.FormatFieldDescription))
+	assert.Equal(t, "Brand", item.ContentItemString(types.FormatFieldSponsored))
+	assert.Equal(t, "https://click", item.ActionURL())
+	assert.Equal(t, []string{"https://imp"}, item.ImpressionTrackerLinks())
+	if assert.NotNil(t, item.MainAsset()) {
+		assert.Equal(t, "https://img", item.MainAsset().URL)
+	}
+}
+
+func TestCodecByProtocol(t *testing.T) {
+	tests := []struct {
+		protocol string
+		version  string
+		err      error
+	}{
+		{protocol: "", version: "2.5"},
+		{protocol: ProtocolOpenRTB, version: "2.5"},
+		{protocol: ProtocolOpenRTB2, version: "2.5"},
+		{protocol: ProtocolOpenRTB3, version: "3.0"},
+		{protocol: "unknown", err: ErrUnsupportedProtocol},
+	}
+	for _, test := range tests {
+		t.Run(test.protocol, func(t *testing.T) {
+			_, err := NewFactory(nil).New(context.Background(), &admodels.RTBSource{
+				URL: "http://localhost", Protocol: test.protocol, MinBid: billing.MoneyFloat(1.)})
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+				return
+			}
+			codec, err := codecByProtocol(test.protocol)
+			if assert.NoError(t, err) {
+				assert.Equal(t, test.version, codec.Version())
+			}
+		})
+	}
+}
@@ -0,0 +1,498 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package openrtb
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	natresp "github.com/bsm/openrtb/v3/native/response"
+
+	"github.com/geniusrabbit/adcorelib/admodels"
+	"github.com/geniusrabbit/adcorelib/admodels/types"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/adtype/prices"
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+// BidItem contains the protocol independent information of the bid
+type BidItem struct {
+	BidID      string
+	ImpID      string
+	AdID       string
+	CampaignID string
+	CreativeID string
+	Seat       string
+	DealID     string
+
+	Price      float64 // Bid price in CPM
+	Currency   string  // Currency of the bid price (USD by default)
+	Markup     string
+	MarkupType types.FormatType
+
+	WinURL     string // nurl
+	BillingURL string // burl
+	LossURL    string // lurl
+
+	AdvDomains []string
+	Categories []string
+	Width      int
+	Height     int
+}
+
+// ResponseBidItem is the response item of the external platform bid
+type ResponseBidItem struct {
+	ItemID string
+	Bid    BidItem
+
+	Src       adtype.Source
+	Req       adtype.BidRequester
+	Imp       *adtype.Impression
+	FormatVal *types.Format
+
+	// Native response decoded from the markup if the format is native
+	Native *natresp.Response
+
+	PriceScope prices.PriceScope
+
+	// Base currency of the prices and the rates to convert them back into the bid currency
+	base  billing.Currency
+	rates billing.ExchangeRates
+
+	context context.Context
+}
+
+// ID of current response item (unique code of current response)
+func (it *ResponseBidItem) ID() string { return it.ItemID }
+
+// Impression place object
+func (it *ResponseBidItem) Impression() *adtype.Impression { return it.Imp }
+
+// ImpressionID unique code string
+func (it *ResponseBidItem) ImpressionID() string { return it.Imp.ID }
+
+// ExtImpressionID it's unique code of the auction bid impression
+func (it *ResponseBidItem) ExtImpressionID() string { return it.Bid.ImpID }
+
+// ExtTargetID of the external network
+func (it *ResponseBidItem) ExtTargetID() string { return it.Imp.ExternalTargetID }
+
+// TargetCodename of the target placement codename
+func (it *ResponseBidItem) TargetCodename() string { return it.Imp.TargetCodename() }
+
+// NetworkName by source
+func (it *ResponseBidItem) NetworkName() string { return it.Bid.Seat }
+
+// Validate item
+func (it *ResponseBidItem) Validate() error {
+	if it.Imp == nil || it.FormatVal == nil {
+		return adtype.ErrInvalidItemInitialisation
+	}
+	if it.FormatVal.IsNative() {
+		if it.Native == nil {
+			return adtype.ErrInvalidViewType
+		}
+	} else if it.Bid.Markup == "" && it.Bid.WinURL == "" {
+		return adtype.ErrResponseItemEmpty
+	}
+	return nil
+}
+
+// AccountID returns the unique identifier of the advertiser's account.
+func (it *ResponseBidItem) AccountID() uint64 {
+	if it.Src == nil {
+		return 0
+	}
+	return it.Src.AccountID()
+}
+
+// CampaignID returns the unique identifier of the advertising campaign.
+func (*ResponseBidItem) CampaignID() uint64 { return 0 }
+
+// AdID returns the unique identifier of the advertisement.
+func (it *ResponseBidItem) AdID() string { return it.Bid.AdID }
+
+// CreativeID returns the unique identifier of the creative.
+func (it *ResponseBidItem) CreativeID() string { return it.Bid.CreativeID }
+
+// AdvertiserDomains returns the advertiser domains of the bid.
+func (it *ResponseBidItem) AdvertiserDomains() []string { return it.Bid.AdvDomains }
+
+// AdCategories returns the IAB content categories of the bid.
+func (it *ResponseBidItem) AdCategories() []string { return it.Bid.Categories }
+
+// ExtCampaignID returns the campaign identifier of the external platform.
+func (it *ResponseBidItem) ExtCampaignID() string { return it.Bid.CampaignID }
+
+// OriginalBidCPM returns the bid of the external platform in its currency
+func (it *ResponseBidItem) OriginalBidCPM() billing.Amount {
+	return billing.AmountOf(billing.MoneyFloat(it.Bid.Price), billing.Currency(it.Bid.Currency))
+}
+
+// BaseCurrency returns the currency of the item prices
+func (it *ResponseBidItem) BaseCurrency() billing.Currency {
+	if it.base == "" {
+		return billing.DefaultCurrency
+	}
+	return it.base
+}
+
+// Source of response
+func (it *ResponseBidItem) Source() adtype.Source { return it.Src }
+
+// PriorityFormatType from current Ad
+func (it *ResponseBidItem) PriorityFormatType() types.FormatType {
+	if formatType := it.FormatVal.Types.HasOneType(); formatType > types.FormatUndefinedType {
+		return formatType
+	}
+	return it.Imp.FormatTypes.Intersec(it.FormatVal.Types).FirstType()
+}
+
+// Format object
+func (it *ResponseBidItem) Format() *types.Format { return it.FormatVal }
+
+///////////////////////////////////////////////////////////////////////////////
+// Content data accessor method
+///////////////////////////////////////////////////////////////////////////////
+
+// ContentItem returns the ad response data
+func (it *ResponseBidItem) ContentItem(name string) any {
+	if it.Native == nil {
+		switch name {
+		case adtype.ContentItemContent:
+			return it.PrepareURL(it.Bid.Markup)
+		case adtype.ContentItemNotifyWinURL:
+			return it.PrepareURL(it.Bid.WinURL)
+		case adtype.ContentItemNotifyDisplayURL:
+			return it.PrepareURL(it.Bid.BillingURL)
+		}
+		return nil
+	}
+	switch name {
+	case adtype.ContentItemLink:
+		return it.Native.Link.URL
+	case adtype.ContentItemNotifyWinURL:
+		return it.PrepareURL(it.Bid.WinURL)
+	case adtype.ContentItemNotifyDisplayURL:
+		return it.PrepareURL(it.Bid.BillingURL)
+	case types.FormatFieldTitle:
+		for _, asset := range it.Native.Assets {
+			if asset.Title != nil {
+				return asset.Title.Text
+			}
+		}
+	case types.FormatFieldDescription:
+		return it.nativeData(nativeAssetDescriptionID)
+	case types.FormatFieldSponsored, types.FormatFieldBrandname:
+		return it.nativeData(nativeAssetSponsoredID)
+	}
+	return nil
+}
+
+// ContentItemString from the ad
+func (it *ResponseBidItem) ContentItemString(name string) string {
+	val, _ := it.ContentItem(name).(string)
+	return val
+}
+
+// ContentFields from advertisement object
+func (it *ResponseBidItem) ContentFields() map[string]any {
+	if it.Native == nil {
+		return map[string]any{adtype.ContentItemContent: it.ContentItem(adtype.ContentItemContent)}
+	}
+	return map[string]any{
+		types.FormatFieldTitle:       it.ContentItem(types.FormatFieldTitle),
+		types.FormatFieldDescription: it.ContentItem(types.FormatFieldDescription),
+		types.FormatFieldSponsored:   it.ContentItem(types.FormatFieldSponsored),
+	}
+}
+
+// MainAsset from response
+func (it *ResponseBidItem) MainAsset() *admodels.AdFileAsset {
+	return it.Asset(types.FormatAssetMain)
+}
+
+// Asset by name
+func (it *ResponseBidItem) Asset(name string) *admodels.AdFileAsset {
+	for _, asset := range it.Assets() {
+		if asset.Name == name {
+			return asset
+		}
+	}
+	return nil
+}
+
+// Assets list
+func (it *ResponseBidItem) Assets() (assets admodels.AdFileAssets) {
+	if it.Native == nil {
+		return nil
+	}
+	for _, asset := range it.Native.Assets {
+		if asset.Image == nil {
+			continue
+		}
+		name := types.FormatAssetMain
+		if asset.ID == nativeAssetIconID {
+			name = types.FormatAssetIcon
+		}
+		assets = append(assets, &admodels.AdFileAsset{
+			ExternalID: strconv.Itoa(asset.ID),
+			Name:       name,
+			URL:        asset.Image.URL,
+			Type:       types.AdFileAssetImageType,
+			Width:      asset.Image.Width,
+			Height:     asset.Image.Height,
+		})
+	}
+	return assets
+}
+
+// ImpressionTrackerLinks returns traking links for impression action
+func (it *ResponseBidItem) ImpressionTrackerLinks() []string {
+	if it.Native == nil {
+		return nil
+	}
+	return it.Native.ImpTrackers
+}
+
+// ViewTrackerLinks returns traking links for view action
+func (*ResponseBidItem) ViewTrackerLinks() []string { return nil }
+
+// ClickTrackerLinks returns traking links for click action
+func (it *ResponseBidItem) ClickTrackerLinks() []string {
+	if it.Native == nil {
+		return nil
+	}
+	return it.Native.Link.ClickTrackers
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Price calculation methods
+///////////////////////////////////////////////////////////////////////////////
+
+// PricingModel of advertisement
+func (*ResponseBidItem) PricingModel() types.PricingModel { return types.PricingModelCPM }
+
+// FixedPurchasePrice returns the fixed price of the action
+func (it *ResponseBidItem) FixedPurchasePrice(action adtype.Action) billing.Money {
+	return it.Imp.PurchasePrice(action)
+}
+
+// ECPM returns the effective cost per mille of the item.
+func (it *ResponseBidItem) ECPM() billing.Money { return it.PriceScope.EffectiveCPM() }
+
+// Price per specific action type (view, click, lead, etc)
+func (it *ResponseBidItem) Price(action adtype.Action) billing.Money {
+	return it.PriceScope.PricePerAction(action)
+}
+
+// SetBidPrice sets the current bid price for the given action
+func (it *ResponseBidItem) SetBidPrice(action adtype.Action, bid billing.Money, withCommission bool) error {
+	return it.PriceScope.SetBidPrice(action, bid, it, withCommission)
+}
+
+// PrepareBidPrice prepares the bid price for the given action by clamping it
+// to the maximal allowed bid of that action (if defined).
+func (it *ResponseBidItem) PrepareBidPrice(action adtype.Action, p billing.Money) billing.Money {
+	return it.PriceScope.PrepareBidPerAction(action, p)
+}
+
+// PurchasePrice gives the price of view from external resource.
+// The cost of this request.
+func (it *ResponseBidItem) PurchasePrice(action adtype.Action) billing.Money {
+	return it.PriceScope.PublisherPricePerAction(action, it)
+}
+
+// PotentialPrice wich can be received from source but was marked as descrepancy
+func (it *ResponseBidItem) PotentialPrice(action adtype.Action) billing.Money {
+	return it.PriceScope.PotentialPricePerAction(action)
+}
+
+// FinalPrice returns final price for the item which is including all possible commissions with all corrections
+func (it *ResponseBidItem) FinalPrice(action adtype.Action) billing.Money {
+	return it.PriceScope.AdvertiserPricePerAction(action, it)
+}
+
+// InternalAuctionCPMBid value provides maximal possible price without any comission
+// and reduced by the price correction of the source
+func (it *ResponseBidItem) InternalAuctionCPMBid() billing.Money {
+	return billing.MoneyFloat(it.ECPM().Float64() * (1 - it.SourceCorrectionFactor()))
+}
+
+// Second campaigns
+func (*ResponseBidItem) Second() *adtype.SecondAd { return nil }
+
+///////////////////////////////////////////////////////////////////////////////
+// Revenue share/comission methods
+///////////////////////////////////////////////////////////////////////////////
+
+// CommissionShareFactor returns the commission share percentage which system gets from publisher.
+func (it *ResponseBidItem) CommissionShareFactor() float64 {
+	return it.Imp.CommissionShareFactor()
+}
+
+// SourceCorrectionFactor value for the source
+func (it *ResponseBidItem) SourceCorrectionFactor() float64 {
+	if it.Src == nil {
+		return 0
+	}
+	return it.Src.PriceCorrectionReduceFactor()
+}
+
+// TargetCorrectionFactor value for the target
+func (it *ResponseBidItem) TargetCorrectionFactor() float64 {
+	if it.Imp == nil || it.Imp.Target == nil {
+		return 0
+	}
+	return it.Imp.Target.RevenueShareReduceFactor()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Context methods
+///////////////////////////////////////////////////////////////////////////////
+
+// Context value
+func (it *ResponseBidItem) Context(ctx ...context.Context) context.Context {
+	if len(ctx) > 0 {
+		it.context = ctx[0]
+	}
+	if it.context == nil && it.Req != nil {
+		return it.Req.Context()
+	}
+	return it.context
+}
+
+// Get ext field
+func (*ResponseBidItem) Get(key string) any { return nil }
+
+///////////////////////////////////////////////////////////////////////////////
+// Other methods
+///////////////////////////////////////////////////////////////////////////////
+
+// IsDirect AD type
+func (it *ResponseBidItem) IsDirect() bool { return it.FormatVal.IsDirect() }
+
+// IsBackup indicates whether the advertisement is a backup ad type.
+func (*ResponseBidItem) IsBackup() bool { return false }
+
+// ActionURL returns target resource link for direct and banner click as well
+func (it *ResponseBidItem) ActionURL() string {
+	if it.Native != nil {
+		return it.Native.Link.URL
+	}
+	if it.IsDirect() {
+		return it.Bid.Markup
+	}
+	return ""
+}
+
+// Width of item
+func (it *ResponseBidItem) Width() int {
+	if it.Bid.Width > 0 {
+		return it.Bid.Width
+	}
+	return it.FormatVal.Width
+}
+
+// Height of item
+func (it *ResponseBidItem) Height() int {
+	if it.Bid.Height > 0 {
+		return it.Bid.Height
+	}
+	return it.FormatVal.Height
+}
+
+// PrepareURL replaces the OpenRTB 2.x and 3.0 substitution macros of the value
+func (it *ResponseBidItem) PrepareURL(value string) string {
+	if value == "" || (!strings.Contains(value, "${AUCTION_") && !strings.Contains(value, "${OPENRTB_")) {
+		return value
+	}
+	price, currency := it.bidCurrencyCPM(prices.CPMFromPrice(it.Price(adtype.ActionImpression)))
+	priceStr := strconv.FormatFloat(price.Float64(), 'f', -1, 64)
+	return strings.NewReplacer(
+		"${AUCTION_ID}", it.auctionID(),
+		"${AUCTION_BID_ID}", it.Bid.BidID,
+		"${AUCTION_IMP_ID}", it.Bid.ImpID,
+		"${AUCTION_SEAT_ID}", it.Bid.Seat,
+		"${AUCTION_AD_ID}", it.Bid.AdID,
+		"${AUCTION_PRICE}", priceStr,
+		"${AUCTION_CURRENCY}", currency.String(),
+		"${OPENRTB_ID}", it.auctionID(),
+		"${OPENRTB_BID_ID}", it.Bid.BidID,
+		"${OPENRTB_ITEM_ID}", it.Bid.ImpID,
+		"${OPENRTB_SEAT_ID}", it.Bid.Seat,
+		"${OPENRTB_MEDIA_ID}", it.Bid.AdID,
+		"${OPENRTB_PRICE}", priceStr,
+		"${OPENRTB_CURRENCY}", currency.String(),
+	).Replace(value)
+}
+
+// bidCurrencyCPM converts the CPM of the base currency into the bid currency for the macros.
+// The base currency is used if the conversion is impossible.
+func (it *ResponseBidItem) bidCurrencyCPM(cpm billing.Money) (billing.Money, billing.Currency) {
+	base, currency := it.BaseCurrency(), billing.CurrencyOf(it.Bid.Currency)
+	if currency == base || it.rates == nil {
+		return cpm, base
+	}
+	if value, err := it.rates.Convert(cpm, base, currency); err == nil {
+		return value, currency
+	}
+	return cpm, base
+}
+
+func (it *ResponseBidItem) auctionID() string {
+	if it.Req == nil {
+		return ""
+	}
+	return it.Req.ID()
+}
+
+func (it *ResponseBidItem) nativeData(id int) string {
+	var first string
+	for _, asset := range it.Native.Assets {
+		if asset.Data == nil {
+			continue
+		}
+		if asset.ID == id {
+			return asset.Data.Value
+		}
+		if first == "" {
+			first = asset.Data.Value
+		}
+	}
+	if id == nativeAssetDescriptionID {
+		return first
+	}
+	return ""
+}
+
+// decodeNativeMarkup supports both wrapped {"native":{...}} and plain response objects
+func decodeNativeMarkup(markup string) (*natresp.Response, error) {
+	var wrapper struct {
+		Native *natresp.Response `json:"native"`
+	}
+	if err := json.Unmarshal([]byte(markup), &wrapper); err != nil {
+		return nil, err
+	}
+	if wrapper.Native != nil {
+		return wrapper.Native, nil
+	}
+	var resp natresp.Response
+	if err := json.Unmarshal([]byte(markup), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+var (
+	_ adtype.ResponseItem           = (*ResponseBidItem)(nil)
+	_ adtype.ResponseItemAdvertiser = (*ResponseBidItem)(nil)
+	_ adtype.ResponseItemCurrency   = (*ResponseBidItem)(nil)
+	_ prices.Factors                = (*ResponseBidItem)(nil)
+	_ prices.FixedPurchasePricer    = (*ResponseBidItem)(nil)
+)
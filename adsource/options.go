@@ -3,6 +3,8 @@ package adsource
 import (
 	"time"
 
+	"github.com/geniusrabbit/adcorelib/adsource/experiments"
+	"github.com/geniusrabbit/adcorelib/adsource/floors"
 	"github.com/geniusrabbit/adcorelib/adtype"
 )
 
@@ -37,9 +39,84 @@ func WithMaxParallelRequests(maxParallelRequest int) Option {
 	}
 }
 
-// WithResponsePreprocessor returns a response preprocessor
-func WithResponsePreprocessor(preprocessor ResponsePreprocessor) Option {
+// WithResponsePreprocessor returns a response preprocessor.
+// Several preprocessors are applied in the order of the arguments (see ResponsePreprocessorChain).
+func WithResponsePreprocessor(preprocessors ...ResponsePreprocessor) Option {
 	return func(wrp *MultisourceWrapper) {
-		wrp.responsePreprocessor = preprocessor
+		wrp.responsePreprocessor = NewResponsePreprocessorChain(preprocessors...)
+	}
+}
+
+// WithRequestStrategy of the source requests (asynchronous by default).
+// The waterfall strategy splits the sources into tiers by the priority.
+func WithRequestStrategy(strategy adtype.RequestStrategy) Option {
+	return func(wrp *MultisourceWrapper) {
+		wrp.requestStrategy = strategy
+	}
+}
+
+// WithWaterfallTierTimeout limits the time budget of every tier of the waterfall strategy,
+// so the rest of the request time is left for the next tiers
+func WithWaterfallTierTimeout(timeout time.Duration) Option {
+	return func(wrp *MultisourceWrapper) {
+		wrp.waterfallTierTimeout = timeout
+	}
+}
+
+// WithEarlyExit finishes the auction without waiting for the rest of sources
+// once any of the policies is complete. Late responses are logged with events.StatusLate.
+func WithEarlyExit(policies ...EarlyExitPolicy) Option {
+	return func(wrp *MultisourceWrapper) {
+		wrp.earlyExit = append(wrp.earlyExit, policies...)
+	}
+}
+
+// WithShadowSources sends the sampled copy of requests (sampleRate in [0, 1]) to the shadow sources.
+// Shadow sources are requested in the separate pool and never compete in the auction;
+// their bids which would have won are reported as events.SourceShadowWin.
+func WithShadowSources(sources adtype.SourceAccessor, sampleRate float64, maxParallelRequests int) Option {
+	return func(wrp *MultisourceWrapper) {
+		wrp.shadow = newShadowTraffic(sources, sampleRate, maxParallelRequests)
+	}
+}
+
+// WithLoadShedding enables the admission control of the execution pool.
+// Under the high load the sources with the lowest priority are skipped first,
+// down to the main source only on the extreme load.
+func WithLoadShedding(opts ...LoadSheddingOption) Option {
+	return func(wrp *MultisourceWrapper) {
+		wrp.loadShedder = newLoadShedder(opts...)
+	}
+}
+
+// WithMetrics of the source requests (the default metrics are registered in prometheus.DefaultRegisterer)
+func WithMetrics(metrics *Metrics) Option {
+	return func(wrp *MultisourceWrapper) {
+		wrp.metrics = metrics
+	}
+}
+
+// WithExperiment splits the traffic between the arms of the experiment.
+// The arm can override the sources, the timeout, the response preprocessor and the bid floor.
+func WithExperiment(experiment *experiments.Experiment) Option {
+	return func(wrp *MultisourceWrapper) {
+		wrp.experiment = experiment
+	}
+}
+
+// WithFloors computes the bid floors of the impressions before the sources are requested.
+// The auction responses are observed by the engine to learn the floor multipliers.
+func WithFloors(engine *floors.Engine) Option {
+	return func(wrp *MultisourceWrapper) {
+		wrp.floors = engine
+	}
+}
+
+// WithAdaptiveTimeout enables the per-source timeouts computed from the observed
+// latency percentile (p95 by default) plus the margin. The timeouts never exceed
+// the request timeout of the wrapper.
+func WithAdaptiveTimeout(margin time.Duration, opts ...AdaptiveTimeoutOption) Option {
+	return func(wrp *MultisourceWrapper) {
+		wrp.adaptiveTimeout = newAdaptiveTimeout(margin, opts...)
 	}
 }
@@ -0,0 +1,338 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+// Package pacing spreads the daily budget of the entities (accounts, sources, campaigns)
+// over the active hours.
+//
+// The controller compares the spend-to-date with the expected spend curve
+// (the share of the active minutes of the day which are passed) and corrects the
+// participation probability or the bid multiplier by the PID controller:
+//
+//	ctrl := pacing.NewController(pacing.WithSpend(pacing.TrackerSpend(tracker)))
+//	ctrl.SetEntity(pacing.SourceKey(src.ID), pacing.Config{
+//		Pacing:      types.AdPacingEvenly,
+//		DailyBudget: src.DailyBudget,
+//	})
+//	if ctrl.Allow(pacing.SourceKey(src.ID)) {
+//		// request the source
+//	}
+package pacing
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/geniusrabbit/adcorelib/admodels/types"
+	"github.com/geniusrabbit/adcorelib/adsource/budget"
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+const (
+	defaultUpdateInterval = 10 * time.Second
+	defaultMinProbability = 0.01
+	defaultMinMultiplier  = 0.5
+	defaultMaxMultiplier  = 1.5
+	dayFormat             = "2006-01-02"
+)
+
+// EntityKind of the paced entity
+type EntityKind uint8
+
+// Entity kinds
+const (
+	EntityAccount EntityKind = iota + 1
+	EntitySource
+	EntityCampaign
+)
+
+// Key of the paced entity
+type Key struct {
+	Kind EntityKind
+	ID   uint64
+}
+
+// AccountKey of the account
+func AccountKey(id uint64) Key { return Key{Kind: EntityAccount, ID: id} }
+
+// SourceKey of the source
+func SourceKey(id uint64) Key { return Key{Kind: EntitySource, ID: id} }
+
+// CampaignKey of the campaign
+func CampaignKey(id uint64) Key { return Key{Kind: EntityCampaign, ID: id} }
+
+// Mode of the controller output
+type Mode uint8
+
+// Output modes
+const (
+	// ProbabilityMode throttles the participation in the auctions
+	ProbabilityMode Mode = iota
+
+	// BidMultiplierMode corrects the bids and keeps the full participation
+	BidMultiplierMode
+)
+
+// Config of the paced entity
+type Config struct {
+	Pacing      types.AdPacing
+	Hours       types.Hours // Active hours (all by default)
+	DailyBudget billing.Money
+	Location    *time.Location // Timezone of the day and the hours (UTC by default)
+}
+
+// Gains of the PID controller. The error is the difference between
+// the expected and the actual shares of the daily budget.
+type Gains struct {
+	Kp float64
+	Ki float64 // Per minute
+	Kd float64 // Per minute
+}
+
+// DefaultGains of the controller
+var DefaultGains = Gains{Kp: 2, Ki: 0.05, Kd: 1}
+
+// Decision of the controller for the entity
+type Decision struct {
+	Probability   float64 // Participation probability from 0 to 1
+	BidMultiplier float64 // Multiplier of the bid
+	Expected      float64 // Expected share of the daily budget
+	Actual        float64 // Spent share of the daily budget
+}
+
+// SpendFunc returns the daily spend of the entity
+type SpendFunc func(key Key) billing.Money
+
+// Controller of the budget delivery.
+// The entities are corrected independently, each of them has its own lock.
+type Controller struct {
+	mx       sync.RWMutex
+	entities map[Key]*entity
+
+	mode           Mode
+	gains          Gains
+	spend          SpendFunc
+	updateInterval time.Duration
+	minProbability float64
+	minMultiplier  float64
+	maxMultiplier  float64
+	now            func() time.Time
+	rand           func() float64
+}
+
+type entity struct {
+	mx     sync.Mutex
+	config Config
+
+	day        string
+	updatedAt  time.Time
+	integral   float64
+	lastError  float64
+	hasError   bool
+	output     float64
+	lastResult Decision
+}
+
+// Option of the controller
+type Option func(c *Controller)
+
+// WithMode of the controller output (ProbabilityMode by default)
+func WithMode(mode Mode) Option {
+	return func(c *Controller) {
+		c.mode = mode
+	}
+}
+
+// WithGains of the PID controller (DefaultGains by default)
+func WithGains(gains Gains) Option {
+	return func(c *Controller) {
+		c.gains = gains
+	}
+}
+
+// WithSpend sets the source of the daily spends of the entities for Allow and Decide
+func WithSpend(spend SpendFunc) Option {
+	return func(c *Controller) {
+		c.spend = spend
+	}
+}
+
+// WithUpdateInterval sets the minimal interval between the corrections (10 seconds by default)
+func WithUpdateInterval(interval time.Duration) Option {
+	return func(c *Controller) {
+		c.updateInterval = max(interval, 0)
+	}
+}
+
+// WithLimits sets the minimal participation probability and the limits of the bid multiplier
+func WithLimits(minProbability, minMultiplier, maxMultiplier float64) Option {
+	return func(c *Controller) {
+		c.minProbability = min(max(minProbability, 0), 1)
+		c.minMultiplier = max(minMultiplier, 0)
+		c.maxMultiplier = max(maxMultiplier, c.minMultiplier)
+	}
+}
+
+// NewController of the budget delivery
+func NewController(opts ...Option) *Controller {
+	c := &Controller{
+		entities:       map[Key]*entity{},
+		gains:          DefaultGains,
+		updateInterval: defaultUpdateInterval,
+		minProbability: defaultMinProbability,
+		minMultiplier:  defaultMinMultiplier,
+		maxMultiplier:  defaultMaxMultiplier,
+		now:            time.Now,
+		rand:           rand.Float64,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// SetEntity config of the paced entity. The state of the controller is kept on the config update.
+func (c *Controller) SetEntity(key Key, config Config) {
+	if config.Location == nil {
+		config.Location = time.UTC
+	}
+	c.mx.Lock()
+	ent := c.entities[key]
+	if ent == nil {
+		c.entities[key] = &entity{config: config, output: 1}
+	}
+	c.mx.Unlock()
+	if ent != nil {
+		ent.mx.Lock()
+		ent.config = config
+		ent.mx.Unlock()
+	}
+}
+
+// RemoveEntity from the controller
+func (c *Controller) RemoveEntity(key Key) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	delete(c.entities, key)
+}
+
+// Allow the participation of the entity by the probability of the decision.
+// The entities without config are always allowed.
+func (c *Controller) Allow(key Key) bool {
+	decision, ok := c.Decide(key)
+	return !ok || decision.Probability >= 1 || c.rand() < decision.Probability
+}
+
+// Decide by the daily spend of the SpendFunc.
+// Returns false if the entity is not paced.
+func (c *Controller) Decide(key Key) (Decision, bool) {
+	if c.spend == nil {
+		return Decision{Probability: 1, BidMultiplier: 1}, false
+	}
+	return c.Update(key, c.spend(key))
+}
+
+// Update the controller by the daily spend of the entity and returns the decision.
+// Returns false if the entity is not paced.
+func (c *Controller) Update(key Key, dailySpend billing.Money) (Decision, bool) {
+	c.mx.RLock()
+	ent := c.entities[key]
+	c.mx.RUnlock()
+	if ent == nil {
+		return Decision{Probability: 1, BidMultiplier: 1}, false
+	}
+	ent.mx.Lock()
+	defer ent.mx.Unlock()
+	return c.update(ent, dailySpend, c.now().In(ent.config.Location)), true
+}
+
+// Mode of the controller output
+func (c *Controller) Mode() Mode {
+	return c.mode
+}
+
+///////////////////////////////////////////////////////////////////////////////
+/// Internal methods
+///////////////////////////////////////////////////////////////////////////////
+
+func (c *Controller) update(ent *entity, dailySpend billing.Money, now time.Time) Decision {
+	dailyBudget := ent.config.DailyBudget
+	if dailyBudget <= 0 {
+		return Decision{Probability: 1, BidMultiplier: 1}
+	}
+	actual := dailySpend.Float64() / dailyBudget.Float64()
+	if actual >= 1 {
+		return Decision{Probability: 0, BidMultiplier: 1, Expected: 1, Actual: actual}
+	}
+	if !ent.config.Pacing.IsEvenly() {
+		return Decision{Probability: 1, BidMultiplier: 1, Expected: 1, Actual: actual}
+	}
+
+	// The new day resets the state of the controller
+	if day := now.Format(dayFormat); ent.day != day {
+		ent.day, ent.updatedAt, ent.integral, ent.hasError, ent.output = day, time.Time{}, 0, false, 1
+	}
+	if !ent.updatedAt.IsZero() && now.Sub(ent.updatedAt) < c.updateInterval {
+		return ent.lastResult
+	}
+
+	expected := ExpectedShare(ent.config.Hours, now)
+	err := expected - actual // Positive value means the underspend
+	if ent.updatedAt.IsZero() {
+		ent.updatedAt = now
+	}
+	minutes := now.Sub(ent.updatedAt).Minutes()
+
+	var derivative float64
+	if ent.hasError && minutes > 0 {
+		derivative = (err - ent.lastError) / minutes
+	}
+	lo, hi := c.limits()
+
+	// Anti-windup: the integral is not accumulated in the direction of the saturated output
+	if integral := ent.integral + err*minutes; !(ent.output >= hi && err > 0) && !(ent.output <= lo && err < 0) {
+		ent.integral = integral
+	}
+	ent.output = min(max(1+c.gains.Kp*err+c.gains.Ki*ent.integral+c.gains.Kd*derivative, lo), hi)
+	ent.lastError, ent.hasError, ent.updatedAt = err, true, now
+
+	ent.lastResult = Decision{Probability: 1, BidMultiplier: 1, Expected: expected, Actual: actual}
+	if c.mode == BidMultiplierMode {
+		ent.lastResult.BidMultiplier = ent.output
+	} else {
+		ent.lastResult.Probability = ent.output
+	}
+	return ent.lastResult
+}
+
+func (c *Controller) limits() (lo, hi float64) {
+	if c.mode == BidMultiplierMode {
+		return c.minMultiplier, c.maxMultiplier
+	}
+	return c.minProbability, 1
+}
+
+///////////////////////////////////////////////////////////////////////////////
+/// Helpers
+///////////////////////////////////////////////////////////////////////////////
+
+// ExpectedShare of the daily budget which must be spent at the time by the active hours
+func ExpectedShare(hours types.Hours, t time.Time) float64 {
+	total, elapsed := types.HoursActiveMinutes(hours, t)
+	if total == 0 {
+		return 0
+	}
+	return float64(elapsed) / float64(total)
+}
+
+// TrackerSpend returns the daily spends of the sources of the budget tracker
+func TrackerSpend(tracker *budget.Tracker) SpendFunc {
+	return func(key Key) billing.Money {
+		if key.Kind != EntitySource {
+			return 0
+		}
+		return tracker.State(key.ID).Spend.Daily
+	}
+}
@@ -0,0 +1,131 @@
+package pacing
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/geniusrabbit/adcorelib/admodels/types"
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+func newTestController(now *time.Time, opts ...Option) *Controller {
+	c := NewController(append([]Option{WithUpdateInterval(time.Minute)}, opts...)...)
+	c.now = func() time.Time { return *now }
+	return c
+}
+
+func TestControllerEvenlyDelivery(t *testing.T) {
+	var (
+		now  = time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+		ctrl = newTestController(&now)
+		key  = SourceKey(1)
+	)
+	ctrl.SetEntity(key, Config{Pacing: types.AdPacingEvenly, DailyBudget: billing.MoneyInt(1440)})
+
+	// The traffic is enough to spend the budget twice a day
+	var spend billing.Money
+	for range 12 * 60 {
+		decision, _ := ctrl.Update(key, spend)
+		spend += billing.MoneyFloat(2 * decision.Probability)
+		now = now.Add(time.Minute)
+	}
+	decision, _ := ctrl.Update(key, spend)
+	if math.Abs(decision.Actual-decision.Expected) > 0.05 {
+		t.Fatalf("spend is out of the curve: %+v", decision)
+	}
+	if decision.Probability > 0.7 {
+		t.Fatalf("traffic is not throttled: %+v", decision)
+	}
+
+	// The traffic swing: the spend falls behind the curve
+	throttled := decision.Probability
+	now = now.Add(time.Hour)
+	for range 10 {
+		decision, _ = ctrl.Update(key, spend)
+		now = now.Add(time.Minute)
+	}
+	if decision.Probability < throttled+0.2 {
+		t.Fatalf("underspend is still throttled: %v => %+v", throttled, decision)
+	}
+}
+
+func TestControllerModes(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		mode   Mode
+		config Config
+		spend  billing.Money
+		check  func(d Decision) bool
+	}{
+		{
+			name:   "asap",
+			config: Config{Pacing: types.AdPacingASAP, DailyBudget: billing.MoneyInt(100)},
+			spend:  billing.MoneyInt(90),
+			check:  func(d Decision) bool { return d.Probability == 1 },
+		},
+		{
+			name:   "exhausted",
+			config: Config{Pacing: types.AdPacingEvenly, DailyBudget: billing.MoneyInt(100)},
+			spend:  billing.MoneyInt(100),
+			check:  func(d Decision) bool { return d.Probability == 0 },
+		},
+		{
+			name:   "overspend",
+			config: Config{Pacing: types.AdPacingEvenly, DailyBudget: billing.MoneyInt(100)},
+			spend:  billing.MoneyInt(80),
+			check:  func(d Decision) bool { return d.Probability < 1 && d.BidMultiplier == 1 },
+		},
+		{
+			name:   "bid_multiplier",
+			mode:   BidMultiplierMode,
+			config: Config{Pacing: types.AdPacingEvenly, DailyBudget: billing.MoneyInt(100)},
+			spend:  billing.MoneyInt(20),
+			check:  func(d Decision) bool { return d.Probability == 1 && d.BidMultiplier > 1 },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := newTestController(&now, WithMode(tt.mode), WithSpend(func(Key) billing.Money { return tt.spend }))
+			ctrl.SetEntity(CampaignKey(1), tt.config)
+			if decision, ok := ctrl.Decide(CampaignKey(1)); !ok || !tt.check(decision) {
+				t.Fatalf("decision: %+v", decision)
+			}
+			if !ctrl.Allow(CampaignKey(2)) {
+				t.Fatal("the entity without config must be allowed")
+			}
+		})
+	}
+}
+
+func TestControllerConcurrentEntities(t *testing.T) {
+	var (
+		now  = time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+		ctrl = newTestController(&now, WithUpdateInterval(0))
+		wg   sync.WaitGroup
+	)
+	for id := range uint64(8) {
+		ctrl.SetEntity(SourceKey(id), Config{Pacing: types.AdPacingEvenly, DailyBudget: billing.MoneyInt(100)})
+	}
+	for id := range uint64(8) {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				ctrl.Update(SourceKey(id), billing.MoneyInt(int64(id)))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				ctrl.SetEntity(SourceKey(id), Config{Pacing: types.AdPacingEvenly, DailyBudget: billing.MoneyInt(100)})
+			}
+		}()
+	}
+	wg.Wait()
+	if decision, ok := ctrl.Update(SourceKey(7), billing.MoneyInt(90)); !ok || decision.Probability >= 1 {
+		t.Fatalf("overspend decision: %+v", decision)
+	}
+}
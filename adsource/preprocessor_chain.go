@@ -0,0 +1,95 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package adsource
+
+import (
+	"github.com/geniusrabbit/adcorelib/adsource/auctiontrace"
+	"github.com/geniusrabbit/adcorelib/adtype"
+)
+
+// ExtRemovedAds is the request Ext key of the ads removed by the response preprocessors
+const ExtRemovedAds = "removed_ads"
+
+// RemovedAd describes the ad removed from the response by the preprocessor
+type RemovedAd struct {
+	Preprocessor string `json:"preprocessor"`
+	ItemID       string `json:"item_id"`
+	ImpID        string `json:"imp_id"`
+	SourceID     uint64 `json:"source_id,omitempty"`
+	Reason       string `json:"reason"`
+}
+
+// RecordRemovedAd of the request to explain why the ad disappeared from the response
+func RecordRemovedAd(request adtype.BidRequester, preprocessor, reason string, ad adtype.ResponseItemCommon) {
+	if request == nil || ad == nil {
+		return
+	}
+	removed := RemovedAd{
+		Preprocessor: preprocessor,
+		ItemID:       ad.ID(),
+		ImpID:        ad.ImpressionID(),
+		Reason:       reason,
+	}
+	if it, _ := ad.(adtype.ResponseItem); it != nil && it.Source() != nil {
+		removed.SourceID = it.Source().ID()
+	}
+	request.Set(ExtRemovedAds, append(RemovedAdsFromRequest(request), removed))
+	auctiontrace.FromRequest(request).AdRemoved(auctiontrace.Removal{
+		Stage:    removed.Preprocessor,
+		ItemID:   removed.ItemID,
+		ImpID:    removed.ImpID,
+		SourceID: removed.SourceID,
+		Reason:   removed.Reason,
+	})
+}
+
+// RemovedAdsFromRequest returns the ads removed by the response preprocessors
+func RemovedAdsFromRequest(request adtype.BidRequester) []RemovedAd {
+	if request == nil {
+		return nil
+	}
+	removed, _ := request.Get(ExtRemovedAds).([]RemovedAd)
+	return removed
+}
+
+// ResponsePreprocessorChain applies the preprocessors one by one.
+// The chain stops on the first error.
+type ResponsePreprocessorChain []ResponsePreprocessor
+
+// NewResponsePreprocessorChain from the list of preprocessors.
+// Nil preprocessors are skipped and the nested chains are flattened.
+// Returns nil if there are no preprocessors and the preprocessor itself if it's the only one.
+func NewResponsePreprocessorChain(preprocessors ...ResponsePreprocessor) ResponsePreprocessor {
+	var chain ResponsePreprocessorChain
+	for _, preprocessor := range preprocessors {
+		switch p := preprocessor.(type) {
+		case nil:
+		case ResponsePreprocessorChain:
+			chain = append(chain, p...)
+		default:
+			chain = append(chain, p)
+		}
+	}
+	switch len(chain) {
+	case 0:
+		return nil
+	case 1:
+		return chain[0]
+	}
+	return chain
+}
+
+// PreprocessResponse implements ResponsePreprocessor
+func (chain ResponsePreprocessorChain) PreprocessResponse(response adtype.Response) (_ adtype.Response, err error) {
+	for _, preprocessor := range chain {
+		if response, err = preprocessor.PreprocessResponse(response); err != nil {
+			return response, err
+		}
+	}
+	return response, nil
+}
+
+var _ ResponsePreprocessor = ResponsePreprocessorChain(nil)
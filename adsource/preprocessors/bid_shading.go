@@ -0,0 +1,185 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package preprocessors
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/geniusrabbit/adcorelib/adsource"
+	"github.com/geniusrabbit/adcorelib/adsource/auctiontrace"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/adtype/prices"
+)
+
+// BidShading lowers the bids of the first-price auction toward the estimated
+// clearing price.
+//
+// Wire with adsource.WithResponsePreprocessor(preprocessors.NewBidShading(...)).
+//
+// Algorithm:
+//  1. Skip responses of the second-price auction.
+//  2. For each ad the WinRateModel estimates the shaded CPM bid by the win-rate
+//     curve of the source, zone (target) and format of the ad.
+//  3. The shaded price is never lower than Impression.BidFloorCPM and never
+//     higher than the current price.
+//  4. Apply via SetBidPrice(..., withCommission=false).
+//
+// The model learns from the outcomes passed to Observe or ObserveOutcome. In the
+// offline mode the live outcomes are only recorded and the model is trained by
+// the replay of the recorded outcomes.
+type BidShading struct {
+	model        WinRateModel
+	snapshotFile string
+	offline      bool
+	replay       io.Reader
+
+	recordMx sync.Mutex
+	recorder *json.Encoder
+}
+
+const bidShadingStage = "bid_shading"
+
+// BidShadingOption type
+type BidShadingOption func(s *BidShading)
+
+// WithShadingModel sets the custom win-rate model
+func WithShadingModel(model WinRateModel) BidShadingOption {
+	return func(s *BidShading) {
+		s.model = model
+	}
+}
+
+// WithShadingSnapshotFile sets the snapshot file of the model.
+// The snapshot is loaded on creation if the file exists.
+func WithShadingSnapshotFile(filename string) BidShadingOption {
+	return func(s *BidShading) {
+		s.snapshotFile = filename
+	}
+}
+
+// WithShadingRecorder sets the writer of the observed outcomes (JSON lines)
+func WithShadingRecorder(w io.Writer) BidShadingOption {
+	return func(s *BidShading) {
+		s.recorder = json.NewEncoder(w)
+	}
+}
+
+// WithShadingOffline turns on the offline mode and replays the recorded outcomes
+func WithShadingOffline(outcomes io.Reader) BidShadingOption {
+	return func(s *BidShading) {
+		s.offline = true
+		s.replay = outcomes
+	}
+}
+
+// NewBidShading preprocessor with the histogram win-rate model by default
+func NewBidShading(opts ...BidShadingOption) (*BidShading, error) {
+	shading := &BidShading{model: NewHistogramWinRateModel()}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(shading)
+		}
+	}
+	if shading.snapshotFile != "" {
+		err := LoadWinRateModel(shading.model, shading.snapshotFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	// Recorded outcomes are replayed on top of the loaded snapshot
+	if shading.replay != nil {
+		if _, err := ReplayOutcomes(shading.model, shading.replay); err != nil {
+			return nil, err
+		}
+		shading.replay = nil
+	}
+	return shading, nil
+}
+
+var _ adsource.ResponsePreprocessor = (*BidShading)(nil)
+
+// Model returns the win-rate model of the preprocessor
+func (s *BidShading) Model() WinRateModel { return s.model }
+
+// PreprocessResponse implements adsource.ResponsePreprocessor.
+func (s *BidShading) PreprocessResponse(response adtype.Response) (adtype.Response, error) {
+	if response == nil || response.Count() < 1 || !response.AuctionType().IsFirtsPrice() {
+		return response, nil
+	}
+	trace := auctiontrace.FromRequest(response.Request())
+	for ad := range response.IterAds() {
+		if ad == nil {
+			continue
+		}
+		current := ad.Price(adtype.ActionImpression)
+		if current <= 0 {
+			continue
+		}
+		shaded := prices.PriceFromCPM(s.model.ShadedBid(shadingKey(ad), prices.CPMFromPrice(current)))
+		if imp := ad.Impression(); imp != nil {
+			shaded = max(shaded, prices.PriceFromCPM(imp.BidFloorCPM))
+		}
+		if shaded <= 0 || shaded >= current {
+			continue
+		}
+		_ = ad.SetBidPrice(adtype.ActionImpression, shaded, false)
+		trace.PriceChanged(bidShadingStage, ad, current, ad.Price(adtype.ActionImpression))
+	}
+	return response, nil
+}
+
+// Observe the win or loss of the ad in the external auction
+func (s *BidShading) Observe(ad adtype.ResponseItem, win bool) {
+	if ad == nil {
+		return
+	}
+	s.ObserveOutcome(Outcome{
+		ShadingKey: shadingKey(ad),
+		BidCPM:     prices.CPMFromPrice(ad.Price(adtype.ActionImpression)),
+		Win:        win,
+	})
+}
+
+// ObserveOutcome records the outcome and updates the model if it's not in the offline mode
+func (s *BidShading) ObserveOutcome(outcome Outcome) {
+	if outcome.BidCPM <= 0 {
+		return
+	}
+	if s.recorder != nil {
+		s.recordMx.Lock()
+		_ = s.recorder.Encode(&outcome)
+		s.recordMx.Unlock()
+	}
+	if !s.offline {
+		s.model.Observe(outcome.ShadingKey, outcome.BidCPM, outcome.Win)
+	}
+}
+
+// SaveSnapshot of the model into the snapshot file
+func (s *BidShading) SaveSnapshot() error {
+	if s.snapshotFile == "" {
+		return nil
+	}
+	return SaveWinRateModel(s.model, s.snapshotFile)
+}
+
+func shadingKey(ad adtype.ResponseItem) ShadingKey {
+	var key ShadingKey
+	if imp := ad.Impression(); imp != nil {
+		key.ZoneID = uint64(imp.TargetID())
+	}
+	if src := ad.Source(); src != nil {
+		key.SourceID = src.ID()
+	}
+	if format := ad.Format(); format != nil {
+		key.Format = format.Codename
+	}
+	return key
+}
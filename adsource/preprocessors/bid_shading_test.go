@@ -0,0 +1,153 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package preprocessors
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/geniusrabbit/adcorelib/admodels/types"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/adtype/prices"
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+type secondPriceResponse struct{ stubResponse }
+
+func (r *secondPriceResponse) AuctionType() types.AuctionType { return types.SecondPriceAuctionType }
+
+func newShadingAd(cpm, floorCPM billing.Money) *stubAd {
+	return &stubAd{
+		ResponseItemEmpty: adtype.ResponseItemEmpty{Imp: &adtype.Impression{}},
+		price:             prices.PriceFromCPM(cpm),
+		bidFloorCPM:       floorCPM,
+	}
+}
+
+// trainModel with the outcomes where all bids above 1.0 CPM win
+func trainModel(model WinRateModel, key ShadingKey) {
+	for i := range 200 {
+		bid := billing.MoneyFloat(0.1) * billing.Money(i%40+1)
+		model.Observe(key, bid, bid > billing.MoneyFloat(1.))
+	}
+}
+
+func TestBidShading_NoObservations(t *testing.T) {
+	shading, err := NewBidShading()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ad := newShadingAd(billing.MoneyFloat(3.), 0)
+	if _, err = shading.PreprocessResponse(newResp(ad)); err != nil {
+		t.Fatal(err)
+	}
+	if ad.setCalled {
+		t.Fatalf("expected no shading without observations, price=%d", ad.setPrice)
+	}
+}
+
+func TestBidShading_Shade(t *testing.T) {
+	shading, err := NewBidShading()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ad := newShadingAd(billing.MoneyFloat(3.), 0)
+	trainModel(shading.Model(), shadingKey(ad))
+
+	if _, err = shading.PreprocessResponse(newResp(ad)); err != nil {
+		t.Fatal(err)
+	}
+	if !ad.setCalled || ad.withComm {
+		t.Fatalf("setCalled=%v withComm=%v", ad.setCalled, ad.withComm)
+	}
+	cpm := prices.CPMFromPrice(ad.setPrice)
+	if cpm <= billing.MoneyFloat(1.) || cpm >= billing.MoneyFloat(1.5) {
+		t.Fatalf("shaded CPM=%s expected near the 1.0 clearing price", cpm)
+	}
+}
+
+func TestBidShading_BidFloor(t *testing.T) {
+	shading, err := NewBidShading()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ad := newShadingAd(billing.MoneyFloat(3.), billing.MoneyFloat(2.))
+	trainModel(shading.Model(), shadingKey(ad))
+
+	if _, err = shading.PreprocessResponse(newResp(ad)); err != nil {
+		t.Fatal(err)
+	}
+	if want := prices.PriceFromCPM(billing.MoneyFloat(2.)); !ad.setCalled || ad.setPrice != want {
+		t.Fatalf("setCalled=%v price=%d want=%d", ad.setCalled, ad.setPrice, want)
+	}
+}
+
+func TestBidShading_SecondPriceSkip(t *testing.T) {
+	shading, err := NewBidShading()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ad := newShadingAd(billing.MoneyFloat(3.), 0)
+	trainModel(shading.Model(), shadingKey(ad))
+
+	resp := &secondPriceResponse{stubResponse{items: []adtype.ResponseItemCommon{ad}}}
+	if _, err = shading.PreprocessResponse(resp); err != nil {
+		t.Fatal(err)
+	}
+	if ad.setCalled {
+		t.Fatal("expected the second-price response to be untouched")
+	}
+}
+
+func TestBidShading_Snapshot(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "shading.json")
+	shading, err := NewBidShading(WithShadingSnapshotFile(filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := ShadingKey{SourceID: 1, ZoneID: 2, Format: "banner"}
+	trainModel(shading.Model(), key)
+	if err = shading.SaveSnapshot(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := NewBidShading(WithShadingSnapshotFile(filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bid := billing.MoneyFloat(3.)
+	if got, want := loaded.Model().ShadedBid(key, bid), shading.Model().ShadedBid(key, bid); got != want || got >= bid {
+		t.Fatalf("loaded shaded bid=%s want=%s", got, want)
+	}
+}
+
+func TestBidShading_Offline(t *testing.T) {
+	var (
+		records bytes.Buffer
+		key     = ShadingKey{SourceID: 1, Format: "banner"}
+	)
+	recorder, err := NewBidShading(WithShadingRecorder(&records), WithShadingOffline(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 200 {
+		bid := billing.MoneyFloat(0.1) * billing.Money(i%40+1)
+		recorder.ObserveOutcome(Outcome{ShadingKey: key, BidCPM: bid, Win: bid > billing.MoneyFloat(1.)})
+	}
+	bid := billing.MoneyFloat(3.)
+	if got := recorder.Model().ShadedBid(key, bid); got != bid {
+		t.Fatalf("offline model must not learn from live outcomes, shaded=%s", got)
+	}
+
+	replayed, err := NewBidShading(WithShadingOffline(&records))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := replayed.Model().ShadedBid(key, bid); got >= bid {
+		t.Fatalf("replayed model must shade the bid, shaded=%s", got)
+	}
+}
@@ -0,0 +1,85 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package preprocessors
+
+import (
+	"github.com/geniusrabbit/adcorelib/adsource"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/adtype/prices"
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+const (
+	floorPreprocessorName = "floor"
+	floorRemoveReason     = "below the bid floor"
+)
+
+// ZoneFloorTarget is the optional interface of the impression target with the floor CPM of the zone
+type ZoneFloorTarget interface {
+	MinECPM() billing.Money
+}
+
+// ZoneFloorFunc returns the floor CPM of the impression zone
+type ZoneFloorFunc func(imp *adtype.Impression) billing.Money
+
+// FloorEnforcement removes the ads below the floor of the impression.
+// The floor is the maximum of Impression.BidFloorCPM and the zone floor.
+//
+// Wire it after the price corrections (second price, bid shading):
+//
+//	adsource.WithResponsePreprocessor(preprocessors.SecondPrice{}, preprocessors.FloorEnforcement{})
+//
+// The price of the ad is the CPM of the current impression price or the ECPM
+// if the ad is not paid by impressions.
+type FloorEnforcement struct {
+	// ZoneFloor of the impression (ZoneFloorTarget of the impression target by default)
+	ZoneFloor ZoneFloorFunc
+}
+
+var _ adsource.ResponsePreprocessor = FloorEnforcement{}
+
+// PreprocessResponse implements adsource.ResponsePreprocessor.
+func (f FloorEnforcement) PreprocessResponse(response adtype.Response) (adtype.Response, error) {
+	if response == nil || response.Count() < 1 {
+		return response, nil
+	}
+	var reasons map[adtype.ResponseItem]string
+	for ad := range response.IterAds() {
+		if ad == nil {
+			continue
+		}
+		floor := f.floor(ad.Impression())
+		if floor <= 0 || adCPM(ad) >= floor {
+			continue
+		}
+		if reasons == nil {
+			reasons = map[adtype.ResponseItem]string{}
+		}
+		reasons[ad] = floorRemoveReason
+	}
+	return removeAds(response, floorPreprocessorName, reasons), nil
+}
+
+func (f FloorEnforcement) floor(imp *adtype.Impression) billing.Money {
+	if imp == nil {
+		return 0
+	}
+	floor := imp.BidFloorCPM
+	if f.ZoneFloor != nil {
+		floor = max(floor, f.ZoneFloor(imp))
+	} else if zone, _ := imp.Target.(ZoneFloorTarget); zone != nil {
+		floor = max(floor, zone.MinECPM())
+	}
+	return floor
+}
+
+// adCPM returns the current CPM of the ad
+func adCPM(ad adtype.ResponseItem) billing.Money {
+	if price := ad.Price(adtype.ActionImpression); price > 0 {
+		return prices.CPMFromPrice(price)
+	}
+	return ad.ECPM()
+}
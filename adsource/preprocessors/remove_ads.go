@@ -0,0 +1,46 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package preprocessors
+
+import (
+	"github.com/geniusrabbit/adcorelib/adquery/bidresponse"
+	"github.com/geniusrabbit/adcorelib/adsource"
+	"github.com/geniusrabbit/adcorelib/adtype"
+)
+
+// removeAds from the response and records them (see adsource.RemovedAdsFromRequest).
+// The multiple item (complex banner) is removed completely if any of its ads is removed.
+// Returns the same response if nothing is removed.
+func removeAds(response adtype.Response, preprocessor string, reasons map[adtype.ResponseItem]string) adtype.Response {
+	if len(reasons) == 0 {
+		return response
+	}
+	var (
+		request = response.Request()
+		items   = make([]adtype.ResponseItemCommon, 0, response.Count())
+	)
+	for _, it := range response.Ads() {
+		reason := ""
+		switch itV := it.(type) {
+		case adtype.ResponseItem:
+			reason = reasons[itV]
+		case adtype.ResponseMultipleItem:
+			for _, ad := range itV.Ads() {
+				if reason = reasons[ad]; reason != "" {
+					break
+				}
+			}
+		}
+		if reason != "" {
+			adsource.RecordRemovedAd(request, preprocessor, reason, it)
+		} else {
+			items = append(items, it)
+		}
+	}
+	newResponse := bidresponse.NewResponse(request, response.Source(), items, response.Error())
+	newResponse.Context(response.Context())
+	return newResponse
+}
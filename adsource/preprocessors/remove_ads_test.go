@@ -0,0 +1,162 @@
+package preprocessors
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/geniusrabbit/adcorelib/admodels/types"
+	"github.com/geniusrabbit/adcorelib/adquery/bidrequest"
+	"github.com/geniusrabbit/adcorelib/adquery/bidresponse"
+	"github.com/geniusrabbit/adcorelib/adsource"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/adtype/prices"
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+type idSource struct {
+	adtype.Source
+	id uint64
+}
+
+func (s *idSource) ID() uint64 { return s.id }
+
+type zoneTarget struct {
+	adtype.Target
+	minECPM billing.Money
+}
+
+func (z *zoneTarget) MinECPM() billing.Money { return z.minECPM }
+
+type advertiserAd struct {
+	*bidresponse.ResponseItemBlank
+	domains    []string
+	categories []string
+	campaign   string
+}
+
+func (a *advertiserAd) AdvertiserDomains() []string { return a.domains }
+func (a *advertiserAd) AdCategories() []string      { return a.categories }
+func (a *advertiserAd) ExtCampaignID() string       { return a.campaign }
+
+func newBlankAd(id string, imp *adtype.Impression, src adtype.Source, cpm float64) *bidresponse.ResponseItemBlank {
+	return &bidresponse.ResponseItemBlank{
+		ItemID:          id,
+		Imp:             imp,
+		Src:             src,
+		FormatVal:       &types.Format{},
+		PricingModelVal: types.PricingModelCPM,
+		PriceScope:      prices.PriceScope{CPMScope: prices.CPMScope{BidCPM: billing.MoneyFloat(cpm)}},
+	}
+}
+
+func newRemoveRequest() *bidrequest.BidRequest {
+	return &bidrequest.BidRequest{Imps: []*adtype.Impression{
+		{ID: "imp1", Count: 1, BidFloorCPM: billing.MoneyFloat(1.)},
+		{ID: "imp2", Count: 1, Target: &zoneTarget{minECPM: billing.MoneyFloat(2.)}},
+		{ID: "imp3", Count: 1},
+	}}
+}
+
+func responseIDs(response adtype.Response) (ids []string) {
+	for ad := range response.IterAds() {
+		ids = append(ids, ad.ID())
+	}
+	return ids
+}
+
+func removedIDs(request adtype.BidRequester, preprocessor string) (ids []string) {
+	for _, removed := range adsource.RemovedAdsFromRequest(request) {
+		if removed.Preprocessor == preprocessor {
+			ids = append(ids, removed.ItemID)
+		}
+	}
+	return ids
+}
+
+func TestFloorEnforcement(t *testing.T) {
+	var (
+		request = newRemoveRequest()
+		imps    = request.Impressions()
+		src     = &idSource{id: 1}
+	)
+	response := bidresponse.NewResponse(request, nil, []adtype.ResponseItemCommon{
+		newBlankAd("a1", imps[0], src, 0.5),
+		newBlankAd("a2", imps[1], src, 1.5),
+		newBlankAd("a3", imps[2], src, 0.1),
+		newBlankAd("a4", imps[1], src, 2.5),
+	}, nil)
+	response2, err := FloorEnforcement{}.PreprocessResponse(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := responseIDs(response2); !slices.Equal(ids, []string{"a3", "a4"}) {
+		t.Fatalf("ads=%v", ids)
+	}
+	if ids := removedIDs(request, floorPreprocessorName); !slices.Equal(ids, []string{"a1", "a2"}) {
+		t.Fatalf("removed=%v", ids)
+	}
+
+	// Nothing to remove
+	if response3, _ := (FloorEnforcement{}).PreprocessResponse(response2); response3 != response2 {
+		t.Fatal("expected the same response")
+	}
+}
+
+func TestCompetitiveSeparation(t *testing.T) {
+	var (
+		request = newRemoveRequest()
+		imps    = request.Impressions()
+		src     = &idSource{id: 1}
+	)
+	response := bidresponse.NewResponse(request, nil, []adtype.ResponseItemCommon{
+		&advertiserAd{ResponseItemBlank: newBlankAd("a1", imps[0], src, 1), domains: []string{"shop.com"}},
+		&advertiserAd{ResponseItemBlank: newBlankAd("a2", imps[1], src, 3), domains: []string{"Shop.com"}},
+		&advertiserAd{ResponseItemBlank: newBlankAd("a3", imps[2], src, 2), categories: []string{"IAB2"}, campaign: "c1"},
+		&advertiserAd{ResponseItemBlank: newBlankAd("a4", imps[2], src, 1), campaign: "c1"},
+		&advertiserAd{ResponseItemBlank: newBlankAd("a5", imps[2], &idSource{id: 2}, 1), campaign: "c1"},
+	}, nil)
+
+	response2, err := CompetitiveSeparation{}.PreprocessResponse(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := responseIDs(response2); !slices.Equal(ids, []string{"a2", "a3", "a5"}) {
+		t.Fatalf("ads=%v", ids)
+	}
+	if ids := removedIDs(request, separationPreprocessorName); !slices.Equal(ids, []string{"a1", "a4"}) {
+		t.Fatalf("removed=%v", ids)
+	}
+
+	// Separation by the category only
+	response3, _ := CompetitiveSeparation{By: SeparateByCategory}.PreprocessResponse(response)
+	if ids := responseIDs(response3); len(ids) != 5 {
+		t.Fatalf("ads=%v", ids)
+	}
+}
+
+func TestSourceCap(t *testing.T) {
+	var (
+		request = newRemoveRequest()
+		imps    = request.Impressions()
+		src1    = &idSource{id: 1}
+		src2    = &idSource{id: 2}
+	)
+	response := bidresponse.NewResponse(request, nil, []adtype.ResponseItemCommon{
+		newBlankAd("a1", imps[0], src1, 1),
+		newBlankAd("a2", imps[1], src1, 3),
+		newBlankAd("a3", imps[2], src1, 2),
+		newBlankAd("a4", imps[2], src2, 1),
+	}, nil)
+
+	chain := adsource.NewResponsePreprocessorChain(nil, SourceCap{MaxAds: 2}, SourceCap{})
+	response2, err := chain.PreprocessResponse(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := responseIDs(response2); !slices.Equal(ids, []string{"a2", "a3", "a4"}) {
+		t.Fatalf("ads=%v", ids)
+	}
+	if ids := removedIDs(request, sourceCapPreprocessorName); !slices.Equal(ids, []string{"a1"}) {
+		t.Fatalf("removed=%v", ids)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"slices"
 
 	"github.com/geniusrabbit/adcorelib/adsource"
+	"github.com/geniusrabbit/adcorelib/adsource/auctiontrace"
 	"github.com/geniusrabbit/adcorelib/adtype"
 	"github.com/geniusrabbit/adcorelib/billing"
 )
@@ -30,6 +31,8 @@ import (
 //  3. Apply via SetBidPrice(..., withCommission=true). Skip zero/negative.
 type SecondPrice struct{}
 
+const secondPriceStage = "second_price"
+
 var _ adsource.ResponsePreprocessor = SecondPrice{}
 
 // PreprocessResponse implements adsource.ResponsePreprocessor.
@@ -58,11 +61,13 @@ func (SecondPrice) PreprocessResponse(response adtype.Response) (adtype.Response
 		cleared[i] = resolveClearedPrice(ads, origPrices, cleared, i)
 	}
 
+	trace := auctiontrace.FromRequest(response.Request())
 	for i, ad := range ads {
 		if cleared[i] <= 0 {
 			continue
 		}
 		_ = ad.SetBidPrice(adtype.ActionImpression, cleared[i], true)
+		trace.PriceChanged(secondPriceStage, ad, origPrices[i], ad.Price(adtype.ActionImpression))
 	}
 
 	return response, nil
@@ -0,0 +1,120 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package preprocessors
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/geniusrabbit/adcorelib/adsource"
+	"github.com/geniusrabbit/adcorelib/adtype"
+)
+
+const separationPreprocessorName = "separation"
+
+// SeparationBy flags of the competitive separation
+type SeparationBy uint8
+
+// Separation flags
+const (
+	SeparateByDomain SeparationBy = 1 << iota
+	SeparateByCategory
+	SeparateByCampaign
+
+	SeparateByAll = SeparateByDomain | SeparateByCategory | SeparateByCampaign
+)
+
+// CompetitiveSeparation removes the ads of the competing advertisers from the
+// impressions of one page: no two ads with the same advertiser domain, category
+// or campaign. The ad with the highest internal auction bid stays.
+//
+// Domains and categories are taken from adtype.ResponseItemAdvertiser.
+// Zero value separates by all the properties.
+type CompetitiveSeparation struct {
+	By SeparationBy
+}
+
+var _ adsource.ResponsePreprocessor = CompetitiveSeparation{}
+
+// PreprocessResponse implements adsource.ResponsePreprocessor.
+func (s CompetitiveSeparation) PreprocessResponse(response adtype.Response) (adtype.Response, error) {
+	if response == nil || response.Count() < 2 {
+		return response, nil
+	}
+	ads := collectAds(response)
+	slices.SortStableFunc(ads, func(a, b adtype.ResponseItem) int {
+		return cmp.Compare(b.InternalAuctionCPMBid(), a.InternalAuctionCPMBid())
+	})
+
+	var (
+		by       = s.by()
+		seen     = map[string]struct{}{}
+		reasons  map[adtype.ResponseItem]string
+		adv      adtype.ResponseItemAdvertiser
+		keys     []string
+		conflict string
+	)
+	for _, ad := range ads {
+		keys, conflict = keys[:0], ""
+		adv, _ = ad.(adtype.ResponseItemAdvertiser)
+		if by&SeparateByDomain != 0 && adv != nil {
+			for _, domain := range adv.AdvertiserDomains() {
+				keys = append(keys, "domain:"+strings.ToLower(domain))
+			}
+		}
+		if by&SeparateByCategory != 0 && adv != nil {
+			for _, category := range adv.AdCategories() {
+				keys = append(keys, "category:"+category)
+			}
+		}
+		if by&SeparateByCampaign != 0 {
+			if key := campaignKey(ad, adv); key != "" {
+				keys = append(keys, "campaign:"+key)
+			}
+		}
+		for _, key := range keys {
+			if _, ok := seen[key]; ok {
+				conflict = key
+				break
+			}
+		}
+		if conflict != "" {
+			if reasons == nil {
+				reasons = map[adtype.ResponseItem]string{}
+			}
+			reasons[ad] = "competing " + conflict
+			continue
+		}
+		for _, key := range keys {
+			seen[key] = struct{}{}
+		}
+	}
+	return removeAds(response, separationPreprocessorName, reasons), nil
+}
+
+func (s CompetitiveSeparation) by() SeparationBy {
+	if s.By == 0 {
+		return SeparateByAll
+	}
+	return s.By
+}
+
+// campaignKey of the internal campaign or the campaign of the external platform
+func campaignKey(ad adtype.ResponseItem, adv adtype.ResponseItemAdvertiser) string {
+	if id := ad.CampaignID(); id > 0 {
+		return strconv.FormatUint(id, 10)
+	}
+	if adv == nil || adv.ExtCampaignID() == "" {
+		return ""
+	}
+	var sourceID uint64
+	if src := ad.Source(); src != nil {
+		sourceID = src.ID()
+	}
+	return strconv.FormatUint(sourceID, 10) + "/" + adv.ExtCampaignID()
+}
@@ -0,0 +1,57 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package preprocessors
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/geniusrabbit/adcorelib/adsource"
+	"github.com/geniusrabbit/adcorelib/adtype"
+)
+
+const (
+	sourceCapPreprocessorName = "source_cap"
+	sourceCapRemoveReason     = "ads per source limit"
+)
+
+// SourceCap limits the number of ads of one source in the response.
+// The ads with the highest internal auction bid stay.
+type SourceCap struct {
+	MaxAds int
+}
+
+var _ adsource.ResponsePreprocessor = SourceCap{}
+
+// PreprocessResponse implements adsource.ResponsePreprocessor.
+func (c SourceCap) PreprocessResponse(response adtype.Response) (adtype.Response, error) {
+	if response == nil || c.MaxAds <= 0 || response.Count() < 1 {
+		return response, nil
+	}
+	ads := collectAds(response)
+	slices.SortStableFunc(ads, func(a, b adtype.ResponseItem) int {
+		return cmp.Compare(b.InternalAuctionCPMBid(), a.InternalAuctionCPMBid())
+	})
+
+	var (
+		counts  = map[uint64]int{}
+		reasons map[adtype.ResponseItem]string
+	)
+	for _, ad := range ads {
+		var sourceID uint64
+		if src := ad.Source(); src != nil {
+			sourceID = src.ID()
+		}
+		if counts[sourceID]++; counts[sourceID] <= c.MaxAds {
+			continue
+		}
+		if reasons == nil {
+			reasons = map[adtype.ResponseItem]string{}
+		}
+		reasons[ad] = sourceCapRemoveReason
+	}
+	return removeAds(response, sourceCapPreprocessorName, reasons), nil
+}
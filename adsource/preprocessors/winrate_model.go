@@ -0,0 +1,298 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package preprocessors
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+// Default parameters of the histogram win-rate model
+const (
+	defaultWinRateBucketSize      = billing.Money(50_000_000) // 0.05 CPM
+	defaultWinRateMaxBuckets      = 400
+	defaultWinRateMinObservations = 100
+)
+
+var errWinRateModelSnapshot = errors.New("[preprocessors] model does not support snapshots")
+
+// ShadingKey identifies the win-rate curve of the traffic segment
+type ShadingKey struct {
+	SourceID uint64 `json:"source_id,omitempty"`
+	ZoneID   uint64 `json:"zone_id,omitempty"`
+	Format   string `json:"format,omitempty"`
+}
+
+// Outcome of the single bid in the external first-price auction
+type Outcome struct {
+	ShadingKey
+	BidCPM billing.Money `json:"bid"`
+	Win    bool          `json:"win,omitempty"`
+}
+
+// WinRateModel learns the win-rate curve from the observed outcomes
+// and estimates the shaded bid for the first-price auction.
+type WinRateModel interface {
+	// Observe the outcome of the bid
+	Observe(key ShadingKey, bidCPM billing.Money, win bool)
+
+	// ShadedBid returns the estimated clearing price for the bid (CPM).
+	// The result is never higher than the original bid.
+	ShadedBid(key ShadingKey, bidCPM billing.Money) billing.Money
+}
+
+// WinRateModelSnapshotter describes the model which can be persisted
+type WinRateModelSnapshotter interface {
+	// WriteSnapshot of the model state
+	WriteSnapshot(w io.Writer) error
+
+	// ReadSnapshot and replace the model state
+	ReadSnapshot(r io.Reader) error
+}
+
+// SaveWinRateModel snapshot into the file
+func SaveWinRateModel(model WinRateModel, filename string) error {
+	snapshotter, ok := model.(WinRateModelSnapshotter)
+	if !ok {
+		return errWinRateModelSnapshot
+	}
+	tmpFile, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmpFile.Name()) }()
+	if err = snapshotter.WriteSnapshot(tmpFile); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err = tmpFile.Close(); err != nil {
+		return err
+	}
+	// Replace the snapshot atomically to avoid partially written files
+	return os.Rename(tmpFile.Name(), filename)
+}
+
+// LoadWinRateModel snapshot from the file
+func LoadWinRateModel(model WinRateModel, filename string) error {
+	snapshotter, ok := model.(WinRateModelSnapshotter)
+	if !ok {
+		return errWinRateModelSnapshot
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+	return snapshotter.ReadSnapshot(file)
+}
+
+// ReplayOutcomes reads recorded outcomes (JSON lines) and feeds them to the model.
+// Returns the number of replayed outcomes.
+func ReplayOutcomes(model WinRateModel, r io.Reader) (int, error) {
+	var (
+		count   int
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var outcome Outcome
+		if err := json.Unmarshal(line, &outcome); err != nil {
+			return count, err
+		}
+		model.Observe(outcome.ShadingKey, outcome.BidCPM, outcome.Win)
+		count++
+	}
+	return count, scanner.Err()
+}
+
+// HistogramWinRateModel estimates the win-rate curve by the bid price buckets.
+//
+// The curve W(p) is the smoothed share of wins in the bucket of the price,
+// forced to be non-decreasing by the price. The shaded bid is the price which
+// maximizes the expected surplus (bid - p) * W(p) for the original bid.
+// Until the segment collects enough observations the bid is not shaded.
+type HistogramWinRateModel struct {
+	mx sync.RWMutex
+
+	bucketSize      billing.Money
+	maxBuckets      int
+	minObservations uint64
+
+	curves map[ShadingKey]*winRateCurve
+}
+
+type winRateCurve struct {
+	Bids  []uint64 `json:"bids"`
+	Wins  []uint64 `json:"wins"`
+	Total uint64   `json:"total"`
+}
+
+// HistogramWinRateModelOption type
+type HistogramWinRateModelOption func(m *HistogramWinRateModel)
+
+// WithWinRateBucketSize sets the CPM price step of the histogram
+func WithWinRateBucketSize(size billing.Money) HistogramWinRateModelOption {
+	return func(m *HistogramWinRateModel) {
+		m.bucketSize = size
+	}
+}
+
+// WithWinRateMaxBuckets sets the maximal number of price buckets
+func WithWinRateMaxBuckets(count int) HistogramWinRateModelOption {
+	return func(m *HistogramWinRateModel) {
+		m.maxBuckets = count
+	}
+}
+
+// WithWinRateMinObservations sets the minimal number of outcomes required to shade bids
+func WithWinRateMinObservations(count uint64) HistogramWinRateModelOption {
+	return func(m *HistogramWinRateModel) {
+		m.minObservations = count
+	}
+}
+
+// NewHistogramWinRateModel with options
+func NewHistogramWinRateModel(opts ...HistogramWinRateModelOption) *HistogramWinRateModel {
+	model := &HistogramWinRateModel{
+		bucketSize:      defaultWinRateBucketSize,
+		maxBuckets:      defaultWinRateMaxBuckets,
+		minObservations: defaultWinRateMinObservations,
+		curves:          map[ShadingKey]*winRateCurve{},
+	}
+	for _, opt := range opts {
+		opt(model)
+	}
+	if model.bucketSize <= 0 {
+		model.bucketSize = defaultWinRateBucketSize
+	}
+	if model.maxBuckets <= 0 {
+		model.maxBuckets = defaultWinRateMaxBuckets
+	}
+	return model
+}
+
+// Observe the outcome of the bid
+func (m *HistogramWinRateModel) Observe(key ShadingKey, bidCPM billing.Money, win bool) {
+	if bidCPM <= 0 {
+		return
+	}
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	curve := m.curves[key]
+	if curve == nil {
+		curve = &winRateCurve{}
+		m.curves[key] = curve
+	}
+	idx := m.bucket(bidCPM)
+	if idx >= len(curve.Bids) {
+		curve.Bids = append(curve.Bids, make([]uint64, idx+1-len(curve.Bids))...)
+		curve.Wins = append(curve.Wins, make([]uint64, idx+1-len(curve.Wins))...)
+	}
+	curve.Bids[idx]++
+	if win {
+		curve.Wins[idx]++
+	}
+	curve.Total++
+}
+
+// ShadedBid returns the estimated clearing price for the bid (CPM)
+func (m *HistogramWinRateModel) ShadedBid(key ShadingKey, bidCPM billing.Money) billing.Money {
+	if bidCPM <= 0 {
+		return bidCPM
+	}
+	m.mx.RLock()
+	defer m.mx.RUnlock()
+
+	curve := m.curves[key]
+	if curve == nil || curve.Total < m.minObservations {
+		return bidCPM
+	}
+
+	var (
+		bidIdx      = m.bucket(bidCPM)
+		winRate     float64
+		bestPrice   = bidCPM
+		bestSurplus float64
+	)
+	for idx := 0; idx <= bidIdx && idx < len(curve.Bids); idx++ {
+		if curve.Bids[idx] > 0 {
+			// Laplace smoothing of the bucket win rate
+			rate := float64(curve.Wins[idx]+1) / float64(curve.Bids[idx]+2)
+			winRate = max(winRate, rate)
+		}
+		price := min(m.bucketSize*billing.Money(idx)+m.bucketSize/2, bidCPM)
+		if surplus := (bidCPM - price).Float64() * winRate; surplus > bestSurplus {
+			bestPrice, bestSurplus = price, surplus
+		}
+	}
+	return bestPrice
+}
+
+// WriteSnapshot of the model state in JSON format
+func (m *HistogramWinRateModel) WriteSnapshot(w io.Writer) error {
+	m.mx.RLock()
+	defer m.mx.RUnlock()
+
+	snapshot := histogramSnapshot{BucketSize: m.bucketSize}
+	for key, curve := range m.curves {
+		snapshot.Curves = append(snapshot.Curves, histogramCurveSnapshot{ShadingKey: key, winRateCurve: *curve})
+	}
+	return json.NewEncoder(w).Encode(&snapshot)
+}
+
+// ReadSnapshot and replace the model state
+func (m *HistogramWinRateModel) ReadSnapshot(r io.Reader) error {
+	var snapshot histogramSnapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return err
+	}
+	curves := make(map[ShadingKey]*winRateCurve, len(snapshot.Curves))
+	for i := range snapshot.Curves {
+		curve := snapshot.Curves[i].winRateCurve
+		if len(curve.Bids) != len(curve.Wins) {
+			return errors.New("[preprocessors] invalid win-rate curve snapshot")
+		}
+		curves[snapshot.Curves[i].ShadingKey] = &curve
+	}
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	if snapshot.BucketSize > 0 {
+		m.bucketSize = snapshot.BucketSize
+	}
+	m.curves = curves
+	return nil
+}
+
+func (m *HistogramWinRateModel) bucket(bidCPM billing.Money) int {
+	return min(int(bidCPM/m.bucketSize), m.maxBuckets-1)
+}
+
+type histogramSnapshot struct {
+	BucketSize billing.Money            `json:"bucket_size"`
+	Curves     []histogramCurveSnapshot `json:"curves"`
+}
+
+type histogramCurveSnapshot struct {
+	ShadingKey
+	winRateCurve
+}
+
+var (
+	_ WinRateModel            = (*HistogramWinRateModel)(nil)
+	_ WinRateModelSnapshotter = (*HistogramWinRateModel)(nil)
+)
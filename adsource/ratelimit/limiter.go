@@ -0,0 +1,152 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter of the request rate
+type Limiter interface {
+	// Allow returns true if the request can be executed right now
+	Allow() bool
+
+	// Available returns true if the next request will be allowed (without the quota taking)
+	Available() bool
+
+	// SetRate updates the limit of requests per second. Zero means no restrictions.
+	SetRate(rps int)
+}
+
+// TokenBucket limiter refills the bucket by `rps` tokens per second
+// and allows bursts up to the bucket capacity.
+type TokenBucket struct {
+	mx       sync.Mutex
+	rate     float64
+	burst    float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+	now      func() time.Time
+}
+
+// NewTokenBucket limiter with the rate per second and the burst capacity.
+// Zero burst means the capacity equal to the rate.
+func NewTokenBucket(rps, burst int) *TokenBucket {
+	bucket := &TokenBucket{burst: float64(burst), now: time.Now}
+	bucket.SetRate(rps)
+	bucket.tokens = bucket.capacity
+	return bucket
+}
+
+// Allow takes the token from the bucket if it's available
+func (b *TokenBucket) Allow() bool {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	if b.rate <= 0 {
+		return true
+	}
+	now := b.now()
+	if !b.last.IsZero() {
+		b.tokens = min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Available returns true if the bucket has the token
+func (b *TokenBucket) Available() bool {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	if b.rate <= 0 {
+		return true
+	}
+	tokens := b.tokens
+	if !b.last.IsZero() {
+		tokens = min(b.capacity, tokens+b.now().Sub(b.last).Seconds()*b.rate)
+	}
+	return tokens >= 1
+}
+
+// SetRate updates the rate and the capacity of the bucket
+func (b *TokenBucket) SetRate(rps int) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.rate = float64(max(rps, 0))
+	b.capacity = b.burst
+	if b.capacity <= 0 {
+		b.capacity = max(b.rate, 1)
+	}
+	b.tokens = min(b.tokens, b.capacity)
+}
+
+// SlidingWindow limiter approximates the number of requests in the last second
+// by the weighted counters of the current and the previous windows.
+type SlidingWindow struct {
+	mx       sync.Mutex
+	limit    float64
+	start    time.Time
+	current  float64
+	previous float64
+	now      func() time.Time
+}
+
+// NewSlidingWindow limiter with the rate per second
+func NewSlidingWindow(rps int) *SlidingWindow {
+	window := &SlidingWindow{now: time.Now}
+	window.SetRate(rps)
+	return window
+}
+
+// Allow counts the request if the rate of the last second is below the limit
+func (w *SlidingWindow) Allow() bool {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	if w.limit <= 0 {
+		return true
+	}
+	if w.rate(w.now()) >= w.limit {
+		return false
+	}
+	w.current++
+	return true
+}
+
+// Available returns true if the rate of the last second is below the limit
+func (w *SlidingWindow) Available() bool {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	return w.limit <= 0 || w.rate(w.now()) < w.limit
+}
+
+// SetRate updates the limit of the window
+func (w *SlidingWindow) SetRate(rps int) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	w.limit = float64(max(rps, 0))
+}
+
+// rate of the last second with the window shift
+func (w *SlidingWindow) rate(now time.Time) float64 {
+	switch elapsed := now.Sub(w.start); {
+	case elapsed >= 2*time.Second:
+		w.start, w.previous, w.current = now.Truncate(time.Second), 0, 0
+	case elapsed >= time.Second:
+		w.start, w.previous, w.current = w.start.Add(time.Second), w.current, 0
+	}
+	weight := 1 - float64(now.Sub(w.start))/float64(time.Second)
+	return w.previous*weight + w.current
+}
+
+var (
+	_ Limiter = (*TokenBucket)(nil)
+	_ Limiter = (*SlidingWindow)(nil)
+)
@@ -0,0 +1,131 @@
+package ratelimit
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/geniusrabbit/adcorelib/adquery/bidrequest"
+	"github.com/geniusrabbit/adcorelib/adquery/bidresponse"
+	"github.com/geniusrabbit/adcorelib/adtype"
+)
+
+type fakeClock struct{ tm time.Time }
+
+func (c *fakeClock) Now() time.Time             { return c.tm }
+func (c *fakeClock) Add(duration time.Duration) { c.tm = c.tm.Add(duration) }
+
+func allowed(limiter Limiter, count int) int {
+	res := 0
+	for range count {
+		if limiter.Allow() {
+			res++
+		}
+	}
+	return res
+}
+
+func TestTokenBucket(t *testing.T) {
+	clock := &fakeClock{tm: time.Unix(1000, 0)}
+	bucket := NewTokenBucket(10, 0)
+	bucket.now = clock.Now
+
+	if n := allowed(bucket, 20); n != 10 {
+		t.Fatalf("allowed=%d want=10", n)
+	}
+	if bucket.Available() {
+		t.Fatal("expected the empty bucket")
+	}
+	clock.Add(500 * time.Millisecond)
+	if n := allowed(bucket, 20); n != 5 {
+		t.Fatalf("allowed=%d want=5 after refill", n)
+	}
+
+	bucket.SetRate(0)
+	if n := allowed(bucket, 100); n != 100 {
+		t.Fatalf("allowed=%d without the limit", n)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	clock := &fakeClock{tm: time.Unix(1000, 0)}
+	window := NewSlidingWindow(10)
+	window.now = clock.Now
+
+	if n := allowed(window, 20); n != 10 {
+		t.Fatalf("allowed=%d want=10", n)
+	}
+	// Half of the previous window is still counted
+	clock.Add(1500 * time.Millisecond)
+	if n := allowed(window, 20); n != 5 {
+		t.Fatalf("allowed=%d want=5", n)
+	}
+	window.SetRate(20)
+	if !window.Available() {
+		t.Fatal("expected available window after the rate update")
+	}
+}
+
+type testSource struct {
+	adtype.Source
+	calls int
+}
+
+func (s *testSource) ID() uint64       { return 1 }
+func (s *testSource) Protocol() string { return "test" }
+
+func (s *testSource) Bid(request adtype.BidRequester) adtype.Response {
+	s.calls++
+	return bidresponse.NewEmptyResponse(request, s, adtype.ErrResponseNoBid)
+}
+
+func TestSource(t *testing.T) {
+	var (
+		original = &testSource{}
+		src      = Wrap(original, 2)
+		request  = &bidrequest.BidRequest{}
+	)
+	for range 3 {
+		src.Bid(request)
+	}
+	if original.calls != 2 {
+		t.Fatalf("calls=%d want=2", original.calls)
+	}
+	if resp := src.Bid(request); resp.Error() != ErrRateLimitExceeded {
+		t.Fatalf("response error: %v", resp.Error())
+	}
+	if !src.Throttle() {
+		t.Fatal("expected the throttled source")
+	}
+	if metrics := src.Metrics(); metrics.Throttled <= 0 || metrics.QPSLimit != 2 {
+		t.Fatalf("metrics: throttled=%f limit=%d", metrics.Throttled, metrics.QPSLimit)
+	}
+}
+
+func TestSourceSetRPSConcurrent(t *testing.T) {
+	var (
+		src = Wrap(&testSource{}, 10)
+		wg  sync.WaitGroup
+	)
+	for i := range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				src.SetRPS(i*100 + j)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				_ = src.RPS()
+				_ = src.Metrics()
+			}
+		}()
+	}
+	wg.Wait()
+	src.SetRPS(5)
+	if src.RPS() != 5 || src.Metrics().QPSLimit != 5 {
+		t.Fatalf("rps=%d want=5", src.RPS())
+	}
+}
@@ -0,0 +1,134 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+// Package ratelimit provides the request rate limitation of the sources
+// by the RPS value of the RTB source.
+package ratelimit
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/geniusrabbit/adcorelib/adquery/bidresponse"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/openlatency"
+)
+
+// ErrRateLimitExceeded skip reason of the throttled request
+var ErrRateLimitExceeded = adtype.ErrResponseSkipped.WithMessage("rps limit exceeded")
+
+type metricsAccessor interface {
+	Metrics() *openlatency.MetricsInfo
+}
+
+// Source wrapper limits the request rate of the original source.
+//
+// The throttled request returns the empty response with the adtype.ErrResponseSkipped error.
+// The wrapper implements adtype.SourceThrottler so the MultisourceWrapper skips the source
+// before the request execution in the worker pool.
+type Source struct {
+	adtype.Source
+	limiter Limiter
+	rps     atomic.Int64
+	metrics *openlatency.MetricsCounter
+}
+
+// Option of the source wrapper
+type Option func(src *Source)
+
+// WithLimiter sets the custom limiter instead of the token bucket
+func WithLimiter(limiter Limiter) Option {
+	return func(src *Source) {
+		src.limiter = limiter
+	}
+}
+
+// WithSlidingWindow uses the sliding window limiter instead of the token bucket
+func WithSlidingWindow() Option {
+	return func(src *Source) {
+		src.limiter = NewSlidingWindow(src.RPS())
+	}
+}
+
+// WithBurst sets the capacity of the token bucket
+func WithBurst(burst int) Option {
+	return func(src *Source) {
+		src.limiter = NewTokenBucket(src.RPS(), burst)
+	}
+}
+
+// Wrap the source with the rate limit. Zero RPS means no restrictions.
+func Wrap(source adtype.Source, rps int, opts ...Option) *Source {
+	src := &Source{Source: source, metrics: openlatency.NewMetricsCounter()}
+	src.rps.Store(int64(rps))
+	for _, opt := range opts {
+		opt(src)
+	}
+	if src.limiter == nil {
+		src.limiter = NewTokenBucket(rps, 0)
+	}
+	return src
+}
+
+// Unwrap returns the original source
+func (src *Source) Unwrap() adtype.Source { return src.Source }
+
+// Bid request for the original source if the rate limit allows it
+func (src *Source) Bid(request adtype.BidRequester) adtype.Response {
+	if !src.limiter.Allow() {
+		src.metrics.IncThrottle()
+		return bidresponse.NewEmptyResponse(request, src, ErrRateLimitExceeded)
+	}
+	return src.Source.Bid(request)
+}
+
+// Throttle returns true if the request will be rejected by the rate limit
+func (src *Source) Throttle() bool {
+	if src.limiter.Available() {
+		return false
+	}
+	src.metrics.IncThrottle()
+	return true
+}
+
+// SetRPS updates the rate limit (e.g. after the reload of the source model)
+func (src *Source) SetRPS(rps int) {
+	src.rps.Store(int64(rps))
+	src.limiter.SetRate(rps)
+}
+
+// RPS limit of the source
+func (src *Source) RPS() int { return int(src.rps.Load()) }
+
+// SetTimeout for the original source
+func (src *Source) SetTimeout(timeout time.Duration) {
+	if setter, _ := src.Source.(adtype.SourceTimeoutSetter); setter != nil {
+		setter.SetTimeout(timeout)
+	}
+}
+
+// Metrics of the original source with the throttled requests
+func (src *Source) Metrics() *openlatency.MetricsInfo {
+	var (
+		info      *openlatency.MetricsInfo
+		throttled openlatency.MetricsInfo
+	)
+	if accessor, _ := src.Source.(metricsAccessor); accessor != nil {
+		info = accessor.Metrics()
+	}
+	if info == nil {
+		info = &openlatency.MetricsInfo{ID: src.ID(), Protocol: src.Protocol()}
+	}
+	src.metrics.FillMetrics(&throttled)
+	info.Throttled = throttled.Throttled
+	info.QPSLimit = src.RPS()
+	return info
+}
+
+var (
+	_ adtype.Source              = (*Source)(nil)
+	_ adtype.SourceThrottler     = (*Source)(nil)
+	_ adtype.SourceTimeoutSetter = (*Source)(nil)
+)
@@ -0,0 +1,95 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package adsource
+
+import (
+	"math/rand/v2"
+
+	"github.com/demdxx/rpool/v2"
+
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
+	"github.com/geniusrabbit/adcorelib/eventtraking/events"
+	"github.com/geniusrabbit/adcorelib/eventtraking/eventstream"
+)
+
+// shadowTraffic sends the sampled copy of requests to the shadow sources.
+// Shadow sources never compete in the auction, their bids which would have
+// won the auction are reported as events.SourceShadowWin.
+type shadowTraffic struct {
+	sources    adtype.SourceAccessor
+	sampleRate float64
+	execpool   *rpool.Pool
+}
+
+func newShadowTraffic(sources adtype.SourceAccessor, sampleRate float64, maxParallelRequests int) *shadowTraffic {
+	return &shadowTraffic{
+		sources:    sources,
+		sampleRate: sampleRate,
+		execpool:   rpool.NewPool(rpool.WithMaxTasksCount(max(maxParallelRequests, minimalParallelRequests))),
+	}
+}
+
+// dispatch the request to the shadow sources if the request is sampled
+func (sh *shadowTraffic) dispatch(request adtype.BidRequester) *shadowAuction {
+	if sh.sampleRate <= 0 || (sh.sampleRate < 1 && rand.Float64() >= sh.sampleRate) {
+		return nil
+	}
+	auction := &shadowAuction{done: make(chan struct{})}
+	for _, src := range sh.sources.Iterator(request) {
+		if src == nil {
+			break
+		}
+		if !sh.execpool.Go(func() { auction.bid(request, src) }) {
+			break
+		}
+	}
+	return auction
+}
+
+// shadowAuction compares the shadow bids with the winners of the main auction
+type shadowAuction struct {
+	done    chan struct{}
+	winners map[string]billing.Money // Winning CPM by impression ID
+}
+
+// complete the main auction with the final response
+func (a *shadowAuction) complete(response adtype.Response) {
+	if response != nil && response.Error() == nil {
+		a.winners = make(map[string]billing.Money, len(response.Ads()))
+		for _, ad := range response.Ads() {
+			a.winners[ad.ImpressionID()] = max(a.winners[ad.ImpressionID()], ad.InternalAuctionCPMBid())
+		}
+	}
+	close(a.done)
+}
+
+func (a *shadowAuction) bid(request adtype.BidRequester, src adtype.Source) {
+	response := src.Bid(request)
+	if response == nil || response.Error() != nil || len(response.Ads()) == 0 {
+		return
+	}
+
+	// Wait for the main auction result
+	<-a.done
+
+	stream, _ := response.Context().Value(eventstream.CtxStreamObject).(eventstream.Stream)
+	if stream == nil {
+		return
+	}
+	for ad := range response.IterAds() {
+		if isNil(ad) || ad.Validate() != nil {
+			continue
+		}
+		cpm := ad.InternalAuctionCPMBid()
+		if imp := ad.Impression(); imp != nil && cpm < imp.BidFloorCPM {
+			continue
+		}
+		if cpm > a.winners[ad.ImpressionID()] {
+			_ = stream.Send(events.SourceShadowWin, events.StatusSuccess, response, ad)
+		}
+	}
+}
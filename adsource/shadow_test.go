@@ -0,0 +1,54 @@
+package adsource
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/geniusrabbit/adcorelib/eventtraking/events"
+	"github.com/geniusrabbit/adcorelib/eventtraking/eventstream"
+)
+
+func TestMultisourceWrapper_Shadow(t *testing.T) {
+	var (
+		main    = &tierSource{id: 1, bids: map[string]float64{"imp1": 2., "imp2": 2.}}
+		shadow  = &tierSource{id: 2, bids: map[string]float64{"imp1": 3., "imp2": 1., "imp3": 0.5}}
+		stream  = &recordStream{}
+		request = newWaterfallRequest()
+		wrp     = newTestWrapper(t,
+			WithSourceAccessor(&tierAccessor{tiers: [][]*tierSource{{main}}}),
+			WithShadowSources(&tierAccessor{tiers: [][]*tierSource{{shadow}}}, 1, 10),
+		)
+	)
+	request.SetContext(eventstream.WithStream(context.Background(), stream))
+
+	response := wrp.Bid(request)
+	if len(response.Ads()) != 2 {
+		t.Fatalf("ads=%d want=2", len(response.Ads()))
+	}
+	for ad := range response.IterAds() {
+		if ad.Source().ID() != main.id {
+			t.Fatalf("shadow source %d bid in the auction", ad.Source().ID())
+		}
+	}
+
+	var impIDs []string
+	for range 50 {
+		if wins := stream.Events(events.SourceShadowWin); len(wins) == 2 {
+			for _, win := range wins {
+				impIDs = append(impIDs, win.impID)
+			}
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	slices.Sort(impIDs)
+	// imp2 shadow bid is below the floor
+	if want := []string{"imp1", "imp3"}; !slices.Equal(impIDs, want) {
+		t.Fatalf("shadow wins=%v want=%v", impIDs, want)
+	}
+	if statuses := stream.Statuses(shadow.id); len(statuses) != 0 {
+		t.Fatalf("shadow bids must not be logged as source bids: %v", statuses)
+	}
+}
@@ -0,0 +1,220 @@
+package adsource
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/demdxx/gocast/v2"
+	"github.com/demdxx/rpool/v2"
+
+	"github.com/geniusrabbit/adcorelib/admodels/types"
+	"github.com/geniusrabbit/adcorelib/adquery/bidrequest"
+	"github.com/geniusrabbit/adcorelib/adquery/bidresponse"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/adtype/prices"
+	"github.com/geniusrabbit/adcorelib/billing"
+	"github.com/geniusrabbit/adcorelib/eventtraking/events"
+	"github.com/geniusrabbit/adcorelib/eventtraking/eventstream"
+)
+
+type testStream struct{ eventstream.Stream }
+
+func (testStream) Send(events.Type, uint8, adtype.Response, adtype.ResponseItem) error { return nil }
+func (testStream) SendSourceSkip(adtype.Response) error                                { return nil }
+func (testStream) SendSourceNoBid(adtype.Response) error                               { return nil }
+func (testStream) SendSourceFail(adtype.Response) error                                { return nil }
+
+type tierSource struct {
+	adtype.Source
+	id    uint64
+	bids  map[string]float64 // CPM by impression ID
+	delay time.Duration
+
+	mx        sync.Mutex
+	requested [][]string
+}
+
+func (s *tierSource) ID() uint64                           { return s.id }
+func (s *tierSource) Protocol() string                     { return "test" }
+func (s *tierSource) PriceCorrectionReduceFactor() float64 { return 0 }
+func (s *tierSource) RequestStrategy() adtype.RequestStrategy {
+	return adtype.AsynchronousRequestStrategy
+}
+
+func (s *tierSource) Bid(request adtype.BidRequester) adtype.Response {
+	time.Sleep(s.delay)
+	var (
+		ids   []string
+		items []adtype.ResponseItemCommon
+	)
+	for _, imp := range request.Impressions() {
+		ids = append(ids, imp.ID)
+		if cpm, ok := s.bids[imp.ID]; ok {
+			item := newTestItem(imp, cpm)
+			item.ItemID, item.Src = imp.ID+"-"+gocast.Str(s.id), s
+			items = append(items, item)
+		}
+	}
+	s.mx.Lock()
+	s.requested = append(s.requested, ids)
+	s.mx.Unlock()
+	if len(items) == 0 {
+		return bidresponse.NewEmptyResponse(request, s, adtype.ErrResponseNoBid)
+	}
+	return bidresponse.NewResponse(request, s, items, nil)
+}
+
+func newTestItem(imp *adtype.Impression, cpm float64) *bidresponse.ResponseItemBlank {
+	return &bidresponse.ResponseItemBlank{
+		ItemID:          imp.ID,
+		Imp:             imp,
+		Src:             &adtype.SourceEmpty{},
+		FormatVal:       &types.Format{},
+		PricingModelVal: types.PricingModelCPM,
+		PriceScope:      prices.PriceScope{ECPM: billing.MoneyFloat(cpm)},
+	}
+}
+
+type tierAccessor struct {
+	adtype.SourceAccessor
+	tiers   [][]*tierSource
+	pulled  []uint64
+	timeout time.Duration
+}
+
+func (a *tierAccessor) SetTimeout(_ context.Context, timeout time.Duration) { a.timeout = timeout }
+
+func (a *tierAccessor) Iterator(adtype.BidRequester) adtype.SourceIterator {
+	a.pulled = a.pulled[:0]
+	return func(yield func(float32, adtype.Source) bool) {
+		for i, tier := range a.tiers {
+			for _, src := range tier {
+				a.pulled = append(a.pulled, src.id)
+				if !yield(float32(len(a.tiers)-i), src) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func newWaterfallRequest() *bidrequest.BidRequest {
+	request := &bidrequest.BidRequest{
+		Imps: []*adtype.Impression{
+			{ID: "imp1", Count: 1},
+			{ID: "imp2", Count: 1, BidFloorCPM: billing.MoneyFloat(2.)},
+			{ID: "imp3", Count: 1},
+		},
+	}
+	request.SetContext(eventstream.WithStream(context.Background(), testStream{}))
+	_ = request.DeviceInfo()
+	return request
+}
+
+func newTestWrapper(t *testing.T, opts ...Option) *MultisourceWrapper {
+	wrp, err := NewMultisourceWrapper(append([]Option{WithMaxParallelRequests(10), WithTimeout(time.Second)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wrp
+}
+
+func TestMultisourceWrapper_Waterfall(t *testing.T) {
+	var (
+		accessor = &tierAccessor{}
+		wrp      = newTestWrapper(t, WithSourceAccessor(accessor), WithRequestStrategy(adtype.WaterfallRequestStrategy))
+	)
+
+	t.Run("tiers", func(t *testing.T) {
+		var (
+			premium = &tierSource{id: 1, bids: map[string]float64{"imp1": 3., "imp2": 1.}}
+			second  = &tierSource{id: 2, bids: map[string]float64{"imp2": 2.5}}
+			backup  = &tierSource{id: 3, bids: map[string]float64{"imp3": 0.5}}
+		)
+		accessor.tiers = [][]*tierSource{{premium}, {second}, {backup}}
+
+		response := wrp.Bid(newWaterfallRequest())
+		if response.Error() != nil {
+			t.Fatalf("response error: %v", response.Error())
+		}
+		if len(response.Ads()) != 3 {
+			t.Fatalf("ads=%d want=3", len(response.Ads()))
+		}
+		// imp2 is below the floor after the first tier
+		if want := []string{"imp2", "imp3"}; len(second.requested) != 1 || !slices.Equal(second.requested[0], want) {
+			t.Fatalf("second tier requested=%v want=%v", second.requested, want)
+		}
+		if want := []string{"imp3"}; len(backup.requested) != 1 || !slices.Equal(backup.requested[0], want) {
+			t.Fatalf("backup tier requested=%v want=%v", backup.requested, want)
+		}
+	})
+
+	t.Run("filled", func(t *testing.T) {
+		var (
+			premium = &tierSource{id: 1, bids: map[string]float64{"imp1": 3., "imp2": 3., "imp3": 3.}}
+			backup  = &tierSource{id: 2, bids: map[string]float64{"imp1": 1.}}
+		)
+		accessor.tiers = [][]*tierSource{{premium}, {backup}}
+
+		if response := wrp.Bid(newWaterfallRequest()); len(response.Ads()) != 3 {
+			t.Fatalf("ads=%d want=3", len(response.Ads()))
+		}
+		if len(backup.requested) != 0 {
+			t.Fatalf("backup tier must not be requested: %v", backup.requested)
+		}
+	})
+
+	t.Run("lazy", func(t *testing.T) {
+		var (
+			premium  = &tierSource{id: 1, bids: map[string]float64{"imp1": 3., "imp2": 3., "imp3": 3.}}
+			premium2 = &tierSource{id: 2}
+			second   = &tierSource{id: 3}
+			backup   = &tierSource{id: 4}
+		)
+		accessor.tiers = [][]*tierSource{{premium, premium2}, {second}, {backup}}
+
+		if response := wrp.Bid(newWaterfallRequest()); len(response.Ads()) != 3 {
+			t.Fatalf("ads=%d want=3", len(response.Ads()))
+		}
+		// Only the first source of the next tier is pulled to detect the end of the tier
+		if want := []uint64{1, 2, 3}; !slices.Equal(accessor.pulled, want) {
+			t.Fatalf("pulled sources=%v want=%v", accessor.pulled, want)
+		}
+		if len(second.requested) != 0 || len(backup.requested) != 0 {
+			t.Fatalf("next tiers must not be requested: %v %v", second.requested, backup.requested)
+		}
+	})
+}
+
+func TestMultisourceWrapper_WaterfallTierTimeout(t *testing.T) {
+	var (
+		slow     = &tierSource{id: 1, bids: map[string]float64{"imp1": 3.}, delay: 500 * time.Millisecond}
+		backfill = &tierSource{id: 2, bids: map[string]float64{"imp1": 1., "imp3": 1.}}
+		accessor = &tierAccessor{tiers: [][]*tierSource{{slow}, {backfill}}}
+		wrp      = newTestWrapper(t,
+			WithSourceAccessor(accessor),
+			WithRequestStrategy(adtype.WaterfallRequestStrategy),
+			WithWaterfallTierTimeout(50*time.Millisecond),
+		)
+	)
+	// The slow source keeps its worker busy, so the pool must not depend on the CPU count
+	wrp.execpool = rpool.NewPool(rpool.WithWorkerCount(2), rpool.WithMaxTasksCount(10))
+
+	// The slow tier exceeds its budget and the backfill tier still bids
+	response := wrp.Bid(newWaterfallRequest())
+	if len(backfill.requested) != 1 {
+		t.Fatalf("backfill tier requested=%v", backfill.requested)
+	}
+	if len(response.Ads()) != 2 {
+		t.Fatalf("ads=%d want=2", len(response.Ads()))
+	}
+	for _, ad := range response.Ads() {
+		if !strings.HasSuffix(ad.ID(), "-2") {
+			t.Fatalf("ad %s of the slow tier", ad.ID())
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package adtype
+
+// ExtExperimentArm is the request Ext key of the assigned experiment arm
+const ExtExperimentArm = "experiment_arm"
+
+// ExperimentArm assigned to the request
+type ExperimentArm struct {
+	ExperimentID string `json:"experiment_id"`
+	ArmID        string `json:"arm_id"`
+}
+
+// ExperimentArmFromRequest returns the experiment arm assigned to the request or nil
+func ExperimentArmFromRequest(request BidRequester) *ExperimentArm {
+	if request == nil {
+		return nil
+	}
+	switch arm := request.Get(ExtExperimentArm).(type) {
+	case *ExperimentArm:
+		return arm
+	case ExperimentArm:
+		return &arm
+	}
+	return nil
+}
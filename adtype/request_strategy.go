@@ -12,6 +12,11 @@ const (
 	// SingleRequestStrategy tells that if response was
 	// received it should be performed
 	SingleRequestStrategy
+
+	// WaterfallRequestStrategy requests the sources tier by tier
+	// (grouped by the priority) and sends the request to the next
+	// tier only for the impressions which are still unfilled
+	WaterfallRequestStrategy
 )
 
 func (rs RequestStrategy) IsSingle() bool {
@@ -21,3 +26,7 @@ func (rs RequestStrategy) IsSingle() bool {
 func (rs RequestStrategy) IsAsynchronous() bool {
 	return rs == AsynchronousRequestStrategy
 }
+
+func (rs RequestStrategy) IsWaterfall() bool {
+	return rs == WaterfallRequestStrategy
+}
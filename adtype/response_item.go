@@ -200,3 +200,27 @@ type ResponseMultipleItem interface {
 	// Count returns the total number of response items (ads) in the response.
 	Count() int
 }
+
+// ResponseItemAdvertiser is the optional interface of the response item
+// which describes the advertiser of the external platform bid.
+type ResponseItemAdvertiser interface {
+	// AdvertiserDomains returns the advertiser domains of the ad (adomain)
+	AdvertiserDomains() []string
+
+	// AdCategories returns the IAB content categories of the ad (cat)
+	AdCategories() []string
+
+	// ExtCampaignID returns the campaign ID of the external platform
+	ExtCampaignID() string
+}
+
+// ResponseItemCurrency is the optional interface of the response item
+// which was bid in the currency of the external platform.
+// All the prices of the item are converted into the base currency.
+type ResponseItemCurrency interface {
+	// OriginalBidCPM returns the bid of the external platform in its currency
+	OriginalBidCPM() billing.Amount
+
+	// BaseCurrency returns the currency of the item prices
+	BaseCurrency() billing.Currency
+}
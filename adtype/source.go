@@ -73,6 +73,13 @@ type SourceTimeoutSetter interface {
 	SetTimeout(timeout time.Duration)
 }
 
+// SourceThrottler checks the rate limit of the source before the request execution
+type SourceThrottler interface {
+	// Throttle returns true if the request will be rejected by the rate limit.
+	// It doesn't take the request quota.
+	Throttle() bool
+}
+
 // Source of advertisement and where will be selled the traffic
 type Source interface {
 	SourceMinimal
@@ -0,0 +1,347 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package auction
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+const (
+	defaultExactBlocks = 10
+	maxExactBlocks     = 20
+)
+
+// AllocationMode of the referee
+type AllocationMode uint8
+
+// Allocation modes
+const (
+	// GreedyAllocation fills the rings in the order of bids and replaces
+	// the multiple items by the single ones if it's possible (default)
+	GreedyAllocation AllocationMode = iota
+
+	// OptimalAllocation chooses the revenue-maximising combination of the single
+	// and the multiple items. The combination is exact if the number of the multiple
+	// items is not greater than the exact limit (see SetExactBlocks), otherwise
+	// the multiple items are added by the bid density while it increases the revenue.
+	OptimalAllocation
+)
+
+// PricingRule of the winners clearing prices
+type PricingRule uint8
+
+// Pricing rules
+const (
+	// FirstPriceRule charges the bid of the winner (default)
+	FirstPriceRule PricingRule = iota
+
+	// GSPPriceRule charges the next lower bid of the same slot
+	// (the highest losing bids of the slots for the multiple item)
+	GSPPriceRule
+
+	// VCGPriceRule charges the externality of the winner: the revenue lost
+	// by the other bidders because of the winner participation
+	VCGPriceRule
+)
+
+// Winner of the auction with the clearing CPM price (total for the multiple item)
+type Winner struct {
+	Item  adtype.ResponseItemCommon
+	Price billing.Money
+}
+
+// SetAllocationMode of the referee (GreedyAllocation by default)
+func (r *Referee) SetAllocationMode(mode AllocationMode) {
+	r.allocation = mode
+}
+
+// SetPricingRule of the winners clearing prices (FirstPriceRule by default)
+func (r *Referee) SetPricingRule(rule PricingRule) {
+	r.pricing = rule
+}
+
+// SetExactBlocks sets the maximal number of the multiple items for the exact
+// optimal allocation. The value is limited by 20 (2^20 combinations).
+func (r *Referee) SetExactBlocks(blocks int) {
+	r.exactBlocks = min(max(blocks, 0), maxExactBlocks)
+}
+
+// Allocate the rings and calculate the clearing prices of the winners
+func (r *Referee) Allocate(rings ...Ring) []Winner {
+	return r.allocateWinners(r.rng, rings)
+}
+
+// AllocateRequest rings by the request impressions (see Allocate).
+// The randomness is seeded by the request if the RNG is not set (see RequestSeed).
+func (r *Referee) AllocateRequest(req adtype.BidRequester) []Winner {
+	return r.allocateWinners(r.requestRand(req), requestRings(req))
+}
+
+func (r *Referee) allocateWinners(rng Rand, rings []Ring) []Winner {
+	if len(rings) < 1 {
+		return nil
+	}
+	r.normalize(rng)
+	var (
+		items   = r.allocate(slices.Clone(r.equipment), rings)
+		winners = make([]Winner, 0, len(items))
+	)
+	for _, it := range items {
+		winners = append(winners, Winner{Item: it, Price: r.clearingPrice(it, items, rings)})
+	}
+	return winners
+}
+
+// allocate the equipment by the allocation mode
+func (r *Referee) allocate(equipment []adtype.ResponseItemCommon, rings []Ring) []adtype.ResponseItemCommon {
+	if r.allocation == OptimalAllocation {
+		exactBlocks := r.exactBlocks
+		if exactBlocks == 0 {
+			exactBlocks = defaultExactBlocks
+		}
+		return allocateOptimal(equipment, rings, exactBlocks)
+	}
+	return matchGreedy(equipment, rings)
+}
+
+// clearingPrice of the winner by the pricing rule
+func (r *Referee) clearingPrice(winner adtype.ResponseItemCommon, winners []adtype.ResponseItemCommon, rings []Ring) billing.Money {
+	bid := winner.InternalAuctionCPMBid()
+	var price billing.Money
+	switch r.pricing {
+	case GSPPriceRule:
+		price = gspPrice(winner, winners, r.equipment, rings)
+	case VCGPriceRule:
+		// Revenue of the others without the winner minus the revenue of the others with the winner
+		others := slices.DeleteFunc(slices.Clone(r.equipment), func(it adtype.ResponseItemCommon) bool {
+			return it == winner
+		})
+		price = revenue(r.allocate(others, rings)) - (revenue(winners) - bid)
+	default:
+		return bid
+	}
+	return min(max(price, 0), bid)
+}
+
+// allocateOptimal chooses the subset of the multiple items (blocks) which maximises
+// the revenue with the rest of the slots filled by the best single items.
+func allocateOptimal(equipment []adtype.ResponseItemCommon, rings []Ring, exactBlocks int) []adtype.ResponseItemCommon {
+	var (
+		singles = make([][]adtype.ResponseItemCommon, len(rings))
+		blocks  []allocationBlock
+	)
+	for _, it := range equipment {
+		switch v := it.(type) {
+		case nil:
+		case adtype.ResponseMultipleItem:
+			if block, ok := newAllocationBlock(v, rings); ok {
+				blocks = append(blocks, block)
+			}
+		default:
+			if idx, _ := ringByID(it.ImpressionID(), rings); idx >= 0 {
+				singles[idx] = append(singles[idx], it)
+			}
+		}
+	}
+
+	// The best single items of each ring with the prefix sums of the bids
+	prefix := make([][]billing.Money, len(rings))
+	for i := range singles {
+		slices.SortStableFunc(singles[i], func(a, b adtype.ResponseItemCommon) int {
+			return cmp.Compare(b.InternalAuctionCPMBid(), a.InternalAuctionCPMBid())
+		})
+		prefix[i] = make([]billing.Money, len(singles[i])+1)
+		for j, it := range singles[i] {
+			prefix[i][j+1] = prefix[i][j] + it.InternalAuctionCPMBid()
+		}
+	}
+
+	var (
+		alloc = allocationState{rings: rings, prefix: prefix, used: make([]int, len(rings))}
+		best  []bool
+	)
+	if len(blocks) <= exactBlocks {
+		best = alloc.exact(blocks)
+	} else {
+		best = alloc.density(blocks)
+	}
+
+	// Collect the chosen blocks and fill the rest of the slots by the single items
+	var resp []adtype.ResponseItemCommon
+	clear(alloc.used)
+	for i, chosen := range best {
+		if chosen {
+			resp = append(resp, blocks[i].item)
+			alloc.add(&blocks[i], 1)
+		}
+	}
+	for i := range rings {
+		count := min(rings[i].Count-alloc.used[i], len(singles[i]))
+		resp = append(resp, singles[i][:max(count, 0)]...)
+	}
+	return resp
+}
+
+// allocationBlock is the multiple item with the number of ads in each ring
+type allocationBlock struct {
+	item   adtype.ResponseMultipleItem
+	counts []int
+	value  billing.Money
+	slots  int
+}
+
+func newAllocationBlock(item adtype.ResponseMultipleItem, rings []Ring) (allocationBlock, bool) {
+	block := allocationBlock{item: item, counts: make([]int, len(rings)), value: item.InternalAuctionCPMBid()}
+	for i, rn := range rings {
+		block.counts[i] = adsCountByImpID(rn.ID, item.Ads())
+		if block.counts[i] > rn.Count {
+			return block, false
+		}
+		block.slots += block.counts[i]
+	}
+	return block, block.slots > 0
+}
+
+// allocationState keeps the used slots of the rings during the search
+type allocationState struct {
+	rings  []Ring
+	prefix [][]billing.Money
+	used   []int
+}
+
+func (s *allocationState) fits(block *allocationBlock) bool {
+	for i, count := range block.counts {
+		if s.used[i]+count > s.rings[i].Count {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *allocationState) add(block *allocationBlock, sign int) {
+	for i, count := range block.counts {
+		s.used[i] += sign * count
+	}
+}
+
+// singlesValue returns the revenue of the best single items in the free slots
+func (s *allocationState) singlesValue() (value billing.Money) {
+	for i := range s.rings {
+		value += s.prefix[i][min(s.rings[i].Count-s.used[i], len(s.prefix[i])-1)]
+	}
+	return value
+}
+
+// exact search over all the feasible subsets of blocks
+func (s *allocationState) exact(blocks []allocationBlock) []bool {
+	var (
+		chosen    = make([]bool, len(blocks))
+		best      = make([]bool, len(blocks))
+		bestValue = s.singlesValue()
+		search    func(i int, value billing.Money)
+	)
+	search = func(i int, value billing.Money) {
+		if i == len(blocks) {
+			if total := value + s.singlesValue(); total > bestValue {
+				bestValue = total
+				copy(best, chosen)
+			}
+			return
+		}
+		if s.fits(&blocks[i]) {
+			s.add(&blocks[i], 1)
+			chosen[i] = true
+			search(i+1, value+blocks[i].value)
+			chosen[i] = false
+			s.add(&blocks[i], -1)
+		}
+		search(i+1, value)
+	}
+	search(0, 0)
+	return best
+}
+
+// density heuristic adds the blocks by the bid per slot while it increases the revenue
+func (s *allocationState) density(blocks []allocationBlock) []bool {
+	order := make([]int, len(blocks))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(
+			blocks[b].value/billing.Money(blocks[b].slots),
+			blocks[a].value/billing.Money(blocks[a].slots))
+	})
+	var (
+		best        = make([]bool, len(blocks))
+		blocksValue billing.Money
+		bestValue   = s.singlesValue()
+	)
+	for _, i := range order {
+		if !s.fits(&blocks[i]) {
+			continue
+		}
+		s.add(&blocks[i], 1)
+		if total := blocksValue + blocks[i].value + s.singlesValue(); total > bestValue {
+			bestValue = total
+			blocksValue += blocks[i].value
+			best[i] = true
+		} else {
+			s.add(&blocks[i], -1)
+		}
+	}
+	return best
+}
+
+// gspPrice of the single item is the next lower single bid of the same ring and
+// the price of the multiple item is the sum of the highest losing single bids of its slots
+func gspPrice(winner adtype.ResponseItemCommon, winners, equipment []adtype.ResponseItemCommon, rings []Ring) (price billing.Money) {
+	nextBid := func(impID string, skip func(it adtype.ResponseItemCommon) bool) (bid billing.Money) {
+		for _, it := range equipment {
+			if it == nil || it.ImpressionID() != impID || skip(it) {
+				continue
+			}
+			if _, ok := it.(adtype.ResponseMultipleItem); !ok {
+				bid = max(bid, it.InternalAuctionCPMBid())
+			}
+		}
+		return bid
+	}
+	if v, _ := winner.(adtype.ResponseMultipleItem); v != nil {
+		for _, rn := range rings {
+			if count := adsCountByImpID(rn.ID, v.Ads()); count > 0 {
+				price += billing.Money(count) * nextBid(rn.ID, func(it adtype.ResponseItemCommon) bool {
+					return slices.Contains(winners, it)
+				})
+			}
+		}
+		return price
+	}
+	bid := winner.InternalAuctionCPMBid()
+	return nextBid(winner.ImpressionID(), func(it adtype.ResponseItemCommon) bool {
+		return it == winner || it.InternalAuctionCPMBid() > bid
+	})
+}
+
+func revenue(items []adtype.ResponseItemCommon) (value billing.Money) {
+	for _, it := range items {
+		if it != nil {
+			value += it.InternalAuctionCPMBid()
+		}
+	}
+	return value
+}
+
+func requestRings(req adtype.BidRequester) (rings []Ring) {
+	for _, imp := range req.Impressions() {
+		rings = append(rings, Ring{ID: imp.ID, Count: imp.Count})
+	}
+	return rings
+}
@@ -0,0 +1,60 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package auction
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+
+	"github.com/demdxx/gocast/v2"
+
+	"github.com/geniusrabbit/adcorelib/adtype"
+)
+
+// ExtAuctionSeed is the request Ext key of the explicit seed of the auction randomness.
+// It's used to replay the recorded request with the same seed.
+const ExtAuctionSeed = "auction_seed"
+
+// Rand is the source of randomness of the auction.
+// *rand.Rand of math/rand/v2 implements it.
+type Rand interface {
+	Float32() float32
+	Shuffle(n int, swap func(i, j int))
+}
+
+// NewRand returns the deterministic random generator for the seed
+func NewRand(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+}
+
+// RequestSeed returns the seed of the auction randomness.
+// The explicit ExtAuctionSeed of the request has priority over the hash of the auction ID.
+// Returns false if the request has neither the explicit seed nor the auction ID.
+func RequestSeed(request adtype.BidRequester) (uint64, bool) {
+	if request == nil {
+		return 0, false
+	}
+	if seed := request.Get(ExtAuctionSeed); seed != nil {
+		return gocast.Uint64(seed), true
+	}
+	auctionID := request.AuctionID()
+	if auctionID == "" {
+		return 0, false
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(auctionID))
+	return h.Sum64(), true
+}
+
+// NewRequestRand returns the random generator seeded by the request (see RequestSeed)
+// or nil if the request has no seed, so the global generator must be used.
+func NewRequestRand(request adtype.BidRequester) Rand {
+	seed, ok := RequestSeed(request)
+	if !ok {
+		return nil
+	}
+	return NewRand(seed)
+}
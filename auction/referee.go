@@ -6,7 +6,9 @@
 package auction
 
 import (
-	"math/rand"
+	"cmp"
+	"math/rand/v2"
+	"slices"
 	"sort"
 
 	"github.com/geniusrabbit/adcorelib/adtype"
@@ -19,6 +21,19 @@ type Referee struct {
 
 	// equipment which used in auction competition
 	equipment []adtype.ResponseItemCommon
+
+	// rng of the equal bids shuffle (global by default)
+	rng Rand
+
+	// Allocation engine and clearing prices of the winners
+	allocation  AllocationMode
+	pricing     PricingRule
+	exactBlocks int
+}
+
+// SetRand sets the source of randomness of the referee
+func (r *Referee) SetRand(rng Rand) {
+	r.rng = rng
 }
 
 // Push items into equipment
@@ -31,25 +46,18 @@ func (r *Referee) Push(its ...adtype.ResponseItemCommon) {
 
 // TotalCapacity of the equipment
 func (r *Referee) TotalCapacity() (capacity int) {
-	for _, it := range r.equipment {
-		switch a := it.(type) {
-		case adtype.ResponseMultipleItem:
-			capacity += a.Count()
-		default:
-			capacity++
-		}
-	}
-	return capacity
+	return equipmentCapacity(r.equipment)
 }
 
-// Match point O(N * K * 2)
-func (r *Referee) Match(rings ...Ring) (resp []adtype.ResponseItemCommon) {
-	if len(rings) < 1 {
-		return resp
-	}
-
-	r.normalize()
+// Match point O(N * K * 2) for the greedy allocation mode
+// or the revenue-maximising combination for the optimal one (see SetAllocationMode)
+func (r *Referee) Match(rings ...Ring) []adtype.ResponseItemCommon {
+	return r.match(r.rng, rings)
+}
 
+// matchGreedy fills the rings by the equipment in the normalized order
+// and replaces the multiple items by the single ones if it's possible
+func matchGreedy(equipment []adtype.ResponseItemCommon, rings []Ring) (resp []adtype.ResponseItemCommon) {
 	// Borrow counters array from pool
 	var (
 		capacity         = ringsCapacity(rings)
@@ -61,7 +69,7 @@ func (r *Referee) Match(rings ...Ring) (resp []adtype.ResponseItemCommon) {
 	defer returnCounter(counters)
 
 	// First fill loop, complexity O(N * k)
-	for i, it := range r.equipment {
+	for i, it := range equipment {
 		if v, _ := it.(adtype.ResponseMultipleItem); v != nil {
 			if capacity < v.Count() {
 				continue
@@ -106,7 +114,7 @@ func (r *Referee) Match(rings ...Ring) (resp []adtype.ResponseItemCommon) {
 
 		if capacity < 1 {
 			if multipleResponse {
-				tail = r.equipment[i:]
+				tail = equipment[i:]
 			}
 			break
 		}
@@ -114,7 +122,7 @@ func (r *Referee) Match(rings ...Ring) (resp []adtype.ResponseItemCommon) {
 
 	// Here we are cheking do we have extra equipment
 	// for optimisation of response
-	if len(tail) < 1 || capacity < r.TotalCapacity() {
+	if len(tail) < 1 || capacity < equipmentCapacity(equipment) {
 		return resp
 	}
 
@@ -151,13 +159,10 @@ func (r *Referee) Match(rings ...Ring) (resp []adtype.ResponseItemCommon) {
 	return resp
 }
 
-// MatchRequest response by request
+// MatchRequest response by request.
+// The randomness is seeded by the request if the RNG is not set (see RequestSeed).
 func (r *Referee) MatchRequest(req adtype.BidRequester) []adtype.ResponseItemCommon {
-	var rings []Ring
-	for _, imp := range req.Impressions() {
-		rings = append(rings, Ring{ID: imp.ID, Count: imp.Count})
-	}
-	return r.Match(rings...)
+	return r.match(r.requestRand(req), requestRings(req))
 }
 
 // Equipment list
@@ -169,11 +174,37 @@ func (r *Referee) Equipment() []adtype.ResponseItemCommon {
 /// Internal methods
 ///////////////////////////////////////////////////////////////////////////////
 
-// normalize data for competition
-func (r *Referee) normalize() {
+func (r *Referee) match(rng Rand, rings []Ring) (resp []adtype.ResponseItemCommon) {
+	if len(rings) < 1 {
+		return resp
+	}
+	r.normalize(rng)
+	return r.allocate(r.equipment, rings)
+}
+
+// requestRand returns the RNG of the referee or the new one seeded by the request.
+// The RNG of the request is not kept, so the next request gets its own one.
+func (r *Referee) requestRand(req adtype.BidRequester) Rand {
+	if r.rng != nil {
+		return r.rng
+	}
+	return NewRequestRand(req)
+}
+
+// normalize data for competition (the global RNG is used if rng is nil)
+func (r *Referee) normalize(rng Rand) {
 	if !r.normalized {
+		swap := func(i, j int) { r.equipment[i], r.equipment[j] = r.equipment[j], r.equipment[i] }
 		// Shuffle data before processing
-		rand.Shuffle(len(r.equipment), func(i, j int) { r.equipment[i], r.equipment[j] = r.equipment[j], r.equipment[i] })
+		if rng != nil {
+			// The order of pushes doesn't affect the result of the seeded shuffle
+			slices.SortStableFunc(r.equipment, func(a, b adtype.ResponseItemCommon) int {
+				return cmp.Compare(a.ID(), b.ID())
+			})
+			rng.Shuffle(len(r.equipment), swap)
+		} else {
+			rand.Shuffle(len(r.equipment), swap)
+		}
 		sort.Sort(equipmentSlice(r.equipment))
 		r.normalized = true
 	}
@@ -183,6 +214,18 @@ func (r *Referee) normalize() {
 /// Helpers
 ///////////////////////////////////////////////////////////////////////////////
 
+func equipmentCapacity(equipment []adtype.ResponseItemCommon) (capacity int) {
+	for _, it := range equipment {
+		switch a := it.(type) {
+		case adtype.ResponseMultipleItem:
+			capacity += a.Count()
+		default:
+			capacity++
+		}
+	}
+	return capacity
+}
+
 func normalizeResponseForOptimization(resp []adtype.ResponseItemCommon) {
 	sort.Slice(resp, func(i, j int) bool {
 		e1, _ := resp[i].(adtype.ResponseMultipleItem)
@@ -10,9 +10,11 @@ package auction
 
 import (
 	"fmt"
+	"slices"
 	"sort"
 	"testing"
 
+	"github.com/geniusrabbit/adcorelib/adquery/bidrequest"
 	"github.com/geniusrabbit/adcorelib/adquery/bidresponse"
 	"github.com/geniusrabbit/adcorelib/adtype"
 	"github.com/geniusrabbit/adcorelib/adtype/prices"
@@ -254,6 +256,142 @@ func BenchmarkRefereeMatch(b *testing.B) {
 	})
 }
 
+func TestRefereeReproducible(t *testing.T) {
+	var scope []adtype.ResponseItemCommon
+	for i := range 10 {
+		it := newItem("1", int64(1+i%2)).(*bidresponse.ResponseItemBlank)
+		it.ItemID = fmt.Sprintf("ad%d", i)
+		scope = append(scope, it)
+	}
+	match := func(seed uint64, scope []adtype.ResponseItemCommon) (ids []string) {
+		ref := Referee{}
+		ref.SetRand(NewRand(seed))
+		ref.Push(scope...)
+		for _, it := range ref.Match(Ring{ID: "1", Count: 3}) {
+			ids = append(ids, it.ID())
+		}
+		return ids
+	}
+	expected := match(42, scope)
+	for range 10 {
+		reversed := slices.Clone(scope)
+		slices.Reverse(reversed)
+		if ids := match(42, reversed); !slices.Equal(ids, expected) {
+			t.Fatalf("replay: %v != %v", ids, expected)
+		}
+	}
+}
+
+func TestRefereeRequestRand(t *testing.T) {
+	var scope []adtype.ResponseItemCommon
+	for i := range 10 {
+		it := newItem("1", 1).(*bidresponse.ResponseItemBlank)
+		it.ItemID = fmt.Sprintf("ad%d", i)
+		scope = append(scope, it)
+	}
+	newRequest := func(auctionID string) *bidrequest.BidRequest {
+		return &bidrequest.BidRequest{IDVal: auctionID, Imps: []*adtype.Impression{{ID: "1", Count: 3}}}
+	}
+	match := func(ref *Referee, request adtype.BidRequester) (ids []string) {
+		ref.Push(scope...)
+		for _, it := range ref.MatchRequest(request) {
+			ids = append(ids, it.ID())
+		}
+		return ids
+	}
+
+	// The RNG of the request is not kept by the referee
+	ref := &Referee{}
+	expected := match(ref, newRequest("auction1"))
+	if ref.rng != nil {
+		t.Fatal("the request RNG must not be kept")
+	}
+	if ids := match(&Referee{}, newRequest("auction1")); !slices.Equal(ids, expected) {
+		t.Fatalf("replay: %v != %v", ids, expected)
+	}
+
+	// The request without the auction ID and the seed uses the global RNG
+	if _, ok := RequestSeed(newRequest("")); ok {
+		t.Fatal("the request without the auction ID has no seed")
+	}
+	if NewRequestRand(newRequest("")) != nil {
+		t.Fatal("the request without the auction ID must use the global RNG")
+	}
+	request := newRequest("")
+	request.Set(ExtAuctionSeed, 42)
+	if seed, ok := RequestSeed(request); !ok || seed != 42 {
+		t.Fatalf("explicit seed=%d ok=%t", seed, ok)
+	}
+}
+
+func TestRefereeOptimalAllocation(t *testing.T) {
+	scope := func() []adtype.ResponseItemCommon {
+		return []adtype.ResponseItemCommon{
+			newMultipleItem(
+				titem{ImpID: "1", Bid: 5},
+				titem{ImpID: "2", Bid: 2},
+			),
+			newMultipleItem(
+				titem{ImpID: "1", Bid: 4},
+				titem{ImpID: "3", Bid: 2},
+			),
+			newItem("1", 4),
+			newItem("2", 3),
+			newItem("3", 3),
+		}
+	}
+	rings := []Ring{{ID: "1", Count: 2}, {ID: "2", Count: 1}, {ID: "3", Count: 1}}
+
+	for _, exactBlocks := range []int{0, 1} {
+		t.Run(fmt.Sprintf("exact %d", exactBlocks), func(t *testing.T) {
+			ref := Referee{}
+			ref.SetAllocationMode(OptimalAllocation)
+			ref.SetExactBlocks(exactBlocks)
+			ref.Push(scope()...)
+
+			greedy := Referee{}
+			greedy.Push(scope()...)
+
+			res := ref.Match(rings...)
+			if revenue(res) < revenue(greedy.Match(rings...)) {
+				t.Fatalf("optimal revenue %v is less than greedy", respToBids(res))
+			}
+			if bids := []billing.Money{mi(5), mi(2), mi(4), mi(3)}; !testBids(bids, res) {
+				t.Fatalf("Fail result test: %v => %v", bids, respToBids(res))
+			}
+		})
+	}
+}
+
+func TestRefereeAllocatePricing(t *testing.T) {
+	tests := []struct {
+		rule   PricingRule
+		prices []billing.Money
+	}{
+		{rule: FirstPriceRule, prices: []billing.Money{mi(7), mi(5)}},
+		{rule: GSPPriceRule, prices: []billing.Money{mi(5), mi(2)}},
+		{rule: VCGPriceRule, prices: []billing.Money{mi(2), mi(2)}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("rule %d", tt.rule), func(t *testing.T) {
+			ref := Referee{}
+			ref.SetAllocationMode(OptimalAllocation)
+			ref.SetPricingRule(tt.rule)
+			ref.Push(newItem("1", 2), newItem("1", 7), newItem("1", 5))
+
+			winners := ref.Allocate(Ring{ID: "1", Count: 2})
+			if len(winners) != len(tt.prices) {
+				t.Fatalf("winners: %d != %d", len(winners), len(tt.prices))
+			}
+			for i, winner := range winners {
+				if winner.Price != tt.prices[i] {
+					t.Fatalf("price of %s: %s != %s", winner.Item.InternalAuctionCPMBid(), winner.Price, tt.prices[i])
+				}
+			}
+		})
+	}
+}
+
 func newItem(impid string, bid int64) adtype.ResponseItem {
 	return &bidresponse.ResponseItemBlank{
 		Src: nil,
@@ -1,15 +1,29 @@
 package trafaret
 
 import (
+	"iter"
 	"reflect"
 
 	"github.com/geniusrabbit/adcorelib/adtype"
 	"github.com/geniusrabbit/adcorelib/billing"
 )
 
+// Rand is the source of randomness of the priority choice.
+// *rand.Rand of math/rand/v2 implements it.
+type Rand interface {
+	Float32() float32
+}
+
 // Filler manages a collection of blockPriority objects.
 type Filler struct {
 	blocks []blockPriority
+	rng    Rand
+}
+
+// SetRand sets the source of randomness (global by default).
+// With the seeded RNG the result doesn't depend on the order of pushes.
+func (f *Filler) SetRand(rng Rand) {
+	f.rng = rng
 }
 
 // Push adds ads to the filler collection, grouping them by impression ID.
@@ -111,12 +125,16 @@ func (f *Filler) Fill(impid string, size int) []adtype.ResponseItemCommon {
 		return nil
 	}
 
+	if f.rng != nil {
+		block.sortBuckets()
+	}
+
 	muliadsCount := 0
 	result := make([]adtype.ResponseItemCommon, 0, size)
 
 	// Retrieve ads from the block.
 	for i := 0; i < size; i++ {
-		_, ad := block.Pop()
+		_, ad := block.Pop(f.rng)
 		if ad == nil {
 			break
 		}
@@ -136,6 +154,40 @@ func (f *Filler) Fill(impid string, size int) []adtype.ResponseItemCommon {
 	return packAdObjects(result, size)
 }
 
+// Count returns the number of ads (slots) for the impression ID
+// with the CPM bid not lower than minCPM. It doesn't modify the filler.
+func (f *Filler) Count(impid string, minCPM billing.Money) int {
+	block := f.Block(impid)
+	if block == nil {
+		return 0
+	}
+	count := 0
+	for i := range block.ads {
+		for _, ad := range block.ads[i].ads {
+			if ad.InternalAuctionCPMBid() >= minCPM {
+				count += adSize(ad)
+			}
+		}
+	}
+	return count
+}
+
+// Buckets returns the priority buckets of the impression ID with the ads
+// in ascending order of the CPM bid. It doesn't modify the filler.
+func (f *Filler) Buckets(impid string) iter.Seq2[float32, []adtype.ResponseItemCommon] {
+	return func(yield func(float32, []adtype.ResponseItemCommon) bool) {
+		block := f.Block(impid)
+		if block == nil {
+			return
+		}
+		for i := range block.ads {
+			if !yield(block.ads[i].priority, block.ads[i].ads) {
+				return
+			}
+		}
+	}
+}
+
 // Block retrieves a blockPriority by impression ID.
 func (f *Filler) Block(impid string) *blockPriority {
 	for i := range f.blocks {
@@ -152,7 +204,7 @@ func (f *Filler) Copy() *Filler {
 	for i := range f.blocks {
 		copyBlocks[i].copyFrom(&f.blocks[i])
 	}
-	return &Filler{blocks: copyBlocks}
+	return &Filler{blocks: copyBlocks, rng: f.rng}
 }
 
 // packAdObjects optimizes the selection of ads to fit within the maximum size.
@@ -215,12 +267,20 @@ func isNilAd(ad adtype.ResponseItemCommon) bool {
 	}
 }
 
+// filterNilAds returns the ads without nil items.
+// The original slice belongs to the response (which can be read concurrently)
+// so it's copied only if there are nil items.
 func filterNilAds(ads []adtype.ResponseItemCommon) []adtype.ResponseItemCommon {
-	filtered := ads[:0]
-	for _, ad := range ads {
-		if !isNilAd(ad) {
-			filtered = append(filtered, ad)
+	for i, ad := range ads {
+		if isNilAd(ad) {
+			filtered := append(make([]adtype.ResponseItemCommon, 0, len(ads)-1), ads[:i]...)
+			for _, ad := range ads[i+1:] {
+				if !isNilAd(ad) {
+					filtered = append(filtered, ad)
+				}
+			}
+			return filtered
 		}
 	}
-	return filtered
+	return ads
 }
@@ -1,6 +1,7 @@
 package trafaret
 
 import (
+	"math/rand/v2"
 	"testing"
 
 	"github.com/geniusrabbit/adcorelib/admodels/types"
@@ -64,6 +65,77 @@ func TestFiller(t *testing.T) {
 	}
 }
 
+func TestFillerCount(t *testing.T) {
+	var (
+		filler = &Filler{}
+		imp    = adtype.Impression{ID: "imp1"}
+		format = types.Format{}
+	)
+	for i, cpm := range []float64{0.5, 1.0, 2.0} {
+		filler.Push(0.5, &bidresponse.ResponseItemBlank{
+			ItemID:          "ad" + string(rune('1'+i)),
+			Imp:             &imp,
+			Src:             &adtype.SourceEmpty{},
+			FormatVal:       &format,
+			PricingModelVal: types.PricingModelCPM,
+			PriceScope:      prices.PriceScope{ECPM: billing.MoneyFloat(cpm)},
+		})
+	}
+	assert.Equal(t, 3, filler.Count("imp1", 0))
+	assert.Equal(t, 2, filler.Count("imp1", billing.MoneyFloat(1.0)))
+	assert.Equal(t, 0, filler.Count("imp2", 0))
+	assert.Equal(t, 3, filler.Count("imp1", 0), "Count must not modify the filler")
+}
+
+func TestFillerReproducible(t *testing.T) {
+	var (
+		imp     = adtype.Impression{ID: "imp1"}
+		format  = types.Format{}
+		buckets [][]adtype.ResponseItemCommon
+	)
+	for i := range 6 {
+		buckets = append(buckets, []adtype.ResponseItemCommon{&bidresponse.ResponseItemBlank{
+			ItemID:          "ad" + string(rune('1'+i)),
+			Imp:             &imp,
+			Src:             &adtype.SourceEmpty{},
+			FormatVal:       &format,
+			PricingModelVal: types.PricingModelCPM,
+			PriceScope:      prices.PriceScope{ECPM: billing.MoneyFloat(1.0)},
+		}})
+	}
+	fill := func(order []int) (ids []string) {
+		filler := &Filler{}
+		filler.SetRand(rand.New(rand.NewPCG(42, 42)))
+		for _, i := range order {
+			filler.Push(0.5, buckets[i]...)
+		}
+		for _, ad := range filler.Fill("imp1", 3) {
+			ids = append(ids, ad.ID())
+		}
+		return ids
+	}
+	expected := fill([]int{0, 1, 2, 3, 4, 5})
+	assert.Equal(t, expected, fill([]int{5, 4, 3, 2, 1, 0}))
+	assert.Equal(t, expected, fill([]int{3, 1, 5, 0, 2, 4}))
+}
+
+func TestFillerPushKeepsResponseAds(t *testing.T) {
+	var (
+		imp    = adtype.Impression{ID: "imp1"}
+		format = types.Format{}
+		ad1    = &bidresponse.ResponseItemBlank{ItemID: "ad1", Imp: &imp, Src: &adtype.SourceEmpty{}, FormatVal: &format}
+		ad2    = &bidresponse.ResponseItemBlank{ItemID: "ad2", Imp: &imp, Src: &adtype.SourceEmpty{}, FormatVal: &format}
+		ads    = []adtype.ResponseItemCommon{nil, ad1, (*bidresponse.ResponseItemBlank)(nil), ad2}
+		orig   = append([]adtype.ResponseItemCommon(nil), ads...)
+		filler = &Filler{}
+	)
+	filler.Push(0.5, ads...)
+
+	// The ads of the response must not be modified by the nil filtering
+	assert.Equal(t, orig, ads)
+	assert.Equal(t, 2, filler.Count("imp1", 0))
+}
+
 func adsListRealSize(list []adtype.ResponseItemCommon) int {
 	realSize := 0
 	for _, item := range list {
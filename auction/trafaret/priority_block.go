@@ -1,7 +1,9 @@
 package trafaret
 
 import (
+	"cmp"
 	"math/rand/v2"
+	"slices"
 
 	"github.com/geniusrabbit/adcorelib/adtype"
 )
@@ -19,11 +21,17 @@ func (b *blockPriority) Len() int {
 }
 
 // Pop selects an ad from the blocks based on a weighted random selection.
-func (b *blockPriority) Pop() (float32, adtype.ResponseItemCommon) {
+// The global random generator is used if rng is nil.
+func (b *blockPriority) Pop(rng Rand) (float32, adtype.ResponseItemCommon) {
 	if len(b.ads) == 0 {
 		return 0, nil
 	}
-	rv := rand.Float32() * b.summ
+	var rv float32
+	if rng != nil {
+		rv = rng.Float32() * b.summ
+	} else {
+		rv = rand.Float32() * b.summ
+	}
 	vl := float32(0)
 	for i := 0; i < len(b.ads); i++ {
 		vl += b.ads[i].priority
@@ -38,6 +46,17 @@ func (b *blockPriority) Pop() (float32, adtype.ResponseItemCommon) {
 	return 0, nil
 }
 
+// sortBuckets in the deterministic order independent of the push order:
+// by the priority descending and by the ID of the best ad.
+func (b *blockPriority) sortBuckets() {
+	slices.SortStableFunc(b.ads, func(a1, a2 adPreority) int {
+		if c := cmp.Compare(a2.priority, a1.priority); c != 0 {
+			return c
+		}
+		return cmp.Compare(a1.topID(), a2.topID())
+	})
+}
+
 func (b *blockPriority) copyFrom(other *blockPriority) {
 	b.impid = other.impid
 	b.summ = other.summ
@@ -1,6 +1,7 @@
 package trafaret
 
 import (
+	"cmp"
 	"slices"
 
 	"github.com/geniusrabbit/adcorelib/adtype"
@@ -29,12 +30,13 @@ func (a *adPreority) Pop() adtype.ResponseItemCommon {
 }
 
 // Sort orders the ads in ascending order based on their CPM bid.
+// The ads with the same bid are ordered by ID to keep the order reproducible.
 func (a *adPreority) Sort() {
 	slices.SortFunc(a.ads, func(a, b adtype.ResponseItemCommon) int {
 		bid1 := a.InternalAuctionCPMBid()
 		bid2 := b.InternalAuctionCPMBid()
 		if bid1 == bid2 {
-			return 0
+			return cmp.Compare(b.ID(), a.ID())
 		}
 		if bid1 < bid2 {
 			return -1
@@ -43,6 +45,14 @@ func (a *adPreority) Sort() {
 	})
 }
 
+// topID returns the ID of the best ad or empty string
+func (a *adPreority) topID() string {
+	if len(a.ads) == 0 {
+		return ""
+	}
+	return a.ads[len(a.ads)-1].ID()
+}
+
 func (a *adPreority) copyFrom(other *adPreority) {
 	a.priority = other.priority
 	a.ads = append(a.ads[:0], other.ads...)
@@ -0,0 +1,106 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package billing
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+// Currency ISO 4217 code
+type Currency string
+
+// DefaultCurrency of the amounts without explicit currency
+const DefaultCurrency Currency = "USD"
+
+// Set of currency errors
+var (
+	ErrUnknownCurrency     = errors.New("[billing] unknown currency")
+	ErrInvalidExchangeRate = errors.New("[billing] invalid exchange rate")
+	ErrConversionOverflow  = errors.New("[billing] currency conversion overflow")
+)
+
+// CurrencyOf returns the normalized currency code or DefaultCurrency if it's empty
+func CurrencyOf(code string) Currency {
+	if code = strings.ToUpper(strings.TrimSpace(code)); code == "" {
+		return DefaultCurrency
+	}
+	return Currency(code)
+}
+
+// String implementation of Stringer interface
+func (c Currency) String() string {
+	return string(c)
+}
+
+// Amount of money in the currency
+type Amount struct {
+	Value    Money    `json:"value"`
+	Currency Currency `json:"currency"`
+}
+
+// AmountOf money in the currency
+func AmountOf(value Money, currency Currency) Amount {
+	return Amount{Value: value, Currency: CurrencyOf(string(currency))}
+}
+
+// String implementation of Stringer interface
+func (a Amount) String() string {
+	return a.Value.String() + " " + a.Currency.String()
+}
+
+// MulRate returns the money multiplied by the fixed-point rate (9 decimals like Money).
+// The result is rounded half away from zero, so the conversion doesn't depend on the float arithmetic.
+func (m Money) MulRate(rate Money) (Money, error) {
+	v, ok := mulDiv(int64(m), int64(rate), moneyIntDelimeter)
+	if !ok {
+		return 0, ErrConversionOverflow
+	}
+	return Money(v), nil
+}
+
+// DivRate returns the money divided by the fixed-point rate (9 decimals like Money).
+// The result is rounded half away from zero.
+func (m Money) DivRate(rate Money) (Money, error) {
+	if rate == 0 {
+		return 0, ErrInvalidExchangeRate
+	}
+	v, ok := mulDiv(int64(m), moneyIntDelimeter, int64(rate))
+	if !ok {
+		return 0, ErrConversionOverflow
+	}
+	return Money(v), nil
+}
+
+// mulDiv calculates a*b/c with the 128-bit intermediate value and rounds the result half away from zero
+func mulDiv(a, b, c int64) (int64, bool) {
+	neg := (a < 0) != (b < 0) != (c < 0)
+	hi, lo := bits.Mul64(absU64(a), absU64(b))
+	uc := absU64(c)
+	if uc == 0 || hi >= uc {
+		return 0, false
+	}
+	q, r := bits.Div64(hi, lo, uc)
+	if r >= uc-r {
+		q++
+	}
+	if q > math.MaxInt64 {
+		return 0, false
+	}
+	if neg {
+		return -int64(q), true
+	}
+	return int64(q), true
+}
+
+func absU64(v int64) uint64 {
+	if v < 0 {
+		return uint64(^v) + 1
+	}
+	return uint64(v)
+}
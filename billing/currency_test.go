@@ -0,0 +1,88 @@
+package billing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoneyRate(t *testing.T) {
+	v, err := MoneyFloat(10.).MulRate(MoneyFloat(1.08))
+	assert.NoError(t, err)
+	assert.Equal(t, MoneyFloat(10.8), v)
+
+	// 1/3 rounds half away from zero on the last decimal
+	v, err = Money(2).DivRate(MoneyInt(3))
+	assert.NoError(t, err)
+	assert.Equal(t, Money(1), v)
+
+	v, err = Money(-5).MulRate(MoneyFloat(0.1))
+	assert.NoError(t, err)
+	assert.Equal(t, Money(-1), v)
+
+	_, err = MoneyInt(1_000_000_000).MulRate(MoneyInt(1_000_000_000))
+	assert.ErrorIs(t, err, ErrConversionOverflow)
+
+	_, err = MoneyInt(1).DivRate(0)
+	assert.ErrorIs(t, err, ErrInvalidExchangeRate)
+}
+
+func TestRateTable(t *testing.T) {
+	table, err := NewRateTable("usd", map[Currency]Money{
+		"EUR": MoneyFloat(1.08),
+		"JPY": MoneyFloat(0.0067),
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, Currency("USD"), table.Base())
+
+	v, err := table.Convert(MoneyInt(2), "EUR", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, MoneyFloat(2.16), v)
+
+	v, err = table.Convert(MoneyFloat(6.7), "usd", "JPY")
+	assert.NoError(t, err)
+	assert.Equal(t, MoneyInt(1000), v)
+
+	// The cross conversion goes through the base currency
+	v, err = table.Convert(MoneyInt(1), "EUR", "JPY")
+	assert.NoError(t, err)
+	assert.Equal(t, MoneyFloat(161.194029851), v)
+
+	_, err = table.Convert(MoneyInt(1), "GBP", "USD")
+	assert.ErrorIs(t, err, ErrUnknownCurrency)
+
+	_, err = NewRateTable("USD", map[Currency]Money{"EUR": 0})
+	assert.ErrorIs(t, err, ErrInvalidExchangeRate)
+}
+
+func TestRatesFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"rates.json": `{"base":"USD","rates":{"EUR":1.08}}`,
+		"rates.yml":  "base: USD\nrates:\n  EUR: 1.08\n",
+	}
+	for name, data := range files {
+		t.Run(name, func(t *testing.T) {
+			filename := filepath.Join(dir, name)
+			if !assert.NoError(t, os.WriteFile(filename, []byte(data), 0o600)) {
+				return
+			}
+			rates, err := NewRatesFile(filename)
+			if !assert.NoError(t, err) {
+				return
+			}
+			v, err := rates.Convert(MoneyInt(1), "EUR", "USD")
+			assert.NoError(t, err)
+			assert.Equal(t, MoneyFloat(1.08), v)
+
+			// The invalid file keeps the previous table
+			assert.NoError(t, os.WriteFile(filename, []byte("{"), 0o600))
+			assert.Error(t, rates.Reload())
+			assert.Equal(t, Currency("USD"), rates.Base())
+		})
+	}
+}
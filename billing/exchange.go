@@ -0,0 +1,160 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package billing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExchangeRates converts the amounts between currencies
+type ExchangeRates interface {
+	// Base currency of the rates
+	Base() Currency
+
+	// Convert the amount from one currency into another
+	Convert(amount Money, from, to Currency) (Money, error)
+}
+
+// RateTable is the static table of rates to the base currency.
+// The cross conversion goes through the base currency with the rounding on each step,
+// so the result is always the same for the same table.
+type RateTable struct {
+	base  Currency
+	rates map[Currency]Money
+}
+
+// NewRateTable returns the table of the rates where the rate is the price
+// of one unit of the currency in the base currency. For example, with USD base:
+//
+//	{"EUR": MoneyFloat(1.08), "JPY": MoneyFloat(0.0067)}
+func NewRateTable(base Currency, rates map[Currency]Money) (*RateTable, error) {
+	table := &RateTable{base: CurrencyOf(string(base)), rates: make(map[Currency]Money, len(rates))}
+	for cur, rate := range rates {
+		if rate <= 0 {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidExchangeRate, cur)
+		}
+		table.rates[CurrencyOf(string(cur))] = rate
+	}
+	return table, nil
+}
+
+// Base currency of the rates
+func (t *RateTable) Base() Currency {
+	return t.base
+}
+
+// Rate of one unit of the currency in the base currency
+func (t *RateTable) Rate(cur Currency) (Money, bool) {
+	if cur = CurrencyOf(string(cur)); cur == t.base {
+		return MoneyInt(1), true
+	}
+	rate, ok := t.rates[cur]
+	return rate, ok
+}
+
+// Convert the amount from one currency into another
+func (t *RateTable) Convert(amount Money, from, to Currency) (Money, error) {
+	if from, to = CurrencyOf(string(from)), CurrencyOf(string(to)); from == to || amount == 0 {
+		return amount, nil
+	}
+	var err error
+	if from != t.base {
+		rate, ok := t.rates[from]
+		if !ok {
+			return 0, fmt.Errorf("%w: %s", ErrUnknownCurrency, from)
+		}
+		if amount, err = amount.MulRate(rate); err != nil {
+			return 0, err
+		}
+	}
+	if to != t.base {
+		rate, ok := t.rates[to]
+		if !ok {
+			return 0, fmt.Errorf("%w: %s", ErrUnknownCurrency, to)
+		}
+		if amount, err = amount.DivRate(rate); err != nil {
+			return 0, err
+		}
+	}
+	return amount, nil
+}
+
+// rateTableFile is the file representation of the rate table
+//
+//	base: USD
+//	rates:
+//	  EUR: 1.08
+//	  JPY: 0.0067
+type rateTableFile struct {
+	Base  Currency           `json:"base" yaml:"base"`
+	Rates map[Currency]Money `json:"rates" yaml:"rates"`
+}
+
+// LoadRateTable from the JSON or YAML (.yml, .yaml) file
+func LoadRateTable(filename string) (*RateTable, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var file rateTableFile
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(data, &file)
+	default:
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewRateTable(file.Base, file.Rates)
+}
+
+// RatesFile is the rate table loaded from the file which can be reloaded on the fly
+type RatesFile struct {
+	filename string
+	table    atomic.Pointer[RateTable]
+}
+
+// NewRatesFile loads the rate table from the file (see LoadRateTable)
+func NewRatesFile(filename string) (*RatesFile, error) {
+	rates := &RatesFile{filename: filename}
+	if err := rates.Reload(); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// Reload the rate table from the file. The previous table stays active if the file is invalid.
+func (f *RatesFile) Reload() error {
+	table, err := LoadRateTable(f.filename)
+	if err != nil {
+		return err
+	}
+	f.table.Store(table)
+	return nil
+}
+
+// Base currency of the rates
+func (f *RatesFile) Base() Currency {
+	return f.table.Load().Base()
+}
+
+// Convert the amount from one currency into another
+func (f *RatesFile) Convert(amount Money, from, to Currency) (Money, error) {
+	return f.table.Load().Convert(amount, from, to)
+}
+
+var (
+	_ ExchangeRates = (*RateTable)(nil)
+	_ ExchangeRates = (*RatesFile)(nil)
+)
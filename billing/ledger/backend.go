@@ -0,0 +1,101 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package ledger
+
+import (
+	"context"
+	"sync"
+
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+// State of the account in the backend
+type State struct {
+	Balance    billing.Money `json:"balance"`
+	Spend      billing.Money `json:"spend"`
+	DailySpend billing.Money `json:"daily_spend"`
+}
+
+// Delta of the account spend by the day
+type Delta struct {
+	AccountID uint64        `json:"account_id"`
+	Day       string        `json:"day"`
+	Amount    billing.Money `json:"amount"`
+}
+
+// Backend of the account balances. It can be shared between several nodes
+// (e.g. Redis or database backend) to keep the balances of the cluster.
+type Backend interface {
+	// Load the state of the account with the daily spend of the day
+	Load(ctx context.Context, accountID uint64, day string) (State, error)
+
+	// Apply the spend deltas and returns the new states of the accounts with the daily spend of the day.
+	// The deltas must be applied atomically: all or nothing.
+	Apply(ctx context.Context, day string, deltas []Delta) (map[uint64]State, error)
+}
+
+// MemoryBackend keeps the balances in the memory of the process
+type MemoryBackend struct {
+	mx       sync.Mutex
+	accounts map[uint64]*memoryAccount
+}
+
+type memoryAccount struct {
+	balance billing.Money
+	spend   billing.Money
+	daily   map[string]billing.Money
+}
+
+// NewMemoryBackend of the balances
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{accounts: map[uint64]*memoryAccount{}}
+}
+
+// Deposit the amount to the account balance
+func (b *MemoryBackend) Deposit(accountID uint64, amount billing.Money) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.account(accountID).balance += amount
+}
+
+// Load the state of the account with the daily spend of the day
+func (b *MemoryBackend) Load(_ context.Context, accountID uint64, day string) (State, error) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	return b.account(accountID).state(day), nil
+}
+
+// Apply the spend deltas and returns the new states of the accounts
+func (b *MemoryBackend) Apply(_ context.Context, day string, deltas []Delta) (map[uint64]State, error) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	states := make(map[uint64]State, len(deltas))
+	for _, delta := range deltas {
+		acc := b.account(delta.AccountID)
+		acc.balance -= delta.Amount
+		acc.spend += delta.Amount
+		acc.daily[delta.Day] += delta.Amount
+	}
+	for _, delta := range deltas {
+		states[delta.AccountID] = b.accounts[delta.AccountID].state(day)
+	}
+	return states, nil
+}
+
+func (b *MemoryBackend) account(accountID uint64) *memoryAccount {
+	acc := b.accounts[accountID]
+	if acc == nil {
+		acc = &memoryAccount{daily: map[string]billing.Money{}}
+		b.accounts[accountID] = acc
+	}
+	return acc
+}
+
+func (a *memoryAccount) state(day string) State {
+	return State{Balance: a.balance, Spend: a.spend, DailySpend: a.daily[day]}
+}
+
+var _ Backend = (*MemoryBackend)(nil)
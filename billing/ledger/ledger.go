@@ -0,0 +1,450 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+// Package ledger books the spend of the accounts in the process and flushes
+// the aggregated deltas to the shared backend.
+//
+// The amount is reserved at the bid time and committed or released on the win,
+// the impression or the reservation timeout:
+//
+//	if err := led.Reserve(accountID, item.ID(), bidPrice); err != nil {
+//		// skip the bid: the balance or the daily limit is exhausted
+//	}
+//	...
+//	led.Commit(item.ID(), clearingPrice) // win or impression
+//	led.Release(item.ID())               // loss
+//
+// The account state (admodels.AccountBalanceState) includes the unflushed spend
+// of the process, so the account model reads the actual balance (see Attach).
+//
+// # Overspend bounds
+//
+// All the checks and the reservations of the process are done under the ledger lock,
+// so the process never reserves more than the available amount. The overspend is
+// possible only by:
+//
+//   - the commits above the reserved amount and the Book calls without reservation
+//     (e.g. clicks and leads), which are always accepted as the facts of the spend;
+//   - the spend of the other nodes which share the backend. Each node sees the spend
+//     of the others after the flush, so the overspend of the cluster is limited by
+//     (nodes - 1) × the spend rate of the node × the flush interval plus the
+//     active reservations of the other nodes.
+package ledger
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/geniusrabbit/adcorelib/admodels"
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+const (
+	dayFormat             = "2006-01-02"
+	defaultFlushInterval  = 5 * time.Second
+	defaultReservationTTL = time.Minute
+)
+
+// Set of ledger errors
+var (
+	ErrBalanceExhausted       = errors.New("[ledger] account balance is exhausted")
+	ErrDailyLimitExhausted    = errors.New("[ledger] account daily limit is exhausted")
+	ErrUnknownAccount         = errors.New("[ledger] unknown account")
+	ErrReservationExists      = errors.New("[ledger] reservation already exists")
+	ErrReservationNotFound    = errors.New("[ledger] reservation not found")
+	ErrInvalidReservationSize = errors.New("[ledger] invalid reservation amount")
+)
+
+// Ledger of the account balances with the reservations
+type Ledger struct {
+	mx           sync.Mutex
+	accounts     map[uint64]*account
+	reservations map[string]*reservation
+
+	backend        Backend
+	flushInterval  time.Duration
+	reservationTTL time.Duration
+	location       *time.Location
+	now            func() time.Time
+}
+
+type account struct {
+	id       uint64
+	maxDaily billing.Money
+
+	// The state of the backend
+	day   string
+	state State
+
+	reserved billing.Money            // Active reservations
+	pending  map[string]billing.Money // Committed and not flushed spend by days
+	inflight map[string]billing.Money // Spend which is flushing now by days
+}
+
+type reservation struct {
+	accountID uint64
+	amount    billing.Money
+	expiresAt time.Time
+}
+
+// Option of the ledger
+type Option func(l *Ledger)
+
+// WithBackend sets the shared backend of the balances (MemoryBackend by default)
+func WithBackend(backend Backend) Option {
+	return func(l *Ledger) {
+		l.backend = backend
+	}
+}
+
+// WithFlushInterval sets the interval of the deltas flush of Run (5 seconds by default)
+func WithFlushInterval(interval time.Duration) Option {
+	return func(l *Ledger) {
+		l.flushInterval = interval
+	}
+}
+
+// WithReservationTTL sets the timeout of the reservation release (1 minute by default)
+func WithReservationTTL(ttl time.Duration) Option {
+	return func(l *Ledger) {
+		l.reservationTTL = ttl
+	}
+}
+
+// WithLocation sets the timezone of the daily limit reset
+func WithLocation(location *time.Location) Option {
+	return func(l *Ledger) {
+		l.location = location
+	}
+}
+
+// New ledger of the account balances
+func New(opts ...Option) *Ledger {
+	l := &Ledger{
+		accounts:       map[uint64]*account{},
+		reservations:   map[string]*reservation{},
+		flushInterval:  defaultFlushInterval,
+		reservationTTL: defaultReservationTTL,
+		location:       time.UTC,
+		now:            time.Now,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.backend == nil {
+		l.backend = NewMemoryBackend()
+	}
+	if l.location == nil {
+		l.location = time.UTC
+	}
+	if l.flushInterval <= 0 {
+		l.flushInterval = defaultFlushInterval
+	}
+	return l
+}
+
+// Load the account state from the backend and sets the daily limit of the account
+func (l *Ledger) Load(ctx context.Context, accountID uint64, maxDaily billing.Money) error {
+	day := l.day()
+	state, err := l.backend.Load(ctx, accountID, day)
+	if err != nil {
+		return err
+	}
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	acc := l.account(accountID)
+	acc.maxDaily, acc.day, acc.state = maxDaily, day, state
+	return nil
+}
+
+// Attach the ledger state to the account model and load its state from the backend
+func (l *Ledger) Attach(ctx context.Context, acc *admodels.Account) error {
+	if err := l.Load(ctx, acc.ID(), acc.MaxDaily); err != nil {
+		return err
+	}
+	acc.CurrentState = l.State(acc.ID())
+	return nil
+}
+
+// State of the account balance which includes the unflushed spend of the process
+func (l *Ledger) State(accountID uint64) admodels.AccountBalanceState {
+	return accountState{ledger: l, id: accountID}
+}
+
+// Reserve the amount of the account for the bid by the key (the ID of the response item).
+// Returns the error if the balance or the daily limit of the account is not enough.
+func (l *Ledger) Reserve(accountID uint64, key string, amount billing.Money) error {
+	if amount <= 0 {
+		return ErrInvalidReservationSize
+	}
+	day := l.day()
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	acc := l.accounts[accountID]
+	if acc == nil {
+		return ErrUnknownAccount
+	}
+	if l.reservations[key] != nil {
+		return ErrReservationExists
+	}
+	if err := acc.check(day, amount); err != nil {
+		return err
+	}
+	acc.reserved += amount
+	l.reservations[key] = &reservation{accountID: accountID, amount: amount, expiresAt: l.now().Add(l.reservationTTL)}
+	return nil
+}
+
+// Commit the spend of the reservation. The amount can differ from the reserved one
+// (e.g. the clearing price of the second price auction), the rest of the reservation is released.
+func (l *Ledger) Commit(key string, amount billing.Money) error {
+	day := l.day()
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	res := l.reservations[key]
+	if res == nil {
+		return ErrReservationNotFound
+	}
+	delete(l.reservations, key)
+	acc := l.accounts[res.accountID]
+	acc.reserved -= res.amount
+	acc.book(day, amount)
+	return nil
+}
+
+// Release the reservation without the spend
+func (l *Ledger) Release(key string) error {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	res := l.reservations[key]
+	if res == nil {
+		return ErrReservationNotFound
+	}
+	delete(l.reservations, key)
+	l.accounts[res.accountID].reserved -= res.amount
+	return nil
+}
+
+// Book the spend of the account without reservation (clicks, leads, etc.)
+func (l *Ledger) Book(accountID uint64, amount billing.Money) error {
+	day := l.day()
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	acc := l.accounts[accountID]
+	if acc == nil {
+		return ErrUnknownAccount
+	}
+	acc.book(day, amount)
+	return nil
+}
+
+// Check returns the error if the account can't bid: the balance or the daily limit is exhausted
+func (l *Ledger) Check(accountID uint64) error {
+	day := l.day()
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	acc := l.accounts[accountID]
+	if acc == nil {
+		return ErrUnknownAccount
+	}
+	return acc.check(day, 1)
+}
+
+// Exhausted returns true if the account can't bid
+func (l *Ledger) Exhausted(accountID uint64) bool {
+	return l.Check(accountID) != nil
+}
+
+// Expire releases the reservations after the timeout and returns the number of released ones
+func (l *Ledger) Expire() (count int) {
+	now := l.now()
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	for key, res := range l.reservations {
+		if now.After(res.expiresAt) {
+			delete(l.reservations, key)
+			l.accounts[res.accountID].reserved -= res.amount
+			count++
+		}
+	}
+	return count
+}
+
+// Flush the committed spend to the backend and update the account states.
+// The deltas are kept for the next flush if the backend fails.
+func (l *Ledger) Flush(ctx context.Context) error {
+	var deltas []Delta
+	l.mx.Lock()
+	for _, acc := range l.accounts {
+		for day, amount := range acc.pending {
+			deltas = append(deltas, Delta{AccountID: acc.id, Day: day, Amount: amount})
+			if acc.inflight == nil {
+				acc.inflight = map[string]billing.Money{}
+			}
+			acc.inflight[day] += amount
+		}
+		clear(acc.pending)
+	}
+	l.mx.Unlock()
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	day := l.day()
+	states, err := l.backend.Apply(ctx, day, deltas)
+
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	for _, delta := range deltas {
+		acc := l.accounts[delta.AccountID]
+		acc.inflight[delta.Day] -= delta.Amount
+		if acc.inflight[delta.Day] == 0 {
+			delete(acc.inflight, delta.Day)
+		}
+		if err != nil {
+			acc.pending[delta.Day] += delta.Amount
+		}
+	}
+	for accountID, state := range states {
+		if acc := l.accounts[accountID]; acc != nil {
+			acc.day, acc.state = day, state
+		}
+	}
+	return err
+}
+
+// Refresh the states of the accounts from the backend to see the spend of the other nodes.
+// It's called by Run after the flush and must not be called concurrently with Flush.
+func (l *Ledger) Refresh(ctx context.Context) error {
+	l.mx.Lock()
+	ids := make([]uint64, 0, len(l.accounts))
+	for id := range l.accounts {
+		ids = append(ids, id)
+	}
+	l.mx.Unlock()
+
+	day := l.day()
+	for _, id := range ids {
+		state, err := l.backend.Load(ctx, id, day)
+		if err != nil {
+			return err
+		}
+		l.mx.Lock()
+		acc := l.accounts[id]
+		acc.day, acc.state = day, state
+		l.mx.Unlock()
+	}
+	return nil
+}
+
+// Run the periodic flush, the refresh and the reservations expiration until the context is done.
+// The last flush is done on exit.
+func (l *Ledger) Run(ctx context.Context) error {
+	ticker := time.NewTicker(l.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			l.Expire()
+			return l.Flush(context.WithoutCancel(ctx))
+		case <-ticker.C:
+			l.Expire()
+			if err := l.Flush(ctx); err == nil {
+				_ = l.Refresh(ctx)
+			}
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+/// Internal methods
+///////////////////////////////////////////////////////////////////////////////
+
+// account returns the account state (must be called under the lock)
+func (l *Ledger) account(accountID uint64) *account {
+	acc := l.accounts[accountID]
+	if acc == nil {
+		acc = &account{id: accountID, pending: map[string]billing.Money{}}
+		l.accounts[accountID] = acc
+	}
+	return acc
+}
+
+func (l *Ledger) day() string {
+	return l.now().In(l.location).Format(dayFormat)
+}
+
+// local spend of the account which is not in the backend state yet
+func (a *account) local(day string) (total, daily billing.Money) {
+	for d, amount := range a.pending {
+		total += amount
+		if d == day {
+			daily += amount
+		}
+	}
+	for d, amount := range a.inflight {
+		total += amount
+		if d == day {
+			daily += amount
+		}
+	}
+	return total, daily
+}
+
+func (a *account) check(day string, amount billing.Money) error {
+	total, daily := a.local(day)
+	if a.state.Balance-total-a.reserved < amount {
+		return ErrBalanceExhausted
+	}
+	if a.maxDaily > 0 {
+		if a.day == day {
+			daily += a.state.DailySpend
+		}
+		if a.maxDaily-daily-a.reserved < amount {
+			return ErrDailyLimitExhausted
+		}
+	}
+	return nil
+}
+
+func (a *account) book(day string, amount billing.Money) {
+	if amount > 0 {
+		a.pending[day] += amount
+	}
+}
+
+// accountState implements admodels.AccountBalanceState with the local spend of the ledger
+type accountState struct {
+	ledger *Ledger
+	id     uint64
+}
+
+// Balance of the account without the unflushed spend
+func (s accountState) Balance() billing.Money {
+	s.ledger.mx.Lock()
+	defer s.ledger.mx.Unlock()
+	acc := s.ledger.accounts[s.id]
+	if acc == nil {
+		return 0
+	}
+	total, _ := acc.local("")
+	return acc.state.Balance - total
+}
+
+// Spend of the account with the unflushed spend
+func (s accountState) Spend() billing.Money {
+	s.ledger.mx.Lock()
+	defer s.ledger.mx.Unlock()
+	acc := s.ledger.accounts[s.id]
+	if acc == nil {
+		return 0
+	}
+	total, _ := acc.local("")
+	return acc.state.Spend + total
+}
+
+var _ admodels.AccountBalanceState = accountState{}
@@ -0,0 +1,123 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/geniusrabbit/adcorelib/admodels"
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+type failBackend struct {
+	*MemoryBackend
+	fail bool
+}
+
+func (b *failBackend) Apply(ctx context.Context, day string, deltas []Delta) (map[uint64]State, error) {
+	if b.fail {
+		return nil, errors.New("backend is unavailable")
+	}
+	return b.MemoryBackend.Apply(ctx, day, deltas)
+}
+
+func TestLedgerReservation(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		backend = NewMemoryBackend()
+		led     = New(WithBackend(backend), WithReservationTTL(time.Second))
+		acc     = &admodels.Account{IDval: 1, MaxDaily: billing.MoneyInt(8)}
+	)
+	backend.Deposit(1, billing.MoneyInt(10))
+	if !assert.NoError(t, led.Attach(ctx, acc)) {
+		return
+	}
+
+	assert.NoError(t, led.Reserve(1, "a", billing.MoneyInt(5)))
+	assert.ErrorIs(t, led.Reserve(1, "a", billing.MoneyInt(1)), ErrReservationExists)
+	assert.ErrorIs(t, led.Reserve(1, "b", billing.MoneyInt(4)), ErrDailyLimitExhausted)
+	assert.NoError(t, led.Reserve(1, "b", billing.MoneyInt(3)))
+	assert.True(t, led.Exhausted(1))
+
+	// The win of the second price auction commits the lower amount
+	assert.NoError(t, led.Commit("a", billing.MoneyInt(4)))
+	assert.NoError(t, led.Release("b"))
+	assert.ErrorIs(t, led.Release("b"), ErrReservationNotFound)
+	assert.Equal(t, billing.MoneyInt(6), acc.Balance())
+	assert.Equal(t, billing.MoneyInt(4), acc.Spend())
+
+	// The reservation is released by the timeout
+	assert.NoError(t, led.Reserve(1, "c", billing.MoneyInt(4)))
+	assert.True(t, led.Exhausted(1))
+	led.now = func() time.Time { return time.Now().Add(2 * time.Second) }
+	assert.Equal(t, 1, led.Expire())
+	assert.False(t, led.Exhausted(1))
+
+	assert.NoError(t, led.Flush(ctx))
+	state, _ := backend.Load(ctx, 1, led.day())
+	assert.Equal(t, State{Balance: billing.MoneyInt(6), Spend: billing.MoneyInt(4), DailySpend: billing.MoneyInt(4)}, state)
+	assert.Equal(t, billing.MoneyInt(6), acc.Balance())
+}
+
+func TestLedgerFlushFailure(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		backend = &failBackend{MemoryBackend: NewMemoryBackend(), fail: true}
+		led     = New(WithBackend(backend))
+	)
+	backend.Deposit(1, billing.MoneyInt(10))
+	assert.NoError(t, led.Load(ctx, 1, 0))
+	assert.NoError(t, led.Book(1, billing.MoneyInt(3)))
+
+	assert.Error(t, led.Flush(ctx))
+	assert.Equal(t, billing.MoneyInt(7), led.State(1).Balance(), "the failed deltas are kept")
+
+	backend.fail = false
+	assert.NoError(t, led.Flush(ctx))
+	assert.Equal(t, billing.MoneyInt(7), led.State(1).Balance())
+	assert.NoError(t, led.Flush(ctx))
+	state, _ := backend.Load(ctx, 1, led.day())
+	assert.Equal(t, billing.MoneyInt(7), state.Balance, "the deltas are flushed once")
+}
+
+func TestLedgerConcurrentReservations(t *testing.T) {
+	var (
+		ctx      = context.Background()
+		backend  = NewMemoryBackend()
+		led      = New(WithBackend(backend))
+		wg       sync.WaitGroup
+		mx       sync.Mutex
+		reserved int
+	)
+	backend.Deposit(1, billing.MoneyInt(100))
+	assert.NoError(t, led.Load(ctx, 1, 0))
+
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 10 {
+				key := string(rune('a'+i)) + string(rune('a'+j))
+				if led.Reserve(1, key, billing.MoneyInt(1)) == nil {
+					mx.Lock()
+					reserved++
+					mx.Unlock()
+					_ = led.Commit(key, billing.MoneyInt(1))
+				}
+				if j%3 == 0 {
+					_ = led.Flush(ctx)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.NoError(t, led.Flush(ctx))
+
+	state, _ := backend.Load(ctx, 1, led.day())
+	assert.Equal(t, 100, reserved, "the balance must not be overspent")
+	assert.Equal(t, billing.Money(0), state.Balance)
+}
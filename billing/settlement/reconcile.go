@@ -0,0 +1,195 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package settlement
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	nc "github.com/geniusrabbit/notificationcenter/v2"
+
+	"github.com/geniusrabbit/adcorelib/billing"
+)
+
+// DayReport of the reconciliation of the day records in the currency
+type DayReport struct {
+	Day      string           `json:"day"`
+	Currency billing.Currency `json:"currency"`
+	Records  int              `json:"records"`
+
+	// Duplicates is the number of the records with the same ID which were skipped
+	Duplicates int `json:"duplicates,omitempty"`
+
+	Debit  billing.Money `json:"debit"`
+	Credit billing.Money `json:"credit"`
+
+	// Totals of the entry kinds (debit is positive)
+	Advertiser       billing.Money `json:"advertiser"`
+	Publisher        billing.Money `json:"publisher"`
+	Commission       billing.Money `json:"commission"`
+	SourceCorrection billing.Money `json:"source_correction"`
+	TargetCorrection billing.Money `json:"target_correction"`
+
+	// IDs of the records where the debit is not equal to the credit
+	// or the entries disagree with the prices of the record
+	Unbalanced []string `json:"unbalanced,omitempty"`
+}
+
+// Difference between the debit and the credit
+func (r *DayReport) Difference() billing.Money {
+	return r.Debit - r.Credit
+}
+
+// Balanced returns true if the debit of the day is equal to the credit
+// and all the records are balanced
+func (r *DayReport) Balanced() bool {
+	return r.Debit == r.Credit && len(r.Unbalanced) == 0
+}
+
+func (r *DayReport) add(record *Record) {
+	r.Records++
+	for _, entry := range record.Entries {
+		if entry.Side == Debit {
+			r.Debit += entry.Amount
+		} else {
+			r.Credit += entry.Amount
+		}
+		switch entry.Kind {
+		case KindAdvertiser:
+			r.Advertiser += entry.Signed()
+		case KindPublisher:
+			r.Publisher += entry.Signed()
+		case KindCommission:
+			r.Commission += entry.Signed()
+		case KindSourceCorrection:
+			r.SourceCorrection += entry.Signed()
+		case KindTargetCorrection:
+			r.TargetCorrection += entry.Signed()
+		}
+	}
+	if !record.Balanced() {
+		r.Unbalanced = append(r.Unbalanced, record.ID)
+	}
+}
+
+type reportKey struct {
+	day      string
+	currency billing.Currency
+}
+
+// Reconciler collects the records and checks the debits against the credits per day.
+// It implements the publisher interface, so it can be connected to the generator
+// directly or subscribed to the stream of the records.
+type Reconciler struct {
+	mx      sync.Mutex
+	reports map[reportKey]*DayReport
+	seen    map[string]string // Record ID -> day
+}
+
+// NewReconciler of the settlement records
+func NewReconciler() *Reconciler {
+	return &Reconciler{
+		reports: map[reportKey]*DayReport{},
+		seen:    map[string]string{},
+	}
+}
+
+// Add the records into the reconciliation
+func (r *Reconciler) Add(records ...*Record) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	for _, record := range records {
+		if record == nil {
+			continue
+		}
+		key := reportKey{day: record.Day, currency: record.Currency}
+		report := r.reports[key]
+		if report == nil {
+			report = &DayReport{Day: record.Day, Currency: record.Currency}
+			r.reports[key] = report
+		}
+		if _, ok := r.seen[record.ID]; ok {
+			report.Duplicates++
+			continue
+		}
+		r.seen[record.ID] = record.Day
+		report.add(record)
+	}
+}
+
+// Publish the records into the reconciliation (*Record or Record messages)
+func (r *Reconciler) Publish(_ context.Context, messages ...any) error {
+	for _, msg := range messages {
+		switch record := msg.(type) {
+		case *Record:
+			r.Add(record)
+		case Record:
+			r.Add(&record)
+		default:
+			return fmt.Errorf("%w: %T", ErrUnsupportedMessage, msg)
+		}
+	}
+	return nil
+}
+
+// Report of the day by the currencies
+func (r *Reconciler) Report(day string) []DayReport {
+	return r.collect(func(key reportKey) bool { return key.day == day })
+}
+
+// Reports of all the days ordered by the day and the currency
+func (r *Reconciler) Reports() []DayReport {
+	return r.collect(func(reportKey) bool { return true })
+}
+
+// Remove the reports and the record IDs of the day
+func (r *Reconciler) Remove(day string) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	for key := range r.reports {
+		if key.day == day {
+			delete(r.reports, key)
+		}
+	}
+	for id, recordDay := range r.seen {
+		if recordDay == day {
+			delete(r.seen, id)
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+/// Internal methods
+///////////////////////////////////////////////////////////////////////////////
+
+func (r *Reconciler) collect(filter func(key reportKey) bool) []DayReport {
+	r.mx.Lock()
+	reports := make([]DayReport, 0, len(r.reports))
+	for key, report := range r.reports {
+		if filter(key) {
+			rep := *report
+			rep.Unbalanced = slices.Clone(report.Unbalanced)
+			reports = append(reports, rep)
+		}
+	}
+	r.mx.Unlock()
+	slices.SortFunc(reports, func(a, b DayReport) int {
+		return cmp.Or(cmp.Compare(a.Day, b.Day), cmp.Compare(a.Currency, b.Currency))
+	})
+	return reports
+}
+
+// Reconcile the records and returns the reports of the days
+func Reconcile(records ...*Record) []DayReport {
+	r := NewReconciler()
+	r.Add(records...)
+	return r.Reports()
+}
+
+var _ nc.Publisher = (*Reconciler)(nil)
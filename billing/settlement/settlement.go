@@ -0,0 +1,383 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+// Package settlement turns the billable events into the double-entry financial records.
+//
+// Each record of the impression, view, click or lead debits the advertiser by the
+// AdvertiserPrice of the action and credits the publisher by the PublisherPrice and
+// the network by the NetworkProfit, split into the discrepancy corrections and the
+// commission (see adtype/prices for the formulas):
+//
+//	gen := settlement.New(settlement.WithPublisher(recordsStream, reconciler))
+//	...
+//	if err := gen.Emit(ctx, events.Click, item); err != nil {
+//		// the record is not delivered
+//	}
+//
+// The Reconciler checks the debits against the credits of the records per day
+// and reports the records where the formulas disagree.
+package settlement
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	nc "github.com/geniusrabbit/notificationcenter/v2"
+
+	"github.com/geniusrabbit/adcorelib/admodels/types"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/adtype/prices"
+	"github.com/geniusrabbit/adcorelib/billing"
+	"github.com/geniusrabbit/adcorelib/eventtraking/events"
+	"github.com/geniusrabbit/adcorelib/rand"
+)
+
+const dayFormat = "2006-01-02"
+
+// Errors of the settlement
+var (
+	ErrNotBillable        = errors.New("[settlement] event is not billable")
+	ErrUnsupportedMessage = errors.New("[settlement] unsupported message")
+)
+
+// Side of the entry
+type Side string
+
+// Entry sides
+const (
+	Debit  Side = "debit"
+	Credit Side = "credit"
+)
+
+// Party of the entry
+type Party string
+
+// Entry parties
+const (
+	PartyAdvertiser Party = "advertiser"
+	PartyPublisher  Party = "publisher"
+	PartyNetwork    Party = "network"
+)
+
+// Kind of the entry
+type Kind string
+
+// Entry kinds
+const (
+	KindAdvertiser       Kind = "advertiser"        // Charge of the advertiser
+	KindPublisher        Kind = "publisher"         // Revenue of the publisher
+	KindCommission       Kind = "commission"        // Commission share of the network
+	KindSourceCorrection Kind = "source_correction" // Discrepancy correction of the source
+	KindTargetCorrection Kind = "target_correction" // Discrepancy correction of the target
+)
+
+// Entry of the record. The amount is never negative, the negative value
+// of the kind (e.g. the loss of the network on the fixed purchase price)
+// is booked on the opposite side.
+type Entry struct {
+	Kind      Kind          `json:"kind"`
+	Party     Party         `json:"party"`
+	AccountID uint64        `json:"account_id,omitempty"`
+	Side      Side          `json:"side"`
+	Amount    billing.Money `json:"amount"`
+}
+
+// Signed amount of the entry: positive for the debit and negative for the credit
+func (e Entry) Signed() billing.Money {
+	if e.Side == Credit {
+		return -e.Amount
+	}
+	return e.Amount
+}
+
+// Factors used for the calculation of the record
+type Factors struct {
+	prices.StaticFactors
+
+	// Fixed purchase price of the target which overrides the publisher revenue
+	FixedPurchasePrice billing.Money `json:"fixed_purchase_price,omitempty"`
+}
+
+// Record of the billable event
+type Record struct {
+	ID           string             `json:"id"`
+	Time         time.Time          `json:"time"`
+	Day          string             `json:"day"`
+	Event        events.Type        `json:"event"`
+	Action       adtype.Action      `json:"action"`
+	PricingModel types.PricingModel `json:"pricing_model"`
+	ItemID       string             `json:"item_id"`
+	ImpID        string             `json:"imp_id"`
+	SourceID     uint64             `json:"source_id,omitempty"`
+	AccountID    uint64             `json:"account_id,omitempty"` // Advertiser account
+	CampaignID   uint64             `json:"campaign_id,omitempty"`
+	AdID         string             `json:"ad_id,omitempty"`
+	TargetID     uint64             `json:"target_id,omitempty"`
+	PublisherID  uint64             `json:"publisher_id,omitempty"` // Publisher account
+	Currency     billing.Currency   `json:"currency"`
+
+	// Prices of the action
+	Price          billing.Money `json:"price"`           // AdvertiserPrice
+	PublisherPrice billing.Money `json:"publisher_price"` // PublisherPrice
+	NetworkProfit  billing.Money `json:"network_profit"`  // NetworkProfit
+	PotentialPrice billing.Money `json:"potential_price"` // PotentialPrice
+
+	Factors Factors `json:"factors"`
+	Entries []Entry `json:"entries"`
+}
+
+// Debit total of the record
+func (r *Record) Debit() (total billing.Money) {
+	for _, entry := range r.Entries {
+		if entry.Side == Debit {
+			total += entry.Amount
+		}
+	}
+	return total
+}
+
+// Credit total of the record
+func (r *Record) Credit() (total billing.Money) {
+	for _, entry := range r.Entries {
+		if entry.Side == Credit {
+			total += entry.Amount
+		}
+	}
+	return total
+}
+
+// Amount of the entries of the kind (debit is positive)
+func (r *Record) Amount(kind Kind) (total billing.Money) {
+	for _, entry := range r.Entries {
+		if entry.Kind == kind {
+			total += entry.Signed()
+		}
+	}
+	return total
+}
+
+// Balanced returns true if the debit of the record is equal to the credit
+// and the entries of every party are equal to the prices of the record:
+// the advertiser is charged by the Price, the publisher gets the PublisherPrice
+// and the network gets the NetworkProfit.
+func (r *Record) Balanced() bool {
+	network := r.Amount(KindCommission) + r.Amount(KindSourceCorrection) + r.Amount(KindTargetCorrection)
+	return r.Debit() == r.Credit() &&
+		r.Amount(KindAdvertiser) == r.Price &&
+		-r.Amount(KindPublisher) == r.PublisherPrice &&
+		-network == r.NetworkProfit
+}
+
+// Generator of the settlement records
+type Generator struct {
+	publishers []nc.Publisher
+	location   *time.Location
+	now        func() time.Time
+	newID      func() string
+}
+
+// Option of the generator
+type Option func(g *Generator)
+
+// WithPublisher adds the streams of the records
+func WithPublisher(publishers ...nc.Publisher) Option {
+	return func(g *Generator) {
+		g.publishers = append(g.publishers, publishers...)
+	}
+}
+
+// WithLocation sets the timezone of the record days (UTC by default)
+func WithLocation(location *time.Location) Option {
+	return func(g *Generator) {
+		if location != nil {
+			g.location = location
+		}
+	}
+}
+
+// New generator of the settlement records
+func New(opts ...Option) *Generator {
+	g := &Generator{
+		location: time.UTC,
+		now:      time.Now,
+		newID:    rand.UUID,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Record of the event of the response item.
+// Returns ErrNotBillable if the event is not charged by the pricing model of the item.
+func (g *Generator) Record(event events.Type, item adtype.ResponseItem) (*Record, error) {
+	action, ok := eventAction(event)
+	if !ok || item == nil || !chargedAction(item.PricingModel(), action) {
+		return nil, ErrNotBillable
+	}
+	var (
+		scope     = itemPrices{item: item}
+		price     = prices.AdvertiserPrice(scope, item, action)
+		publisher = prices.PublisherPrice(scope, item, action)
+	)
+	if price <= 0 {
+		return nil, ErrNotBillable
+	}
+
+	now := g.now().In(g.location)
+	record := &Record{
+		ID:             g.newID(),
+		Time:           now,
+		Day:            now.Format(dayFormat),
+		Event:          event,
+		Action:         action,
+		PricingModel:   item.PricingModel(),
+		ItemID:         item.ID(),
+		ImpID:          item.ImpressionID(),
+		AccountID:      item.AccountID(),
+		CampaignID:     item.CampaignID(),
+		AdID:           item.AdID(),
+		Currency:       billing.DefaultCurrency,
+		Price:          price,
+		PublisherPrice: publisher,
+		NetworkProfit:  prices.NetworkProfit(scope, item, action),
+		PotentialPrice: prices.PotentialPrice(scope, action),
+		Factors: Factors{StaticFactors: prices.StaticFactors{
+			Commission: item.CommissionShareFactor(),
+			Source:     item.SourceCorrectionFactor(),
+			Target:     item.TargetCorrectionFactor(),
+		}},
+	}
+	if src := item.Source(); src != nil {
+		record.SourceID = src.ID()
+	}
+	if imp := item.Impression(); imp != nil {
+		record.TargetID = uint64(imp.TargetID())
+		record.PublisherID = imp.AccountID()
+	}
+	if cur, _ := item.(adtype.ResponseItemCurrency); cur != nil {
+		record.Currency = cur.BaseCurrency()
+	}
+	if pricer, _ := item.(prices.FixedPurchasePricer); pricer != nil {
+		record.Factors.FixedPurchasePrice = max(pricer.FixedPurchasePrice(action), 0)
+	}
+
+	// The corrections are calculated by the same calculator step by step,
+	// the commission is the rest of the network profit. If the profit does not
+	// cover the difference between the advertiser and the publisher prices
+	// the record is not balanced and it is reported by the reconciliation.
+	var (
+		afterSource = prices.PublisherPrice(scope,
+			prices.StaticFactors{Source: record.Factors.Source}, action)
+		afterTarget = prices.PublisherPrice(scope,
+			prices.StaticFactors{Source: record.Factors.Source, Target: record.Factors.Target}, action)
+		sourceCorrection = price - afterSource
+		targetCorrection = afterSource - afterTarget
+	)
+	record.Entries = make([]Entry, 0, 5)
+	record.addEntry(KindAdvertiser, PartyAdvertiser, record.AccountID, Debit, price)
+	record.addEntry(KindPublisher, PartyPublisher, record.PublisherID, Credit, publisher)
+	record.addEntry(KindCommission, PartyNetwork, 0, Credit, record.NetworkProfit-sourceCorrection-targetCorrection)
+	record.addEntry(KindSourceCorrection, PartyNetwork, 0, Credit, sourceCorrection)
+	record.addEntry(KindTargetCorrection, PartyNetwork, 0, Credit, targetCorrection)
+	return record, nil
+}
+
+// Emit the record of the event to the streams.
+// The events which are not billable are skipped.
+func (g *Generator) Emit(ctx context.Context, event events.Type, item adtype.ResponseItem) error {
+	record, err := g.Record(event, item)
+	if errors.Is(err, ErrNotBillable) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, publisher := range g.publishers {
+		if err := publisher.Publish(ctx, record); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+/// Internal methods
+///////////////////////////////////////////////////////////////////////////////
+
+func (r *Record) addEntry(kind Kind, party Party, accountID uint64, side Side, amount billing.Money) {
+	if amount == 0 && kind != KindAdvertiser && kind != KindPublisher {
+		return
+	}
+	if amount < 0 {
+		amount = -amount
+		if side == Debit {
+			side = Credit
+		} else {
+			side = Debit
+		}
+	}
+	r.Entries = append(r.Entries, Entry{
+		Kind:      kind,
+		Party:     party,
+		AccountID: accountID,
+		Side:      side,
+		Amount:    amount,
+	})
+}
+
+// itemPrices adapts the response item to the prices.PriceProvider
+type itemPrices struct {
+	item adtype.ResponseItem
+}
+
+func (p itemPrices) PricePerAction(action adtype.Action) billing.Money {
+	return p.item.Price(action)
+}
+
+func (p itemPrices) MaxPricePerAction(action adtype.Action) billing.Money {
+	return p.item.PotentialPrice(action)
+}
+
+var _ prices.PriceProvider = itemPrices{}
+
+///////////////////////////////////////////////////////////////////////////////
+/// Helpers
+///////////////////////////////////////////////////////////////////////////////
+
+// eventAction returns the action of the billable event
+func eventAction(event events.Type) (adtype.Action, bool) {
+	switch event {
+	case events.Impression:
+		return adtype.ActionImpression, true
+	case events.Direct:
+		return adtype.ActionDirect, true
+	case events.View:
+		return adtype.ActionView, true
+	case events.Click:
+		return adtype.ActionClick, true
+	case events.Lead:
+		return adtype.ActionLead, true
+	}
+	return 0, false
+}
+
+// chargedAction returns true if the action is charged by the pricing model.
+// The undefined model charges any action which has the price.
+func chargedAction(model types.PricingModel, action adtype.Action) bool {
+	switch model {
+	case types.PricingModelCPM:
+		return action == adtype.ActionImpression || action == adtype.ActionDirect
+	case types.PricingModelCPMV:
+		return action == adtype.ActionView
+	case types.PricingModelCPC:
+		return action == adtype.ActionClick
+	case types.PricingModelCPA:
+		return action == adtype.ActionLead
+	}
+	return true
+}
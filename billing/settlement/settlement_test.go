@@ -0,0 +1,184 @@
+package settlement
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/geniusrabbit/adcorelib/admodels/types"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/adtype/prices"
+	"github.com/geniusrabbit/adcorelib/billing"
+	"github.com/geniusrabbit/adcorelib/eventtraking/events"
+)
+
+type testItem struct {
+	*adtype.ResponseItemEmpty
+	id      string
+	model   types.PricingModel
+	scope   prices.PriceScope
+	factors prices.StaticFactors
+	fixed   billing.Money
+}
+
+func (it *testItem) ID() string                          { return it.id }
+func (it *testItem) AccountID() uint64                   { return 7 }
+func (it *testItem) PricingModel() types.PricingModel    { return it.model }
+func (it *testItem) Price(a adtype.Action) billing.Money { return it.scope.PricePerAction(a) }
+func (it *testItem) PotentialPrice(a adtype.Action) billing.Money {
+	return it.scope.MaxPricePerAction(a)
+}
+func (it *testItem) CommissionShareFactor() float64  { return it.factors.Commission }
+func (it *testItem) SourceCorrectionFactor() float64 { return it.factors.Source }
+func (it *testItem) TargetCorrectionFactor() float64 { return it.factors.Target }
+func (it *testItem) FixedPurchasePrice(adtype.Action) billing.Money {
+	return it.fixed
+}
+
+func newTestItem(id string, model types.PricingModel, factors prices.StaticFactors) *testItem {
+	it := &testItem{ResponseItemEmpty: &adtype.ResponseItemEmpty{}, id: id, model: model, factors: factors}
+	it.scope.BidCPC, it.scope.MaxBidCPC = billing.MoneyFloat(0.5), billing.MoneyFloat(0.6)
+	it.scope.BidCPM, it.scope.MaxBidCPM = billing.MoneyInt(2), billing.MoneyInt(3)
+	return it
+}
+
+func TestGeneratorRecord(t *testing.T) {
+	gen := New()
+	gen.now = func() time.Time { return time.Date(2026, 3, 1, 23, 30, 0, 0, time.UTC) }
+	factors := prices.StaticFactors{Commission: 0.2, Source: 0.1, Target: 0.05}
+
+	record, err := gen.Record(events.Click, newTestItem("a", types.PricingModelCPC, factors))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "2026-03-01", record.Day)
+	assert.Equal(t, adtype.ActionClick, record.Action)
+	assert.Equal(t, billing.DefaultCurrency, record.Currency)
+	assert.Equal(t, factors, record.Factors.StaticFactors)
+	assert.Equal(t, billing.MoneyFloat(0.5), record.Price)
+	assert.Equal(t, billing.MoneyFloat(0.6), record.PotentialPrice)
+	assert.Equal(t, billing.MoneyFloat(0.5), record.Debit())
+	assert.True(t, record.Balanced())
+	assert.Equal(t, billing.MoneyFloat(0.5*0.9*0.95*0.8), -record.Amount(KindPublisher))
+	assert.Equal(t, billing.MoneyFloat(0.05), -record.Amount(KindSourceCorrection))
+	assert.Equal(t, billing.MoneyFloat(0.5*0.9*0.05), -record.Amount(KindTargetCorrection))
+	assert.Equal(t, record.NetworkProfit, -record.Amount(KindCommission)-record.Amount(KindSourceCorrection)-record.Amount(KindTargetCorrection))
+	assert.Len(t, record.Entries, 5)
+
+	// The impression price of the CPM is the CPM divided by 1000
+	record, err = gen.Record(events.Impression, newTestItem("b", types.PricingModelCPM, prices.StaticFactors{}))
+	if assert.NoError(t, err) {
+		assert.Equal(t, billing.MoneyFloat(0.002), record.Price)
+		assert.Len(t, record.Entries, 2)
+		assert.True(t, record.Balanced())
+	}
+
+	// The actions which are not charged by the pricing model
+	_, err = gen.Record(events.Impression, newTestItem("c", types.PricingModelCPC, factors))
+	assert.ErrorIs(t, err, ErrNotBillable)
+	_, err = gen.Record(events.SourceWin, newTestItem("c", types.PricingModelCPC, factors))
+	assert.ErrorIs(t, err, ErrNotBillable)
+	_, err = gen.Record(events.Lead, newTestItem("c", types.PricingModelCPA, factors))
+	assert.ErrorIs(t, err, ErrNotBillable)
+}
+
+func TestGeneratorFixedPurchaseLoss(t *testing.T) {
+	item := newTestItem("a", types.PricingModelCPC, prices.StaticFactors{Commission: 0.1})
+	item.fixed = billing.MoneyFloat(0.7)
+
+	record, err := New().Record(events.Click, item)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, billing.MoneyFloat(0.7), record.Factors.FixedPurchasePrice)
+	assert.Equal(t, billing.MoneyFloat(0.7), record.PublisherPrice)
+	assert.Equal(t, billing.MoneyFloat(0.2), record.Amount(KindCommission)) // Loss is booked as the debit of the network
+	assert.Equal(t, billing.MoneyFloat(0.7), record.Debit())
+	assert.True(t, record.Balanced())
+}
+
+func TestReconciler(t *testing.T) {
+	var (
+		ctx        = context.Background()
+		reconciler = NewReconciler()
+		gen        = New(WithPublisher(reconciler))
+		factors    = prices.StaticFactors{Commission: 0.3, Source: 0.1}
+		day        = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	)
+	gen.now = func() time.Time { return day }
+	assert.NoError(t, gen.Emit(ctx, events.Click, newTestItem("a", types.PricingModelCPC, factors)))
+	assert.NoError(t, gen.Emit(ctx, events.Impression, newTestItem("b", types.PricingModelCPM, factors)))
+	assert.NoError(t, gen.Emit(ctx, events.View, newTestItem("c", types.PricingModelCPM, factors)))
+
+	gen.now = func() time.Time { return day.Add(24 * time.Hour) }
+	record, _ := gen.Record(events.Click, newTestItem("d", types.PricingModelCPC, factors))
+	assert.NoError(t, reconciler.Publish(ctx, record, *record))
+	assert.ErrorIs(t, reconciler.Publish(ctx, "record"), ErrUnsupportedMessage)
+
+	// The broken record is reported
+	broken := *record
+	broken.ID = "broken"
+	broken.Entries = broken.Entries[:len(broken.Entries)-1]
+	reconciler.Add(&broken)
+
+	reports := reconciler.Reports()
+	if !assert.Len(t, reports, 2) {
+		return
+	}
+	assert.Equal(t, "2026-03-01", reports[0].Day)
+	assert.Equal(t, 2, reports[0].Records)
+	assert.True(t, reports[0].Balanced())
+	assert.Equal(t, billing.MoneyFloat(0.502), reports[0].Advertiser)
+	assert.Equal(t, reports[0].Debit, reports[0].Credit)
+	assert.Equal(t, reports[0].Advertiser, -(reports[0].Publisher + reports[0].Commission + reports[0].SourceCorrection))
+
+	assert.Equal(t, "2026-03-02", reports[1].Day)
+	assert.Equal(t, 2, reports[1].Records)
+	assert.Equal(t, 1, reports[1].Duplicates)
+	assert.False(t, reports[1].Balanced())
+	assert.Equal(t, []string{"broken"}, reports[1].Unbalanced)
+	assert.NotZero(t, reports[1].Difference())
+
+	reconciler.Remove("2026-03-02")
+	assert.Len(t, reconciler.Report("2026-03-02"), 0)
+	assert.Len(t, Reconcile(record), 1)
+}
+
+func TestRecordUnbalanced(t *testing.T) {
+	record, err := New().Record(events.Click,
+		newTestItem("a", types.PricingModelCPC, prices.StaticFactors{Commission: 0.2, Source: 0.1}))
+	if !assert.NoError(t, err) || !assert.True(t, record.Balanced()) {
+		return
+	}
+
+	// The network profit disagrees with the prices of the record
+	profit := *record
+	profit.ID = "profit"
+	profit.NetworkProfit += billing.MoneyFloat(0.01)
+	assert.Equal(t, profit.Debit(), profit.Credit())
+	assert.False(t, profit.Balanced())
+
+	// The entries are moved from the publisher to the network
+	entries := *record
+	entries.ID = "entries"
+	entries.Entries = append([]Entry(nil), record.Entries...)
+	for i := range entries.Entries {
+		switch entries.Entries[i].Kind {
+		case KindPublisher:
+			entries.Entries[i].Amount -= billing.MoneyFloat(0.01)
+		case KindCommission:
+			entries.Entries[i].Amount += billing.MoneyFloat(0.01)
+		}
+	}
+	assert.Equal(t, entries.Debit(), entries.Credit())
+	assert.False(t, entries.Balanced())
+
+	reports := Reconcile(record, &profit, &entries)
+	if assert.Len(t, reports, 1) {
+		assert.Equal(t, reports[0].Debit, reports[0].Credit)
+		assert.False(t, reports[0].Balanced())
+		assert.Equal(t, []string{"profit", "entries"}, reports[0].Unbalanced)
+	}
+}
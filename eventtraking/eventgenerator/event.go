@@ -2,6 +2,7 @@ package eventgenerator
 
 import (
 	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
 	"github.com/geniusrabbit/adcorelib/eventtraking/events"
 )
 
@@ -41,6 +42,19 @@ type EventType interface {
 	Fill(service string, event events.Type, status uint8, response adtype.Response, it adtype.ResponseItem) error
 }
 
+// ExperimentEventType is the optional interface of the event which keeps
+// the experiment arm of the request (see adtype.ExperimentArmFromRequest)
+type ExperimentEventType interface {
+	SetExperimentArm(experimentID, armID string)
+}
+
+// CurrencyEventType is the optional interface of the event which keeps
+// the original bid of the external platform in its currency and the base
+// currency of the event prices (see adtype.ResponseItemCurrency)
+type CurrencyEventType interface {
+	SetCurrencies(original billing.Amount, base billing.Currency)
+}
+
 // LeadType object for lead basic type interface
 type LeadType interface {
 	// String returns string representation of object
@@ -68,7 +82,12 @@ type UserInfoType interface {
 }
 
 // TestEvent object for testing
-type TestEvent struct{}
+type TestEvent struct {
+	ExperimentID string
+	ArmID        string
+	OriginalBid  billing.Amount
+	BaseCurrency billing.Currency
+}
 
 // SetDateTime set date time of event
 func (e *TestEvent) SetDateTime(t int64) {}
@@ -99,7 +118,21 @@ func (e *TestEvent) Fill(service string, event events.Type, status uint8, respon
 	return nil
 }
 
-var _ EventType = &TestEvent{}
+// SetExperimentArm set experiment arm of the event
+func (e *TestEvent) SetExperimentArm(experimentID, armID string) {
+	e.ExperimentID, e.ArmID = experimentID, armID
+}
+
+// SetCurrencies set original bid and base currency of the event
+func (e *TestEvent) SetCurrencies(original billing.Amount, base billing.Currency) {
+	e.OriginalBid, e.BaseCurrency = original, base
+}
+
+var (
+	_ EventType           = &TestEvent{}
+	_ ExperimentEventType = &TestEvent{}
+	_ CurrencyEventType   = &TestEvent{}
+)
 
 // TestLead object for testing
 type TestLead struct{}
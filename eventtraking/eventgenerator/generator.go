@@ -54,6 +54,18 @@ func (g generator[EventT, UserInfoT]) Event(event events.Type, status uint8, res
 	if err := eventObj.Fill(g.service, event, status, response, it); err != nil {
 		return eventObj, err
 	}
+	// Stamp the experiment arm to split the reports by arms
+	if expEvent, ok := any(eventObj).(ExperimentEventType); ok && response != nil {
+		if arm := adtype.ExperimentArmFromRequest(response.Request()); arm != nil {
+			expEvent.SetExperimentArm(arm.ExperimentID, arm.ArmID)
+		}
+	}
+	// Keep the original bid of the external platform currency
+	if curEvent, ok := any(eventObj).(CurrencyEventType); ok {
+		if curItem, _ := it.(adtype.ResponseItemCurrency); curItem != nil {
+			curEvent.SetCurrencies(curItem.OriginalBidCPM(), curItem.BaseCurrency())
+		}
+	}
 	return eventObj, nil
 }
 
@@ -0,0 +1,79 @@
+package eventgenerator
+
+import (
+	"testing"
+
+	"github.com/geniusrabbit/adcorelib/adquery/bidrequest"
+	"github.com/geniusrabbit/adcorelib/adquery/bidresponse"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
+	"github.com/geniusrabbit/adcorelib/eventtraking/events"
+)
+
+type currencyItem struct {
+	*bidresponse.ResponseItemBlank
+}
+
+func (it currencyItem) OriginalBidCPM() billing.Amount {
+	return billing.AmountOf(billing.MoneyFloat(0.8), "EUR")
+}
+
+func (it currencyItem) BaseCurrency() billing.Currency { return "USD" }
+
+func newTestGenerator() Generator[*TestEvent, *TestUserInfo] {
+	return New("test",
+		func() *TestEvent { return &TestEvent{} },
+		func() *TestUserInfo { return &TestUserInfo{} })
+}
+
+func TestGeneratorExperimentArm(t *testing.T) {
+	var (
+		gen     = newTestGenerator()
+		request = &bidrequest.BidRequest{}
+		item    = &bidresponse.ResponseItemBlank{}
+	)
+
+	event, err := gen.Event(events.Impression, events.StatusSuccess,
+		bidresponse.NewResponse(request, nil, []adtype.ResponseItemCommon{item}, nil), item)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event.ExperimentID != "" || event.ArmID != "" {
+		t.Fatalf("arm of the request without experiment: %s/%s", event.ExperimentID, event.ArmID)
+	}
+
+	request.Set(adtype.ExtExperimentArm, &adtype.ExperimentArm{ExperimentID: "floors", ArmID: "b"})
+	event, err = gen.Event(events.Impression, events.StatusSuccess,
+		bidresponse.NewResponse(request, nil, []adtype.ResponseItemCommon{item}, nil), item)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event.ExperimentID != "floors" || event.ArmID != "b" {
+		t.Fatalf("invalid arm of the event: %s/%s", event.ExperimentID, event.ArmID)
+	}
+}
+
+func TestGeneratorCurrencies(t *testing.T) {
+	var (
+		gen      = newTestGenerator()
+		request  = &bidrequest.BidRequest{}
+		item     = currencyItem{ResponseItemBlank: &bidresponse.ResponseItemBlank{}}
+		response = bidresponse.NewResponse(request, nil, []adtype.ResponseItemCommon{item}, nil)
+	)
+
+	event, err := gen.Event(events.Impression, events.StatusSuccess, response, item)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event.OriginalBid != billing.AmountOf(billing.MoneyFloat(0.8), "EUR") || event.BaseCurrency != "USD" {
+		t.Fatalf("invalid currencies of the event: %v %s", event.OriginalBid, event.BaseCurrency)
+	}
+
+	event, err = gen.Event(events.Impression, events.StatusSuccess, response, item.ResponseItemBlank)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event.BaseCurrency != "" {
+		t.Fatalf("currency of the item without currencies: %s", event.BaseCurrency)
+	}
+}
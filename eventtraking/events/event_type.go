@@ -20,11 +20,14 @@ const (
 	SourceWin   Type = "src.win"
 	SourceFail  Type = "src.fail"
 	SourceSkip  Type = "src.skip"
+	// SourceShadowWin is the bid of the shadow source which would have won the auction
+	SourceShadowWin Type = "src.shadow.win"
 	// Access Point types
 	AccessPointNoBid         Type = "ap.nobid"
 	AccessPointBid           Type = "ap.bid"
 	AccessPointWin           Type = "ap.win"
 	AccessPointBillingNotice Type = "ap.bin"
+	AccessPointLoss          Type = "ap.loss"
 	AccessPointFail          Type = "ap.fail"
 	AccessPointSkip          Type = "ap.skip"
 )
@@ -36,4 +39,5 @@ const (
 	StatusFailed      = 2
 	StatusCompromised = 3
 	StatusCustom      = 4 // User code
+	StatusLate        = 5 // Response received after the end of the auction
 )
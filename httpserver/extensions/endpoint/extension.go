@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/demdxx/gocast/v2"
 	"github.com/demdxx/xtypes"
@@ -17,6 +18,7 @@ import (
 
 	"github.com/geniusrabbit/adcorelib/admodels"
 	"github.com/geniusrabbit/adcorelib/admodels/types"
+	"github.com/geniusrabbit/adcorelib/adsource/auctiontrace"
 	"github.com/geniusrabbit/adcorelib/adtype"
 	"github.com/geniusrabbit/adcorelib/context/ctxlogger"
 	"github.com/geniusrabbit/adcorelib/httpserver/wrappers/httphandler"
@@ -45,6 +47,12 @@ type (
 	sourceMetricsAccessor interface {
 		Metrics() *openlatency.MetricsInfo
 	}
+	sourceTimeoutAccessor interface {
+		SourceTimeout(sourceID uint64) time.Duration
+	}
+	sourceStatsAccessor interface {
+		FillSourceMetrics(sourceID uint64, info *openlatency.MetricsInfo)
+	}
 )
 
 // Extension of the server
@@ -73,6 +81,9 @@ type Extension struct {
 	// List of endpoints of classic executors
 	endpoints []Endpoint
 
+	// Checks if the request is trusted to receive the auction trace
+	traceTrust func(req *fasthttp.RequestCtx) bool
+
 	// Metrics
 	adRequestCountMetrics *prometheus.CounterVec
 }
@@ -171,8 +182,18 @@ func (ext *Extension) endpointRequestHandler(ctx context.Context, req *fasthttp.
 		b2sbool(bidRequest.IsProxy()),
 	).Inc()
 
+	// Collect the auction trace of the trusted request only
+	var trace *auctiontrace.Trace
+	if ext.isTraceTrusted(req) {
+		trace = auctiontrace.Start(bidRequest)
+	}
+
 	response := endpoint.Handle(ext.source, bidRequest)
 	ext.source.ProcessResponse(response)
+
+	if trace != nil {
+		writeAuctionTrace(req, trace)
+	}
 }
 
 func (ext *Extension) factoryListHandler(fa factoryListAccessor) fasthttp.RequestHandler {
@@ -250,10 +271,21 @@ func (ext *Extension) sourceMetricsHandler(sa adtype.SourceAccessor) fasthttp.Re
 			_, _ = ctx.Write([]byte(`{"error":"source not found"}`))
 			return
 		}
-		var metrics any
+		var metrics *openlatency.MetricsInfo
 		if sm, ok := src.(sourceMetricsAccessor); ok {
 			metrics = sm.Metrics()
 		}
+		if metrics == nil {
+			metrics = &openlatency.MetricsInfo{ID: src.ID(), Protocol: src.Protocol()}
+		}
+		// Rates and latency of the source observed by the wrapper
+		if sa, ok := ext.source.(sourceStatsAccessor); ok {
+			sa.FillSourceMetrics(src.ID(), metrics)
+		}
+		// Per-source timeout computed by the wrapper from the observed latency
+		if ta, ok := ext.source.(sourceTimeoutAccessor); ok {
+			metrics.Timeout = ta.SourceTimeout(src.ID()).Milliseconds()
+		}
 		ctx.SetContentType("application/json")
 		ctx.SetStatusCode(http.StatusOK)
 		_ = json.NewEncoder(ctx).Encode(metrics)
@@ -264,6 +296,31 @@ func (ext *Extension) sourceMetricsHandler(sa adtype.SourceAccessor) fasthttp.Re
 /// Helpers
 ///////////////////////////////////////////////////////////////////////////////
 
+// isTraceTrusted returns true if the auction trace is enabled for the request
+func (ext *Extension) isTraceTrusted(req *fasthttp.RequestCtx) bool {
+	return ext.traceTrust != nil && ext.traceTrust(req)
+}
+
+// writeAuctionTrace replaces the endpoint response by the JSON with the auction trace.
+// The original response is kept as the string in the "response" field.
+func writeAuctionTrace(req *fasthttp.RequestCtx, trace *auctiontrace.Trace) {
+	var (
+		statusCode  = req.Response.StatusCode()
+		contentType = string(req.Response.Header.ContentType())
+		body        = string(req.Response.Body())
+	)
+	req.Response.ResetBody()
+	req.Response.Header.Del("Location")
+	req.SetContentType("application/json")
+	req.SetStatusCode(http.StatusOK)
+	_ = json.NewEncoder(req).Encode(map[string]any{
+		"trace":        trace,
+		"status_code":  statusCode,
+		"content_type": contentType,
+		"response":     body,
+	})
+}
+
 func (ext *Extension) requestByHTTPRequest(ctx context.Context, person personification.Person, rctx *fasthttp.RequestCtx) adtype.BidRequester {
 	var (
 		app       *admodels.Application
@@ -1,6 +1,8 @@
 package endpoint
 
 import (
+	"github.com/valyala/fasthttp"
+
 	"github.com/geniusrabbit/adcorelib/admodels/types"
 	"github.com/geniusrabbit/adcorelib/httpserver/wrappers/httphandler"
 	"github.com/geniusrabbit/adcorelib/net/fasthttp/middleware"
@@ -69,3 +71,11 @@ func WithSendpoints(endpoints ...Endpoint) Option {
 		}
 	}
 }
+
+// WithAuctionTrace enables the auction trace in the response of the trusted requests
+// (e.g. by the token or the network). The trace is disabled by default.
+func WithAuctionTrace(trust func(req *fasthttp.RequestCtx) bool) Option {
+	return func(ext *Extension) {
+		ext.traceTrust = trust
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
 
 	"github.com/geniusrabbit/adcorelib/admodels"
 	"github.com/geniusrabbit/adcorelib/adtype"
@@ -39,3 +40,20 @@ func Test_Options(t *testing.T) {
 	assert.True(t, server.handlerWrapper != nil, "invalid handlerWrapper initialisation")
 	assert.True(t, server.zoneAccessor != nil, "invalid zoneAccessor initialisation")
 }
+
+func Test_OptionAuctionTrace(t *testing.T) {
+	var (
+		ext     = &Extension{}
+		request = &fasthttp.RequestCtx{}
+	)
+	request.Request.SetRequestURI("/b/direct/zone?debug=1")
+	assert.False(t, ext.isTraceTrusted(request), "the trace must be disabled by default")
+
+	WithAuctionTrace(func(req *fasthttp.RequestCtx) bool {
+		return string(req.Request.Header.Peek("X-Trace-Token")) == "secret"
+	})(ext)
+	assert.False(t, ext.isTraceTrusted(request), "the debug flag must not enable the trace")
+
+	request.Request.Header.Set("X-Trace-Token", "secret")
+	assert.True(t, ext.isTraceTrusted(request), "the trace of the trusted request")
+}
@@ -0,0 +1,212 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	openrtb2 "github.com/bsm/openrtb/v3"
+	"github.com/demdxx/gocast/v2"
+	"github.com/fasthttp/router"
+	"github.com/opentracing/opentracing-go"
+	"github.com/valyala/fasthttp"
+	"go.uber.org/zap"
+
+	"github.com/geniusrabbit/adcorelib/admodels/types"
+	"github.com/geniusrabbit/adcorelib/adquery/bidresponse"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/context/ctxlogger"
+	"github.com/geniusrabbit/adcorelib/eventtraking/eventstream"
+	"github.com/geniusrabbit/adcorelib/httpserver/wrappers/httphandler"
+	"github.com/geniusrabbit/adcorelib/httpserver/wrappers/httptraceroute"
+)
+
+const defaultURLQueryPattern = "/ortb/{accesspoint}"
+
+// Errors of the extension configuration
+var (
+	ErrSourceRequired       = errors.New("[exchange] advertisement source is required")
+	ErrURLGeneratorRequired = errors.New("[exchange] URL generator is required")
+)
+
+type (
+	accessPointAccessor interface {
+		AccessPointByID(ctx context.Context, id uint64) (adtype.AccessPoint, error)
+	}
+	zoneAccessor interface {
+		TargetByCodename(context.Context, string) (adtype.Target, error)
+	}
+)
+
+// Source of the advertisement
+type Source interface {
+	// Bid request for standart system filter
+	Bid(request adtype.BidRequester) adtype.Response
+
+	// ProcessResponse when need to fix the result and process all counters
+	ProcessResponse(response adtype.Response)
+}
+
+// Extension of the server which accepts OpenRTB 2.x bid requests
+// from the external exchanges and responds with the OpenRTB bid response
+type Extension struct {
+	// Source of the advertisement
+	source Source
+
+	// Wrapper of extended handler to default
+	handlerWrapper *httphandler.HTTPHandlerWrapper
+
+	// Format accessor
+	formatAccessor types.FormatsAccessor
+
+	// Access point data accessor
+	accessPointAccessor accessPointAccessor
+
+	// Zone data accessor (maps the `tagid` of impression to the target)
+	zoneAccessor zoneAccessor
+
+	// URL generator for the notification URLs
+	urlGenerator adtype.URLGenerator
+
+	// Event stream for the access point events
+	eventStream eventstream.Stream
+
+	// Name of the query parameter which receives the `${AUCTION_PRICE}` macro
+	// in the win and billing notice URLs. Empty means no price macro.
+	priceParam string
+
+	// URL query pattern like `/ortb/{accesspoint}` by default
+	URLQueryPattern string
+}
+
+// NewExtension with options.
+// The advertisement source and the URL generator of the notification URLs are required.
+func NewExtension(opts ...Option) (*Extension, error) {
+	ext := &Extension{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(ext)
+		}
+	}
+	if ext.source == nil {
+		return nil, ErrSourceRequired
+	}
+	if ext.urlGenerator == nil {
+		return nil, ErrURLGeneratorRequired
+	}
+	return ext, nil
+}
+
+// InitRouter of the HTTP server
+func (ext *Extension) InitRouter(ctx context.Context, router *router.Router, tracer opentracing.Tracer) {
+	routeWrapper := httptraceroute.Wrap(router, tracer)
+	routeWrapper.POST(gocast.Or(ext.URLQueryPattern, defaultURLQueryPattern),
+		ext.handlerWrapper.Metrics("exchange", ext.bidRequestHandler))
+}
+
+func (ext *Extension) bidRequestHandler(ctx context.Context, rctx *fasthttp.RequestCtx) {
+	accessPoint := ext.accessPoint(ctx, rctx)
+	if accessPoint == nil {
+		rctx.SetStatusCode(http.StatusNotFound)
+		return
+	}
+
+	var rtbRequest openrtb2.BidRequest
+	err := json.Unmarshal(rctx.PostBody(), &rtbRequest)
+	if err == nil {
+		err = rtbRequest.Validate()
+	}
+	if err != nil {
+		ctxlogger.Get(ctx).Debug("Invalid OpenRTB request", zap.Error(err))
+		rctx.SetStatusCode(http.StatusBadRequest)
+		return
+	}
+
+	if rtbRequest.TimeMax > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(rtbRequest.TimeMax)*time.Millisecond)
+		defer cancel()
+	}
+
+	request := ext.newBidRequest(ctx, rctx, accessPoint, &rtbRequest)
+
+	// None of the impressions can be served by the system
+	if len(request.Imps) == 0 {
+		response := bidresponse.NewEmptyResponse(request, nil, adtype.ErrResponseSkipped)
+		ext.sendEvents(ctx, response, nil)
+		ext.writeNoBid(rctx)
+		return
+	}
+
+	var bidRequest adtype.BidRequester = request
+	if ext.formatAccessor != nil {
+		bidRequest = request.WithFormats(ext.formatAccessor)
+	}
+
+	response := ext.source.Bid(bidRequest)
+	if response == nil {
+		response = bidresponse.NewEmptyResponse(bidRequest, nil, adtype.ErrResponseNoBid)
+	}
+
+	bidResponse, items := ext.newBidResponse(ctx, &rtbRequest, response)
+	ext.sendEvents(ctx, response, items)
+	if bidResponse == nil {
+		ext.writeNoBid(rctx)
+	} else {
+		rctx.SetContentType("application/json")
+		rctx.SetStatusCode(http.StatusOK)
+		if err := json.NewEncoder(rctx).Encode(bidResponse); err != nil {
+			ctxlogger.Get(ctx).Error("Encode OpenRTB response", zap.Error(err))
+		}
+	}
+
+	ext.source.ProcessResponse(response)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+/// Internal methods
+///////////////////////////////////////////////////////////////////////////////
+
+func (ext *Extension) accessPoint(ctx context.Context, rctx *fasthttp.RequestCtx) adtype.AccessPoint {
+	if ext.accessPointAccessor == nil {
+		return nil
+	}
+	accessPoint, err := ext.accessPointAccessor.AccessPointByID(ctx, gocast.Uint64(rctx.UserValue("accesspoint")))
+	if err != nil {
+		ctxlogger.Get(ctx).Debug("Access point not found", zap.Error(err))
+		return nil
+	}
+	return accessPoint
+}
+
+func (ext *Extension) writeNoBid(rctx *fasthttp.RequestCtx) {
+	rctx.SetStatusCode(http.StatusNoContent)
+}
+
+// sendEvents of the access point by the response state
+func (ext *Extension) sendEvents(ctx context.Context, response adtype.Response, items []adtype.ResponseItem) {
+	if ext.eventStream == nil {
+		return
+	}
+	var err error
+	switch {
+	case len(items) > 0:
+		err = ext.eventStream.SendAccessPointBid(response, items...)
+	case response.Error() == nil, errors.Is(response.Error(), adtype.ErrResponseNoBid):
+		err = ext.eventStream.SendAccessPointNoBid(response)
+	case errors.Is(response.Error(), adtype.ErrResponseSkipped):
+		err = ext.eventStream.SendAccessPointSkip(response)
+	default:
+		err = ext.eventStream.SendAccessPointFail(response)
+	}
+	if err != nil {
+		ctxlogger.Get(ctx).Error("Send access point event", zap.Error(err))
+	}
+}
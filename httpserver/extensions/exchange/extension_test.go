@@ -0,0 +1,350 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	openrtb2 "github.com/bsm/openrtb/v3"
+	natresp "github.com/bsm/openrtb/v3/native/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+
+	"github.com/geniusrabbit/adcorelib/admodels"
+	"github.com/geniusrabbit/adcorelib/admodels/types"
+	"github.com/geniusrabbit/adcorelib/adquery/bidresponse"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
+	"github.com/geniusrabbit/adcorelib/eventtraking/events"
+)
+
+type testAccessPoint struct{}
+
+func (testAccessPoint) ID() uint64                       { return 1 }
+func (testAccessPoint) AccountID() uint64                { return 1 }
+func (testAccessPoint) PricingModel() types.PricingModel { return types.PricingModelCPM }
+
+type testAccessPointAccessor struct{}
+
+func (testAccessPointAccessor) AccessPointByID(_ context.Context, id uint64) (adtype.AccessPoint, error) {
+	if id != 1 {
+		return nil, errors.New("not found")
+	}
+	return testAccessPoint{}, nil
+}
+
+type testZoneAccessor struct{}
+
+func (testZoneAccessor) TargetByCodename(_ context.Context, codename string) (adtype.Target, error) {
+	if codename != "zone1" {
+		return nil, errors.New("not found")
+	}
+	return &adtype.TargetEmpty{}, nil
+}
+
+type testItem struct {
+	*bidresponse.ResponseItemBlank
+	content map[string]string
+	assets  admodels.AdFileAssets
+	price   billing.Money
+}
+
+func (it *testItem) ContentItemString(name string) string { return it.content[name] }
+func (it *testItem) MainAsset() *admodels.AdFileAsset     { return it.assets.Main() }
+func (it *testItem) Assets() admodels.AdFileAssets        { return it.assets }
+func (it *testItem) Width() int                           { return 300 }
+func (it *testItem) Height() int                          { return 250 }
+func (it *testItem) PurchasePrice(action adtype.Action) billing.Money {
+	return it.price
+}
+
+type testSource struct {
+	check func(request adtype.BidRequester)
+	items func(request adtype.BidRequester) []adtype.ResponseItemCommon
+}
+
+func (s *testSource) Bid(request adtype.BidRequester) adtype.Response {
+	if s.check != nil {
+		s.check(request)
+	}
+	if s.items == nil {
+		return bidresponse.NewEmptyResponse(request, nil, adtype.ErrResponseNoBid)
+	}
+	return bidresponse.NewResponse(request, nil, s.items(request), nil)
+}
+
+func (s *testSource) ProcessResponse(response adtype.Response) {}
+
+type testURLGenerator struct{}
+
+func (testURLGenerator) CDNURL(path string) string { return "https://cdn/" + path }
+func (testURLGenerator) LibURL(path string) string { return "https://lib/" + path }
+func (testURLGenerator) PixelURL(event events.Type, _ uint8, _ adtype.ResponseItem, _ adtype.Response, _ bool) (string, error) {
+	return "https://px/" + event.String(), nil
+}
+func (testURLGenerator) PixelDirectURL(events.Type, uint8, adtype.ResponseItem, adtype.Response, string) (string, error) {
+	return "", nil
+}
+func (testURLGenerator) PixelLead(adtype.ResponseItem, adtype.Response, bool) (string, error) {
+	return "", nil
+}
+func (testURLGenerator) MustClickURL(adtype.ResponseItem, adtype.Response) string {
+	return "https://click"
+}
+func (testURLGenerator) ClickURL(adtype.ResponseItem, adtype.Response) (string, error) {
+	return "https://click", nil
+}
+func (testURLGenerator) ClickRouterURL() string { return "/click" }
+func (testURLGenerator) DirectURL(events.Type, adtype.ResponseItem, adtype.Response) (string, error) {
+	return "", nil
+}
+func (testURLGenerator) DirectRouterURL() string { return "/direct" }
+func (testURLGenerator) WinURL(events.Type, uint8, adtype.ResponseItem, adtype.Response) (string, error) {
+	return "https://win?e=1", nil
+}
+func (testURLGenerator) BillingNoticeURL(events.Type, uint8, adtype.ResponseItem, adtype.Response) (string, error) {
+	return "https://bin", nil
+}
+func (testURLGenerator) WinRouterURL() string { return "/win" }
+
+type testStream struct{ events []events.Type }
+
+func (s *testStream) SendEvent(context.Context, any) error     { return nil }
+func (s *testStream) SendLeadEvent(context.Context, any) error { return nil }
+func (s *testStream) Send(event events.Type, _ uint8, _ adtype.Response, _ adtype.ResponseItem) error {
+	s.events = append(s.events, event)
+	return nil
+}
+func (s *testStream) SendSourceSkip(adtype.Response) error  { return nil }
+func (s *testStream) SendSourceNoBid(adtype.Response) error { return nil }
+func (s *testStream) SendSourceFail(adtype.Response) error  { return nil }
+func (s *testStream) SendAccessPointBid(response adtype.Response, it ...adtype.ResponseItem) error {
+	for _, item := range it {
+		_ = s.Send(events.AccessPointBid, events.StatusSuccess, response, item)
+	}
+	return nil
+}
+func (s *testStream) SendAccessPointSkip(response adtype.Response) error {
+	return s.Send(events.AccessPointSkip, events.StatusUndefined, response, nil)
+}
+func (s *testStream) SendAccessPointNoBid(response adtype.Response) error {
+	return s.Send(events.AccessPointNoBid, events.StatusUndefined, response, nil)
+}
+func (s *testStream) SendAccessPointFail(response adtype.Response) error {
+	return s.Send(events.AccessPointFail, events.StatusFailed, response, nil)
+}
+
+func newTestItem(request adtype.BidRequester, content map[string]string, assets admodels.AdFileAssets, format types.FormatType) *testItem {
+	return &testItem{
+		ResponseItemBlank: &bidresponse.ResponseItemBlank{
+			ItemID:    "item1",
+			Imp:       request.Impressions()[0],
+			FormatVal: &types.Format{Types: *types.NewFormatTypeBitset(format)},
+		},
+		content: content,
+		assets:  assets,
+		price:   billing.MoneyFloat(0.0015),
+	}
+}
+
+func doRequest(t *testing.T, source Source, accessPoint, body string) (*fasthttp.RequestCtx, *testStream) {
+	stream := &testStream{}
+	ext, err := NewExtension(
+		WithAdvertisementSource(source),
+		WithAccessPointAccessor(testAccessPointAccessor{}),
+		WithZoneAccessor(testZoneAccessor{}),
+		WithURLGenerator(testURLGenerator{}),
+		WithEventStream(stream),
+		WithPriceParam("price"),
+	)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	rctx := &fasthttp.RequestCtx{}
+	rctx.Request.Header.SetMethod(http.MethodPost)
+	rctx.Request.SetBodyString(body)
+	rctx.SetUserValue("accesspoint", accessPoint)
+	ext.bidRequestHandler(context.Background(), rctx)
+	return rctx, stream
+}
+
+func TestNewExtensionRequiredOptions(t *testing.T) {
+	_, err := NewExtension(WithURLGenerator(testURLGenerator{}))
+	assert.ErrorIs(t, err, ErrSourceRequired)
+
+	_, err = NewExtension(WithAdvertisementSource(&testSource{}))
+	assert.ErrorIs(t, err, ErrURLGeneratorRequired)
+}
+
+func TestExchangeBannerBid(t *testing.T) {
+	source := &testSource{
+		check: func(request adtype.BidRequester) {
+			assert.Equal(t, uint64(1), request.AccessPoint().ID())
+			assert.Equal(t, "req1", request.ExternalID())
+			assert.Equal(t, types.FirstPriceAuctionType, request.AuctionType())
+			assert.True(t, request.IsSecure())
+			assert.Equal(t, 1, request.Get(ExtKeyGDPR))
+			assert.Equal(t, "consent-string", request.Get(ExtKeyConsent))
+			assert.Equal(t, "1YNN", request.Get(ExtKeyUSPrivacy))
+			assert.Equal(t, "US", request.GeoInfo().Country)
+			assert.Equal(t, "1.2.3.4", request.GeoInfo().IP.String())
+			assert.Equal(t, "buyer-uid", request.UserInfo().ID)
+			if imps := request.Impressions(); assert.Len(t, imps, 1) {
+				assert.Equal(t, "imp1", imps[0].ExternalID)
+				assert.Equal(t, "zone1", imps[0].ExternalTargetID)
+				assert.Equal(t, billing.MoneyFloat(1.0), imps[0].BidFloorCPM)
+				assert.Equal(t, 300, imps[0].WidthMax)
+				assert.Equal(t, 250, imps[0].HeightMax)
+			}
+		},
+		items: func(request adtype.BidRequester) []adtype.ResponseItemCommon {
+			return []adtype.ResponseItemCommon{newTestItem(request, nil,
+				admodels.AdFileAssets{{URL: "img.png", Width: 300, Height: 250}}, types.FormatBannerType)}
+		},
+	}
+	rctx, stream := doRequest(t, source, "1", `{
+		"id": "req1", "at": 1, "cur": ["USD"],
+		"imp": [
+			{"id": "imp1", "tagid": "zone1", "bidfloor": 1, "secure": 1, "banner": {"format": [{"w": 300, "h": 250}]}},
+			{"id": "imp2", "tagid": "unknown", "banner": {"w": 728, "h": 90}}
+		],
+		"site": {"id": "s1", "domain": "example.com", "page": "https://example.com/"},
+		"device": {"ua": "Mozilla", "ip": "1.2.3.4", "geo": {"country": "US"}},
+		"user": {"id": "u1", "buyeruid": "buyer-uid", "ext": {"consent": "consent-string"}},
+		"regs": {"ext": {"gdpr": 1, "us_privacy": "1YNN"}}
+	}`)
+
+	if !assert.Equal(t, http.StatusOK, rctx.Response.StatusCode()) {
+		return
+	}
+	var resp openrtb2.BidResponse
+	if !assert.NoError(t, json.Unmarshal(rctx.Response.Body(), &resp)) || !assert.Len(t, resp.SeatBids, 1) {
+		return
+	}
+	assert.Equal(t, "req1", resp.ID)
+	assert.Equal(t, "USD", resp.Currency)
+	bid := resp.SeatBids[0].Bids[0]
+	assert.Equal(t, "imp1", bid.ImpID)
+	assert.Equal(t, 1.5, bid.Price)
+	assert.Equal(t, "https://win?e=1&price=${AUCTION_PRICE}", bid.NoticeURL)
+	assert.Equal(t, "https://bin?price=${AUCTION_PRICE}", bid.BillingURL)
+	assert.Equal(t, "https://px/ap.loss?price=${AUCTION_PRICE}", bid.LossURL)
+	assert.Equal(t, openrtb2.MarkupBanner, bid.MarkupType)
+	assert.Contains(t, bid.AdMarkup, `<a href="https://click"`)
+	assert.Contains(t, bid.AdMarkup, `src="https://cdn/img.png"`)
+	assert.Contains(t, bid.AdMarkup, `src="https://px/impression"`)
+	assert.Equal(t, []events.Type{events.AccessPointBid}, stream.events)
+}
+
+func TestExchangeNativeBid(t *testing.T) {
+	source := &testSource{
+		items: func(request adtype.BidRequester) []adtype.ResponseItemCommon {
+			assert.NotNil(t, request.Impressions()[0].RTBNativeRequestV3())
+			return []adtype.ResponseItemCommon{newTestItem(request,
+				map[string]string{
+					types.FormatFieldTitle:       "Long title of the ad",
+					types.FormatFieldDescription: "Description",
+				},
+				admodels.AdFileAssets{{URL: "main.png", Width: 600, Height: 400}},
+				types.FormatNativeType)}
+		},
+	}
+	rctx, stream := doRequest(t, source, "1", `{
+		"id": "req1",
+		"imp": [{"id": "imp1", "tagid": "zone1", "native": {
+			"request": "{\"ver\":\"1.2\",\"assets\":[{\"id\":10,\"required\":1,\"title\":{\"len\":10}},{\"id\":11,\"required\":1,\"img\":{\"type\":3}},{\"id\":12,\"data\":{\"type\":2}},{\"id\":13,\"data\":{\"type\":1}}]}"
+		}}]
+	}`)
+
+	if !assert.Equal(t, http.StatusOK, rctx.Response.StatusCode()) {
+		return
+	}
+	var resp openrtb2.BidResponse
+	if !assert.NoError(t, json.Unmarshal(rctx.Response.Body(), &resp)) {
+		return
+	}
+	bid := resp.SeatBids[0].Bids[0]
+	assert.Equal(t, openrtb2.MarkupNative, bid.MarkupType)
+
+	var native natresp.Response
+	if !assert.NoError(t, json.Unmarshal([]byte(bid.AdMarkup), &native)) || !assert.Len(t, native.Assets, 3) {
+		return
+	}
+	assert.Equal(t, "https://click", native.Link.URL)
+	assert.Equal(t, []string{"https://px/impression"}, native.ImpTrackers)
+	assert.Equal(t, 10, native.Assets[0].ID)
+	assert.Equal(t, "Long title", native.Assets[0].Title.Text)
+	assert.Equal(t, 11, native.Assets[1].ID)
+	assert.Equal(t, "https://cdn/main.png", native.Assets[1].Image.URL)
+	assert.Equal(t, 12, native.Assets[2].ID)
+	assert.Equal(t, "Description", native.Assets[2].Data.Value)
+	assert.Equal(t, []events.Type{events.AccessPointBid}, stream.events)
+}
+
+func TestExchangeNoBid(t *testing.T) {
+	tests := []struct {
+		name        string
+		accessPoint string
+		body        string
+		status      int
+		events      []events.Type
+	}{
+		{
+			name:        "nobid",
+			accessPoint: "1",
+			body:        `{"id": "req1", "imp": [{"id": "imp1", "tagid": "zone1", "banner": {"w": 300, "h": 250}}]}`,
+			status:      http.StatusNoContent,
+			events:      []events.Type{events.AccessPointNoBid},
+		},
+		{
+			name:        "unknown_zone",
+			accessPoint: "1",
+			body:        `{"id": "req1", "imp": [{"id": "imp1", "tagid": "zone2", "banner": {"w": 300, "h": 250}}]}`,
+			status:      http.StatusNoContent,
+			events:      []events.Type{events.AccessPointSkip},
+		},
+		{
+			name:        "unsupported_currency",
+			accessPoint: "1",
+			body:        `{"id": "req1", "cur": ["EUR"], "imp": [{"id": "imp1", "tagid": "zone1", "banner": {"w": 300, "h": 250}}]}`,
+			status:      http.StatusNoContent,
+			events:      []events.Type{events.AccessPointSkip},
+		},
+		{
+			name:        "invalid_request",
+			accessPoint: "1",
+			body:        `{"id": "req1", "imp": []}`,
+			status:      http.StatusBadRequest,
+		},
+		{
+			name:        "unknown_access_point",
+			accessPoint: "2",
+			body:        `{"id": "req1", "imp": [{"id": "imp1", "tagid": "zone1", "banner": {"w": 300, "h": 250}}]}`,
+			status:      http.StatusNotFound,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rctx, stream := doRequest(t, &testSource{}, test.accessPoint, test.body)
+			assert.Equal(t, test.status, rctx.Response.StatusCode())
+			assert.Empty(t, rctx.Response.Body())
+			assert.Equal(t, test.events, stream.events)
+		})
+	}
+}
+
+func TestDecodeNativeRequest(t *testing.T) {
+	tests := []string{
+		`"{\"ver\":\"1.2\",\"assets\":[{\"id\":1,\"title\":{\"len\":10}}]}"`,
+		`{"native":{"ver":"1.2","assets":[{"id":1,"title":{"len":10}}]}}`,
+		`{"ver":"1.2","assets":[{"id":1,"title":{"len":10}}]}`,
+	}
+	for _, data := range tests {
+		request, err := decodeNativeRequest([]byte(data))
+		if assert.NoError(t, err, data) && assert.Len(t, request.Assets, 1, data) {
+			assert.Equal(t, 10, request.Assets[0].Title.Length)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package exchange
+
+import (
+	"github.com/geniusrabbit/adcorelib/admodels/types"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/eventtraking/eventstream"
+	"github.com/geniusrabbit/adcorelib/httpserver/wrappers/httphandler"
+)
+
+// Option type
+type Option func(ext *Extension)
+
+// WithAdvertisementSource accessor
+func WithAdvertisementSource(source Source) Option {
+	return func(ext *Extension) {
+		ext.source = source
+	}
+}
+
+// WithHTTPHandlerWrapper setter
+func WithHTTPHandlerWrapper(handlerWrapper *httphandler.HTTPHandlerWrapper) Option {
+	return func(ext *Extension) {
+		ext.handlerWrapper = handlerWrapper
+	}
+}
+
+// WithFormatAccessor setter
+func WithFormatAccessor(formatAccessor types.FormatsAccessor) Option {
+	return func(ext *Extension) {
+		ext.formatAccessor = formatAccessor
+	}
+}
+
+// WithAccessPointAccessor setter
+func WithAccessPointAccessor(accessPointAccessor accessPointAccessor) Option {
+	return func(ext *Extension) {
+		ext.accessPointAccessor = accessPointAccessor
+	}
+}
+
+// WithZoneAccessor setter
+func WithZoneAccessor(zoneAccessor zoneAccessor) Option {
+	return func(ext *Extension) {
+		ext.zoneAccessor = zoneAccessor
+	}
+}
+
+// WithURLGenerator setter
+func WithURLGenerator(urlGenerator adtype.URLGenerator) Option {
+	return func(ext *Extension) {
+		ext.urlGenerator = urlGenerator
+	}
+}
+
+// WithEventStream setter
+func WithEventStream(eventStream eventstream.Stream) Option {
+	return func(ext *Extension) {
+		ext.eventStream = eventStream
+	}
+}
+
+// WithPriceParam setter of the query parameter name which receives
+// the `${AUCTION_PRICE}` macro in the win and billing notice URLs
+func WithPriceParam(name string) Option {
+	return func(ext *Extension) {
+		ext.priceParam = name
+	}
+}
+
+// WithURLQueryPattern setter
+func WithURLQueryPattern(pattern string) Option {
+	return func(ext *Extension) {
+		ext.URLQueryPattern = pattern
+	}
+}
@@ -0,0 +1,367 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"slices"
+	"time"
+
+	openrtb2 "github.com/bsm/openrtb/v3"
+	natreq "github.com/bsm/openrtb/v3/native/request"
+	"github.com/demdxx/gocast/v2"
+	"github.com/geniusrabbit/udetect"
+	"github.com/valyala/fasthttp"
+	"go.uber.org/zap"
+
+	"github.com/geniusrabbit/adcorelib/admodels/types"
+	"github.com/geniusrabbit/adcorelib/adquery/bidrequest"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/billing"
+	"github.com/geniusrabbit/adcorelib/context/ctxlogger"
+)
+
+// Currency which is supported by the exchange endpoint
+const currencyUSD = "USD"
+
+// Ext keys of the bid request privacy signals
+const (
+	ExtKeyCOPPA     = "coppa"
+	ExtKeyGDPR      = "gdpr"
+	ExtKeyConsent   = "gdpr_consent"
+	ExtKeyUSPrivacy = "us_privacy"
+)
+
+// privacyFields which are defined in the OpenRTB 2.6 objects (regs, user)
+// or in the `ext` of the OpenRTB 2.5 objects
+type privacyFields struct {
+	GDPR      *int   `json:"gdpr,omitempty"`
+	USPrivacy string `json:"us_privacy,omitempty"`
+	Consent   string `json:"consent,omitempty"`
+}
+
+// newBidRequest converts the OpenRTB request into the internal bid request.
+// Impressions which can't be served by the system are excluded from the request.
+func (ext *Extension) newBidRequest(ctx context.Context, rctx *fasthttp.RequestCtx, accessPoint adtype.AccessPoint, rtbRequest *openrtb2.BidRequest) *bidrequest.BidRequest {
+	var (
+		geo     = geoFrom(rtbRequest.Device, rtbRequest.User)
+		aucType = types.SecondPriceAuctionType
+	)
+	if rtbRequest.AuctionType == int(types.FirstPriceAuctionType) {
+		aucType = types.FirstPriceAuctionType
+	}
+
+	request := &bidrequest.BidRequest{
+		IDVal:          adtype.NewRequestID(),
+		ExtID:          rtbRequest.ID,
+		Timemark:       time.Now(),
+		Ctx:            ctx,
+		AccessPointLnk: accessPoint,
+		AucType:        aucType,
+		RequestCtx:     rctx,
+		Request:        rtbRequest,
+		Device:         deviceFrom(rtbRequest.Device),
+		App:            appFrom(rtbRequest.App),
+		Site:           siteFrom(rtbRequest.Site),
+		User:           userFrom(rtbRequest.User, geo),
+		Ext:            privacyExt(rctx.PostBody(), rtbRequest),
+	}
+
+	// The system trades only in USD
+	if len(rtbRequest.Currencies) == 0 || slices.Contains(rtbRequest.Currencies, currencyUSD) {
+		for i := range rtbRequest.Impressions {
+			rtbImp := &rtbRequest.Impressions[i]
+			if imp := ext.impression(ctx, rtbImp); imp != nil {
+				if rtbImp.Secure == 1 {
+					request.StateFlags |= bidrequest.BidRequestFlagSecure
+				}
+				request.Imps = append(request.Imps, imp)
+			}
+		}
+	}
+
+	// Prepare bid request with categories and tags
+	_ = request.PrepareRequest(0, nil)
+
+	return request
+}
+
+// impression converts the OpenRTB impression into the internal one.
+// Returns nil if the impression has no target or supported format.
+func (ext *Extension) impression(ctx context.Context, rtbImp *openrtb2.Impression) *adtype.Impression {
+	if rtbImp.BidFloorCurrency != "" && rtbImp.BidFloorCurrency != currencyUSD {
+		return nil
+	}
+	target := ext.target(ctx, rtbImp.TagID)
+	if target == nil {
+		return nil
+	}
+
+	imp := &adtype.Impression{
+		ID:               adtype.NewImpressionID(),
+		ExternalID:       rtbImp.ID,
+		ExternalTargetID: rtbImp.TagID,
+		Target:           target,
+		BidFloorCPM:      billing.MoneyFloat(rtbImp.BidFloor),
+		Count:            1,
+		Interstitial:     rtbImp.Interstitial == 1,
+	}
+
+	if banner := rtbImp.Banner; banner != nil {
+		imp.FormatTypes.Set(types.FormatBannerType, types.FormatBannerHTML5Type)
+		imp.Pos = int(banner.Position)
+		imp.Width, imp.Height, imp.WidthMax, imp.HeightMax = bannerSize(banner)
+	}
+	if video := rtbImp.Video; video != nil {
+		imp.FormatTypes.Set(types.FormatVideoType)
+		if imp.Width == 0 && imp.Height == 0 {
+			imp.Pos = int(video.Position)
+			imp.Width, imp.Height = video.Width, video.Height
+			imp.WidthMax, imp.HeightMax = video.Width, video.Height
+		}
+	}
+	if native := rtbImp.Native; native != nil {
+		nativeRequest, err := decodeNativeRequest(native.Request)
+		if err != nil {
+			ctxlogger.Get(ctx).Debug("Invalid OpenRTB native request",
+				zap.String("imp", rtbImp.ID), zap.Error(err))
+		} else {
+			imp.FormatTypes.Set(types.FormatNativeType)
+			imp.Request = nativeRequest
+		}
+	}
+	if imp.FormatTypes.IsEmpty() {
+		return nil
+	}
+	return imp
+}
+
+func (ext *Extension) target(ctx context.Context, tagID string) adtype.Target {
+	if ext.zoneAccessor == nil || tagID == "" {
+		return nil
+	}
+	target, err := ext.zoneAccessor.TargetByCodename(ctx, tagID)
+	if err != nil {
+		ctxlogger.Get(ctx).Debug("Target not found", zap.String("tagid", tagID), zap.Error(err))
+		return nil
+	}
+	return target
+}
+
+// bannerSize returns the minimal and maximal sizes of the banner
+func bannerSize(banner *openrtb2.Banner) (w, h, wmax, hmax int) {
+	w, h = banner.Width, banner.Height
+	wmax, hmax = max(banner.WidthMax, w), max(banner.HeightMax, h)
+	for _, format := range banner.Formats {
+		if w == 0 || (format.Width > 0 && format.Width < w) {
+			w = format.Width
+		}
+		if h == 0 || (format.Height > 0 && format.Height < h) {
+			h = format.Height
+		}
+		wmax, hmax = max(wmax, format.Width), max(hmax, format.Height)
+	}
+	return w, h, wmax, hmax
+}
+
+// decodeNativeRequest supports the string encoded native request as required
+// by the specification and the object form, both with and without the `native` wrapper
+func decodeNativeRequest(data json.RawMessage) (*natreq.Request, error) {
+	if len(data) > 0 && data[0] == '"' {
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return nil, err
+		}
+		data = json.RawMessage(str)
+	}
+	var wrapper struct {
+		Native *natreq.Request `json:"native"`
+	}
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return nil, err
+	}
+	if wrapper.Native != nil {
+		return wrapper.Native, nil
+	}
+	var request natreq.Request
+	if err := json.Unmarshal(data, &request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func deviceFrom(device *openrtb2.Device) *udetect.Device {
+	if device == nil {
+		return nil
+	}
+	return &udetect.Device{
+		Make:  device.Make,
+		Model: device.Model,
+		OS:    &udetect.OS{Name: device.OS, Version: device.OSVersion},
+		Browser: &udetect.Browser{
+			UA:              device.UA,
+			DNT:             int8(device.DNT),
+			LMT:             int8(device.LMT),
+			JS:              int8(device.JS),
+			PrimaryLanguage: device.Language,
+		},
+		ConnType:   int(device.ConnType),
+		DeviceType: udetect.DeviceType(device.DeviceType),
+		IFA:        device.IFA,
+		Height:     device.Height,
+		Width:      device.Width,
+		PPI:        device.PPI,
+		PxRatio:    device.PixelRatio,
+		HwVer:      device.HWVersion,
+	}
+}
+
+// geoFrom device or user geo information (device has priority)
+func geoFrom(device *openrtb2.Device, user *openrtb2.User) *udetect.Geo {
+	var (
+		geo    = &udetect.Geo{}
+		rtbGeo *openrtb2.Geo
+	)
+	if device != nil && device.Geo != nil {
+		rtbGeo = device.Geo
+	} else if user != nil {
+		rtbGeo = user.Geo
+	}
+	if rtbGeo != nil {
+		geo.Lat = rtbGeo.Latitude
+		geo.Lon = rtbGeo.Longitude
+		geo.Country = rtbGeo.Country
+		geo.Region = rtbGeo.Region
+		geo.RegionFIPS104 = rtbGeo.RegionFIPS104
+		geo.Metro = rtbGeo.Metro
+		geo.City = rtbGeo.City
+		geo.ZIP = rtbGeo.ZIP
+		geo.UTCOffset = rtbGeo.UTCOffset
+	}
+	if device != nil {
+		geo.IP = net.ParseIP(gocast.Or(device.IP, device.IPv6))
+		if device.Carrier != "" || device.MCCMNC != "" {
+			geo.Carrier = &udetect.Carrier{Name: device.Carrier, Code: device.MCCMNC}
+		}
+	}
+	return geo
+}
+
+func siteFrom(site *openrtb2.Site) *udetect.Site {
+	if site == nil {
+		return nil
+	}
+	return &udetect.Site{
+		ExtID:         site.ID,
+		Domain:        site.Domain,
+		Cat:           categories(site.Categories),
+		PrivacyPolicy: intValue(site.PrivacyPolicy),
+		Keywords:      site.Keywords,
+		Page:          site.Page,
+		Referrer:      site.Referrer,
+		Search:        site.Search,
+		Mobile:        site.Mobile,
+	}
+}
+
+func appFrom(app *openrtb2.App) *udetect.App {
+	if app == nil {
+		return nil
+	}
+	return &udetect.App{
+		ExtID:         app.ID,
+		Keywords:      app.Keywords,
+		Cat:           categories(app.Categories),
+		Bundle:        app.Bundle,
+		StoreURL:      app.StoreURL,
+		Ver:           app.Version,
+		Paid:          app.Paid,
+		PrivacyPolicy: intValue(app.PrivacyPolicy),
+	}
+}
+
+func userFrom(user *openrtb2.User, geo *udetect.Geo) *adtype.User {
+	res := &adtype.User{Geo: geo}
+	if user == nil {
+		return res
+	}
+	// The buyer ID is our user ID mapped by the exchange (cookie sync)
+	res.ID = gocast.Or(user.BuyerUID, user.BuyerID, user.ID)
+	res.Gender = user.Gender
+	res.Keywords = user.Keywords
+	if user.YearOfBirth > 0 {
+		age := time.Now().Year() - user.YearOfBirth
+		res.AgeStart, res.AgeEnd = age, age
+	}
+	for _, data := range user.Data {
+		item := adtype.Data{Name: data.Name}
+		for _, segment := range data.Segment {
+			item.Segment = append(item.Segment, adtype.Segment{Name: segment.Name, Value: segment.Value})
+		}
+		res.Data = append(res.Data, item)
+	}
+	return res
+}
+
+// privacyExt collects the regulation signals of the request into the ext map
+func privacyExt(body []byte, rtbRequest *openrtb2.BidRequest) map[string]any {
+	var (
+		ext   = map[string]any{}
+		root  struct{ Regs, User privacyFields }
+		regs  privacyFields
+		user  privacyFields
+		merge = func(fields ...privacyFields) {
+			for _, field := range fields {
+				if field.GDPR != nil {
+					ext[ExtKeyGDPR] = *field.GDPR
+				}
+				if field.USPrivacy != "" {
+					ext[ExtKeyUSPrivacy] = field.USPrivacy
+				}
+				if field.Consent != "" {
+					ext[ExtKeyConsent] = field.Consent
+				}
+			}
+		}
+	)
+	// OpenRTB 2.6 fields are not a part of the bsm/openrtb structures
+	_ = json.Unmarshal(body, &root)
+	if rtbRequest.Regulations != nil {
+		if rtbRequest.Regulations.COPPA == 1 {
+			ext[ExtKeyCOPPA] = 1
+		}
+		_ = json.Unmarshal(rtbRequest.Regulations.Ext, &regs)
+	}
+	if rtbRequest.User != nil {
+		_ = json.Unmarshal(rtbRequest.User.Ext, &user)
+	}
+	// Values of the 2.6 objects take precedence over the 2.5 extensions
+	merge(regs, user, root.Regs, root.User)
+	if len(ext) == 0 {
+		return nil
+	}
+	return ext
+}
+
+func categories(cats []openrtb2.ContentCategory) []string {
+	if len(cats) == 0 {
+		return nil
+	}
+	res := make([]string, 0, len(cats))
+	for _, cat := range cats {
+		res = append(res, string(cat))
+	}
+	return res
+}
+
+func intValue(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
@@ -0,0 +1,295 @@
+//
+// @project GeniusRabbit corelib 2026
+// @author Dmitry Ponomarev <demdxx@gmail.com>
+//
+
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	openrtb2 "github.com/bsm/openrtb/v3"
+	natreq "github.com/bsm/openrtb/v3/native/request"
+	natresp "github.com/bsm/openrtb/v3/native/response"
+	"github.com/demdxx/gocast/v2"
+	"go.uber.org/zap"
+
+	"github.com/geniusrabbit/adcorelib/admodels"
+	"github.com/geniusrabbit/adcorelib/admodels/types"
+	"github.com/geniusrabbit/adcorelib/adtype"
+	"github.com/geniusrabbit/adcorelib/adtype/prices"
+	"github.com/geniusrabbit/adcorelib/context/ctxlogger"
+	"github.com/geniusrabbit/adcorelib/eventtraking/events"
+)
+
+const (
+	nativeVersion     = "1.2"
+	auctionPriceMacro = "${AUCTION_PRICE}"
+)
+
+var (
+	errBidBelowFloor       = errors.New("[exchange] bid price is below the floor")
+	errEmptyMarkup         = errors.New("[exchange] empty ad markup")
+	errNoNativeRequest     = errors.New("[exchange] native request is not defined")
+	errRequiredNativeAsset = errors.New("[exchange] required native asset is not available")
+)
+
+// Mapping of the native data asset types to the format fields
+var nativeDataFields = map[natreq.DataTypeID][]string{
+	natreq.DataTypeSponsored:  {types.FormatFieldSponsored, types.FormatFieldBrandname},
+	natreq.DataTypeDesc:       {types.FormatFieldDescription},
+	natreq.DataTypeRating:     {types.FormatFieldRating},
+	natreq.DataTypeLikes:      {types.FormatFieldLikes},
+	natreq.DataTypePhone:      {types.FormatFieldPhone},
+	natreq.DataTypeAddress:    {types.FormatFieldAddress},
+	natreq.DataTypeDisplayURL: {types.FormatFieldURL},
+}
+
+// newBidResponse renders the OpenRTB bid response from the response of the source.
+// Returns nil if there is no any valid bid in the response.
+func (ext *Extension) newBidResponse(ctx context.Context, rtbRequest *openrtb2.BidRequest, response adtype.Response) (*openrtb2.BidResponse, []adtype.ResponseItem) {
+	if response.Error() != nil {
+		return nil, nil
+	}
+	var (
+		bids  []openrtb2.Bid
+		items []adtype.ResponseItem
+	)
+	for item := range response.IterAds() {
+		bid, err := ext.newBid(item, response)
+		if err != nil {
+			ctxlogger.Get(ctx).Debug("Skip OpenRTB bid",
+				zap.String("imp", item.ExtImpressionID()), zap.Error(err))
+			continue
+		}
+		bids = append(bids, *bid)
+		items = append(items, item)
+	}
+	if len(bids) == 0 {
+		return nil, nil
+	}
+	return &openrtb2.BidResponse{
+		ID:       rtbRequest.ID,
+		BidID:    response.Request().ID(),
+		Currency: currencyUSD,
+		SeatBids: []openrtb2.SeatBid{{Bids: bids}},
+	}, items
+}
+
+func (ext *Extension) newBid(item adtype.ResponseItem, response adtype.Response) (*openrtb2.Bid, error) {
+	price := prices.CPMFromPrice(item.PurchasePrice(adtype.ActionImpression))
+	if price <= 0 || price < item.Impression().BidFloorCPM {
+		return nil, errBidBelowFloor
+	}
+	markup, markupType, err := ext.markup(item, response)
+	if err != nil {
+		return nil, err
+	}
+	winURL, err := ext.urlGenerator.WinURL(events.AccessPointWin, events.StatusSuccess, item, response)
+	if err != nil {
+		return nil, err
+	}
+	billingURL, err := ext.urlGenerator.BillingNoticeURL(events.AccessPointBillingNotice, events.StatusSuccess, item, response)
+	if err != nil {
+		return nil, err
+	}
+	lossURL, err := ext.urlGenerator.PixelURL(events.AccessPointLoss, events.StatusFailed, item, response, false)
+	if err != nil {
+		return nil, err
+	}
+	bid := &openrtb2.Bid{
+		ID:         item.ID(),
+		ImpID:      item.ExtImpressionID(),
+		Price:      price.Float64(),
+		AdID:       item.AdID(),
+		NoticeURL:  ext.withPriceMacro(winURL),
+		BillingURL: ext.withPriceMacro(billingURL),
+		LossURL:    ext.withPriceMacro(lossURL),
+		AdMarkup:   markup,
+		CreativeID: item.CreativeID(),
+		MarkupType: markupType,
+	}
+	if campaignID := item.CampaignID(); campaignID > 0 {
+		bid.CampaignID = openrtb2.StringOrNumber(strconv.FormatUint(campaignID, 10))
+	}
+	if markupType != openrtb2.MarkupNative {
+		bid.Width, bid.Height = item.Width(), item.Height()
+	}
+	return bid, nil
+}
+
+// withPriceMacro adds the auction price macro to the notification URL
+func (ext *Extension) withPriceMacro(link string) string {
+	if ext.priceParam == "" || link == "" {
+		return link
+	}
+	if strings.Contains(link, "?") {
+		return link + "&" + ext.priceParam + "=" + auctionPriceMacro
+	}
+	return link + "?" + ext.priceParam + "=" + auctionPriceMacro
+}
+
+///////////////////////////////////////////////////////////////////////////////
+/// Markup rendering
+///////////////////////////////////////////////////////////////////////////////
+
+func (ext *Extension) markup(item adtype.ResponseItem, response adtype.Response) (string, openrtb2.MarkupType, error) {
+	switch formatType := item.PriorityFormatType(); {
+	case formatType.IsNative():
+		markup, err := ext.nativeMarkup(item, response)
+		return markup, openrtb2.MarkupNative, err
+	case formatType == types.FormatVideoType:
+		vast := item.ContentItemString(adtype.ContentItemContent)
+		if vast == "" {
+			return "", 0, errEmptyMarkup
+		}
+		return vast, openrtb2.MarkupVideo, nil
+	default:
+		markup, err := ext.bannerMarkup(item, response)
+		return markup, openrtb2.MarkupBanner, err
+	}
+}
+
+func (ext *Extension) bannerMarkup(item adtype.ResponseItem, response adtype.Response) (string, error) {
+	var markup strings.Builder
+	if content := item.ContentItemString(adtype.ContentItemContent); content != "" {
+		markup.WriteString(content)
+	} else if iframeURL := item.ContentItemString(adtype.ContentItemIFrameURL); iframeURL != "" {
+		fmt.Fprintf(&markup, `<iframe src="%s" width="%d" height="%d" frameborder="0" scrolling="no" marginwidth="0" marginheight="0"></iframe>`,
+			html.EscapeString(iframeURL), item.Width(), item.Height())
+	} else if asset := item.MainAsset(); asset != nil && asset.URL != "" {
+		fmt.Fprintf(&markup, `<a href="%s" target="_blank"><img src="%s" width="%d" height="%d" alt="%s" border="0" /></a>`,
+			html.EscapeString(ext.urlGenerator.MustClickURL(item, response)),
+			html.EscapeString(ext.urlGenerator.CDNURL(asset.URL)),
+			gocast.Or(asset.Width, item.Width()), gocast.Or(asset.Height, item.Height()),
+			html.EscapeString(asset.AltText))
+	} else {
+		return "", errEmptyMarkup
+	}
+	for _, tracker := range ext.impressionTrackers(item, response) {
+		fmt.Fprintf(&markup, `<img src="%s" width="1" height="1" border="0" alt="" style="display:none" />`,
+			html.EscapeString(tracker))
+	}
+	return markup.String(), nil
+}
+
+func (ext *Extension) nativeMarkup(item adtype.ResponseItem, response adtype.Response) (string, error) {
+	request := item.Impression().RTBNativeRequestV3()
+	if request == nil {
+		return "", errNoNativeRequest
+	}
+	native := natresp.Response{
+		Version: openrtb2.StringOrNumber(gocast.Or(request.Version, nativeVersion)),
+		Link: natresp.Link{
+			URL:           ext.urlGenerator.MustClickURL(item, response),
+			ClickTrackers: item.ClickTrackerLinks(),
+		},
+		ImpTrackers: ext.impressionTrackers(item, response),
+	}
+	for i := range request.Assets {
+		asset, ok := ext.nativeAsset(&request.Assets[i], item)
+		if !ok {
+			if request.Assets[i].Required == 1 {
+				return "", fmt.Errorf("%w: %d", errRequiredNativeAsset, request.Assets[i].ID)
+			}
+			continue
+		}
+		native.Assets = append(native.Assets, *asset)
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	// Before the version 1.2 of the specification the response is wrapped by the `native` object
+	if strings.HasPrefix(request.Version, "1.0") || strings.HasPrefix(request.Version, "1.1") {
+		data, err = json.Marshal(map[string]any{"native": native})
+	} else {
+		data, err = json.Marshal(native)
+	}
+	return string(data), err
+}
+
+func (ext *Extension) nativeAsset(reqAsset *natreq.Asset, item adtype.ResponseItem) (*natresp.Asset, bool) {
+	asset := &natresp.Asset{ID: reqAsset.ID, Required: reqAsset.Required}
+	switch {
+	case reqAsset.Title != nil:
+		text := item.ContentItemString(types.FormatFieldTitle)
+		if text == "" {
+			return nil, false
+		}
+		asset.Title = &natresp.Title{Text: truncate(text, reqAsset.Title.Length)}
+	case reqAsset.Image != nil:
+		image := nativeImage(reqAsset.Image, item.Assets())
+		if image == nil {
+			return nil, false
+		}
+		image.URL = ext.urlGenerator.CDNURL(image.URL)
+		asset.Image = image
+	case reqAsset.Data != nil:
+		var value string
+		for _, field := range nativeDataFields[reqAsset.Data.TypeID] {
+			if value = item.ContentItemString(field); value != "" {
+				break
+			}
+		}
+		if value == "" {
+			return nil, false
+		}
+		asset.Data = &natresp.Data{Value: truncate(value, reqAsset.Data.Length)}
+	case reqAsset.Video != nil:
+		vast := item.ContentItemString(adtype.ContentItemContent)
+		if vast == "" {
+			return nil, false
+		}
+		asset.Video = &natresp.Video{VASTTag: vast}
+	default:
+		return nil, false
+	}
+	return asset, true
+}
+
+func (ext *Extension) impressionTrackers(item adtype.ResponseItem, response adtype.Response) []string {
+	trackers := item.ImpressionTrackerLinks()
+	if pixel, _ := ext.urlGenerator.PixelURL(events.Impression, events.StatusSuccess, item, response, false); pixel != "" {
+		trackers = append([]string{pixel}, trackers...)
+	}
+	return trackers
+}
+
+// nativeImage selects the asset of the ad by the image type of request
+func nativeImage(image *natreq.Image, assets admodels.AdFileAssets) *natresp.Image {
+	var asset *admodels.AdFileAsset
+	switch image.TypeID {
+	case natreq.ImageTypeIcon:
+		asset = gocast.Or(assets.Asset(types.FormatAssetIcon), assets.Asset(types.FormatAssetLogo))
+	case natreq.ImageTypeLogo:
+		asset = gocast.Or(assets.Asset(types.FormatAssetLogo), assets.Asset(types.FormatAssetIcon))
+	default:
+		asset = assets.Main()
+	}
+	if asset == nil || asset.URL == "" {
+		return nil
+	}
+	if image.Width > 0 || image.Height > 0 || image.WidthMin > 0 || image.HeightMin > 0 {
+		if thumb := asset.ThumbBy(image.Width, image.Height, image.WidthMin, image.HeightMin); thumb != nil {
+			return &natresp.Image{URL: thumb.URL, Width: thumb.Width, Height: thumb.Height}
+		}
+	}
+	return &natresp.Image{URL: asset.URL, Width: asset.Width, Height: asset.Height}
+}
+
+// truncate the text to the maximal length in runes
+func truncate(text string, length int) string {
+	if length <= 0 || utf8.RuneCountInString(text) <= length {
+		return text
+	}
+	return string([]rune(text)[:length])
+}
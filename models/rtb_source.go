@@ -28,6 +28,9 @@ type RTBSourceFlags struct {
 
 type RTBSourceConfig struct {
 	Rules string `json:"rules,omitempty"`
+
+	// Currency of the source bids and floors (ISO 4217), USD by default
+	Currency string `json:"currency,omitempty"`
 }
 
 // RTBSource for SSP connect
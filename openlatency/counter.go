@@ -14,6 +14,7 @@ type MetricsCounter struct {
 	queries      int32
 	success      int32
 	skips        int32
+	throttles    int32
 	timeouts     int32
 	noBids       int32
 	errors       int32
@@ -62,6 +63,11 @@ func (cnt *MetricsCounter) IncSkip() int32 {
 	return atomic.AddInt32(&cnt.skips, 1)
 }
 
+// IncThrottle counter of requests rejected by the rate limit
+func (cnt *MetricsCounter) IncThrottle() int32 {
+	return atomic.AddInt32(&cnt.throttles, 1)
+}
+
 // IncSuccess counter
 func (cnt *MetricsCounter) IncSuccess() int32 {
 	return atomic.AddInt32(&cnt.success, 1)
@@ -84,6 +90,7 @@ func (cnt *MetricsCounter) FillMetrics(info *MetricsInfo) {
 	info.AvgLatency = atomic.LoadInt64(&cnt.avgLatency)
 	info.QPS = counter(&cnt.queries, seconds)
 	info.Skips = counter(&cnt.skips, seconds)
+	info.Throttled = counter(&cnt.throttles, seconds)
 	info.Success = counter(&cnt.success, seconds)
 	info.Timeouts = counter(&cnt.timeouts, seconds)
 	info.NoBids = counter(&cnt.noBids, seconds)
@@ -108,6 +115,7 @@ func (cnt *MetricsCounter) refresh() {
 	atomic.StoreInt64(&cnt.maxLatency, atomic.LoadInt64(&cnt.avgLatency))
 	atomic.StoreInt32(&cnt.queries, int32(counter(&cnt.queries, seconds)))
 	atomic.StoreInt32(&cnt.success, int32(counter(&cnt.success, seconds)))
+	atomic.StoreInt32(&cnt.throttles, int32(counter(&cnt.throttles, seconds)))
 	atomic.StoreInt32(&cnt.timeouts, int32(counter(&cnt.timeouts, seconds)))
 	atomic.StoreInt32(&cnt.noBids, int32(counter(&cnt.noBids, seconds)))
 	atomic.StoreInt32(&cnt.errors, int32(counter(&cnt.errors, seconds)))
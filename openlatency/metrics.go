@@ -7,6 +7,9 @@ type MetricErrorType string
 const (
 	MetricErrorHTTP    MetricErrorType = "http"
 	MetricErrorNetwork MetricErrorType = "network"
+	MetricErrorTimeout MetricErrorType = "timeout"
+	MetricErrorInvalid MetricErrorType = "invalid"
+	MetricErrorOther   MetricErrorType = "other"
 )
 
 type MetricErrorRate struct {
@@ -23,20 +26,27 @@ type MetricsGeoRate struct {
 // MetricsInfo describes basic metric information of AdNetworks integration
 // All counters it's numbers per second
 type MetricsInfo struct {
-	ID         uint64            `json:"id"`
-	Protocol   string            `json:"protocol"`
-	Codename   string            `json:"codename,omitempty"`
-	Traceroute string            `json:"traceroute,omitempty"`
-	MinLatency int64             `json:"min_latency_ms"` // Minimal request delay in Millisecond
-	MaxLatency int64             `json:"max_latency_ms"` // Maximal request delay in Millisecond
-	AvgLatency int64             `json:"avg_latency_ms"` // Average request delay in Millisecond
-	QPSLimit   int               `json:"qps_limit,omitempty"`
-	QPS        float64           `json:"qps"`
-	Skips      float64           `json:"skips_qps"`
-	Success    float64           `json:"success_qps"`
-	Timeouts   float64           `json:"timeouts_qps"`
-	NoBids     float64           `json:"no_bids_qps"`
-	Errors     float64           `json:"errors_qps"`
-	ErrorRates []MetricErrorRate `json:"error_rates,omitempty"`
-	GeoRates   []MetricsGeoRate  `json:"geo_rates,omitempty"`
+	ID          uint64            `json:"id"`
+	Protocol    string            `json:"protocol"`
+	Codename    string            `json:"codename,omitempty"`
+	Traceroute  string            `json:"traceroute,omitempty"`
+	MinLatency  int64             `json:"min_latency_ms"`       // Minimal request delay in Millisecond
+	MaxLatency  int64             `json:"max_latency_ms"`       // Maximal request delay in Millisecond
+	AvgLatency  int64             `json:"avg_latency_ms"`       // Average request delay in Millisecond
+	Timeout     int64             `json:"timeout_ms,omitempty"` // Current request timeout in Millisecond
+	QPSLimit    int               `json:"qps_limit,omitempty"`
+	QPS         float64           `json:"qps"`
+	Skips       float64           `json:"skips_qps"`
+	Throttled   float64           `json:"throttled_qps"`
+	Success     float64           `json:"success_qps"`
+	Timeouts    float64           `json:"timeouts_qps"`
+	NoBids      float64           `json:"no_bids_qps"`
+	Errors      float64           `json:"errors_qps"`
+	BidRate     float64           `json:"bid_rate"`     // Part of responses with bids
+	NoBidRate   float64           `json:"no_bid_rate"`  // Part of responses without bids
+	TimeoutRate float64           `json:"timeout_rate"` // Part of responses with timeout
+	WinRate     float64           `json:"win_rate"`     // Part of bids which won the auction
+	AvgBidCPM   float64           `json:"avg_bid_cpm"`
+	ErrorRates  []MetricErrorRate `json:"error_rates,omitempty"`
+	GeoRates    []MetricsGeoRate  `json:"geo_rates,omitempty"`
 }